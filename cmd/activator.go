@@ -21,7 +21,27 @@ func runActivator(ctx context.Context) {
 		glog.Fatalf("Error building kubernetes clientset: %s", err)
 	}
 
-	activator, err := deployments.NewActivator(client)
+	dynamicClient, err := kubernetes.DynamicClient()
+	if err != nil {
+		glog.Fatalf("Error building dynamic kubernetes client: %s", err)
+	}
+
+	workloadsClient, err := kubernetes.NewWorkloadsClient()
+	if err != nil {
+		glog.Fatalf("Error building workloads client: %s", err)
+	}
+
+	cfg, err := deployments.GetConfigFromEnvironment()
+	if err != nil {
+		glog.Fatalf("Error getting activator envconfig: %s", err)
+	}
+
+	activator, err := deployments.NewActivator(
+		cfg,
+		client,
+		dynamicClient,
+		workloadsClient,
+	)
 	if err != nil {
 		glog.Fatalf("Error initializing activator: %s", err)
 	}
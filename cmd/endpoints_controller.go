@@ -21,6 +21,11 @@ func runEndpointsController(ctx context.Context) {
 		glog.Fatalf("Error building kubernetes clientset: %s", err)
 	}
 
+	dynamicClient, err := kubernetes.DynamicClient()
+	if err != nil {
+		glog.Fatalf("Error building dynamic kubernetes client: %s", err)
+	}
+
 	controllerCfg, err := endpoints.GetConfigFromEnvironment()
 	if err != nil {
 		glog.Fatalf(
@@ -30,5 +35,5 @@ func runEndpointsController(ctx context.Context) {
 	}
 
 	// Run the controller
-	endpoints.NewController(controllerCfg, client).Run(ctx)
+	endpoints.NewController(controllerCfg, client, dynamicClient).Run(ctx)
 }
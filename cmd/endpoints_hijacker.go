@@ -4,6 +4,7 @@ import (
 	"context"
 
 	endpoints "github.com/deislabs/osiris/pkg/endpoints/hijacker"
+	"github.com/deislabs/osiris/pkg/kubernetes"
 	"github.com/deislabs/osiris/pkg/version"
 	"github.com/golang/glog"
 )
@@ -15,6 +16,16 @@ func runEndpointsHijacker(ctx context.Context) {
 		version.Commit(),
 	)
 
+	client, err := kubernetes.Client()
+	if err != nil {
+		glog.Fatalf("Error building kubernetes clientset: %s", err.Error())
+	}
+
+	dynamicClient, err := kubernetes.DynamicClient()
+	if err != nil {
+		glog.Fatalf("Error building dynamic kubernetes client: %s", err.Error())
+	}
+
 	cfg, err := endpoints.GetConfigFromEnvironment()
 	if err != nil {
 		glog.Fatalf(
@@ -25,5 +36,5 @@ func runEndpointsHijacker(ctx context.Context) {
 	}
 
 	// Run the server
-	endpoints.NewHijacker(cfg).Run(ctx)
+	endpoints.NewHijacker(cfg, client, dynamicClient).Run(ctx)
 }
@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 
+	"github.com/deislabs/osiris/pkg/kubernetes"
 	proxy "github.com/deislabs/osiris/pkg/metrics/proxy/injector"
 	"github.com/deislabs/osiris/pkg/version"
 	"github.com/golang/glog"
@@ -15,6 +16,16 @@ func runProxyInjector(ctx context.Context) {
 		version.Commit(),
 	)
 
+	client, err := kubernetes.Client()
+	if err != nil {
+		glog.Fatalf("Error building kubernetes clientset: %s", err.Error())
+	}
+
+	dynamicClient, err := kubernetes.DynamicClient()
+	if err != nil {
+		glog.Fatalf("Error building dynamic kubernetes client: %s", err.Error())
+	}
+
 	cfg, err := proxy.GetConfigFromEnvironment()
 	if err != nil {
 		glog.Fatalf(
@@ -24,5 +35,5 @@ func runProxyInjector(ctx context.Context) {
 	}
 
 	// Run the proxy injexctor
-	proxy.NewInjector(cfg).Run(ctx)
+	proxy.NewInjector(cfg, client, dynamicClient).Run(ctx)
 }
@@ -21,11 +21,31 @@ func runZeroScaler(ctx context.Context) {
 		glog.Fatalf("Error building kubernetes clientset: %s", err.Error())
 	}
 
+	dynamicClient, err := kubernetes.DynamicClient()
+	if err != nil {
+		glog.Fatalf("Error building dynamic kubernetes client: %s", err.Error())
+	}
+
+	workloadsClient, err := kubernetes.NewWorkloadsClient()
+	if err != nil {
+		glog.Fatalf("Error building workloads client: %s", err.Error())
+	}
+
 	cfg, err := deployments.GetConfigFromEnvironment()
 	if err != nil {
 		glog.Fatalf("Error getting zeroscaler envconfig: %s", err.Error())
 	}
 
+	zeroscaler, err := deployments.NewZeroscaler(
+		cfg,
+		client,
+		dynamicClient,
+		workloadsClient,
+	)
+	if err != nil {
+		glog.Fatalf("Error initializing zeroscaler: %s", err.Error())
+	}
+
 	// Run the zeroscaler
-	deployments.NewZeroscaler(cfg, client).Run(ctx)
+	zeroscaler.Run(ctx)
 }
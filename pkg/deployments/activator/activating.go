@@ -1,58 +1,131 @@
 package activator
 
 import (
-	"encoding/json"
+	"fmt"
+	"time"
 
 	"github.com/deislabs/osiris/pkg/kubernetes"
 	"github.com/golang/glog"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/labels"
-	k8s_types "k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/cache"
 )
 
-func (a *activator) activateDeployment(
+func (a *activator) activateApp(
 	app *app,
-) (*deploymentActivation, error) {
-	deploymentsClient := a.kubeClient.AppsV1().Deployments(app.namespace)
-	deployment, err := deploymentsClient.Get(
-		app.deploymentName,
-		metav1.GetOptions{},
-	)
+	requestTime time.Time,
+) (*appActivation, error) {
+	workload, err := a.workloadsClient.Get(app.namespace, app.workload)
+	if err != nil {
+		return nil, err
+	}
+	// Whatever the kind of workload, its scale subresource tells us how many
+	// replicas are desired and which pods belong to it.
+	scale, err := a.workloadsClient.GetScale(app.namespace, app.workload)
+	if err != nil {
+		return nil, err
+	}
+	selector, err := labels.Parse(scale.Status.Selector)
 	if err != nil {
 		return nil, err
 	}
-	da := &deploymentActivation{
-		readyAppPodIPs: map[string]struct{}{},
-		successCh:      make(chan struct{}),
-		timeoutCh:      make(chan struct{}),
+	if selector.Empty() {
+		return nil, fmt.Errorf(
+			"The scale subresource of %s does not indicate a label selector for "+
+				"its pods",
+			app.workload,
+		)
+	}
+	aa := &appActivation{
+		readyAppPodIPs:  map[string]struct{}{},
+		successCh:       make(chan struct{}),
+		timeoutCh:       make(chan struct{}),
+		requestTime:     requestTime,
+		startTime:       time.Now(),
+		eventRecorder:   a.eventRecorder,
+		workloadsClient: a.workloadsClient,
+		involvedObjects: []*corev1.ObjectReference{
+			app.workload.ObjectReference(app.namespace, workload.GetUID()),
+			app.serviceObjectRef(),
+		},
 	}
 	glog.Infof(
-		"Activating deployment %s in namespace %s",
-		app.deploymentName,
+		"Activating %s in namespace %s",
+		app.workload,
 		app.namespace,
 	)
-	go da.watchForCompletion(
-		a.kubeClient,
-		app,
-		labels.Set(deployment.Spec.Selector.MatchLabels).AsSelector(),
-	)
-	if deployment.Spec.Replicas == nil || *deployment.Spec.Replicas > 0 {
+	// Subscribe to the app's pods and endpoints before scaling, so no change is
+	// missed
+	sub := a.informers.subscribe(app, selector, aa)
+	if scale.Spec.Replicas > 0 {
 		// We don't need to do this, as it turns out! Scaling is either already
 		// in progress-- perhaps initiated by another process-- or may even be
-		// completed already. Just return dr and allow the caller to move on to
+		// completed already. Just return aa and allow the caller to move on to
 		// verifying / waiting for this activation to be complete.
-		return da, nil
-	}
-	patches := []kubernetes.PatchOperation{{
-		Op:    "replace",
-		Path:  "/spec/replicas",
-		Value: kubernetes.GetMinReplicas(deployment.Annotations, 1),
-	}}
-	patchesBytes, _ := json.Marshal(patches)
-	_, err = deploymentsClient.Patch(
-		app.deploymentName,
-		k8s_types.JSONPatchType,
-		patchesBytes,
+		go aa.watchForCompletion(a.informers, sub, app)
+		return aa, nil
+	}
+	replicas := kubernetes.GetActivationReplicas(
+		a.defaults.Apply(app.namespace, workload),
+		1,
 	)
-	return da, err
+	// If a horizontal pod autoscaler manages the app, scale directly to the
+	// autoscaler's minimum, since it would immediately do so itself anyway.
+	// The autoscaler disables itself while its target has zero replicas and
+	// resumes once we have scaled the target up.
+	if hpaMinReplicas :=
+		kubernetes.GetHPAMinReplicas(
+			a.getWorkloadHPA(app),
+			replicas,
+		); hpaMinReplicas > replicas {
+		replicas = hpaMinReplicas
+	}
+	err = a.workloadsClient.Scale(app.namespace, app.workload, replicas)
+	if err != nil {
+		// The activation is abandoned, so there is nothing to watch for
+		a.informers.unsubscribe(sub)
+		aa.recordEvent(
+			corev1.EventTypeWarning,
+			activationFailedEventReason,
+			"Error scaling %s from zero to %d replica(s): %s",
+			app.workload,
+			replicas,
+			err,
+		)
+		return nil, err
+	}
+	go aa.watchForCompletion(a.informers, sub, app)
+	aa.recordEvent(
+		corev1.EventTypeNormal,
+		activationStartedEventReason,
+		"Scaling %s from zero to %d replica(s) to serve a pending request",
+		app.workload,
+		replicas,
+	)
+	return aa, nil
+}
+
+// getWorkloadHPA returns the horizontal pod autoscaler that targets the app's
+// workload, or nil if there is none or if autoscalers could not be listed.
+func (a *activator) getWorkloadHPA(
+	app *app,
+) *autoscalingv1.HorizontalPodAutoscaler {
+	objs, err := a.hpasInformer.GetIndexer().ByIndex(
+		cache.NamespaceIndex,
+		app.namespace,
+	)
+	if err != nil {
+		glog.Errorf(
+			"Error listing horizontal pod autoscalers in namespace %s: %s",
+			app.namespace,
+			err,
+		)
+		return nil
+	}
+	hpas := make([]*autoscalingv1.HorizontalPodAutoscaler, len(objs))
+	for i, obj := range objs {
+		hpas[i] = obj.(*autoscalingv1.HorizontalPodAutoscaler)
+	}
+	return kubernetes.GetWorkloadHPA(hpas, app.workload)
 }
@@ -0,0 +1,142 @@
+package activator
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	k8s "github.com/deislabs/osiris/pkg/kubernetes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
+)
+
+type fakeWorkloadsClient struct {
+	scale    *autoscalingv1.Scale
+	scaleErr error
+}
+
+func (f *fakeWorkloadsClient) ResourceFor(
+	schema.GroupVersionKind,
+) (schema.GroupVersionResource, error) {
+	return schema.GroupVersionResource{}, nil
+}
+
+func (f *fakeWorkloadsClient) Get(
+	string,
+	k8s.WorkloadReference,
+) (*unstructured.Unstructured, error) {
+	return &unstructured.Unstructured{}, nil
+}
+
+func (f *fakeWorkloadsClient) GetScale(
+	string,
+	k8s.WorkloadReference,
+) (*autoscalingv1.Scale, error) {
+	return f.scale, nil
+}
+
+func (f *fakeWorkloadsClient) Scale(
+	_ string,
+	_ k8s.WorkloadReference,
+	replicas int32,
+) error {
+	if f.scaleErr != nil {
+		return f.scaleErr
+	}
+	f.scale.Spec.Replicas = replicas
+	return nil
+}
+
+func (f *fakeWorkloadsClient) Annotate(
+	string,
+	k8s.WorkloadReference,
+	map[string]string,
+) error {
+	return nil
+}
+
+func (f *fakeWorkloadsClient) RemoveAnnotations(
+	string,
+	k8s.WorkloadReference,
+	...string,
+) error {
+	return nil
+}
+
+func newTestIndexInformer(objType runtime.Object) cache.SharedIndexInformer {
+	return cache.NewSharedIndexInformer(
+		&cache.ListWatch{},
+		objType,
+		0,
+		cache.Indexers{
+			cache.NamespaceIndex: cache.MetaNamespaceIndexFunc,
+		},
+	)
+}
+
+func TestActivateAppScaleFailure(t *testing.T) {
+	eventRecorder := record.NewFakeRecorder(10)
+	// The namespace's informers are retained, so they are neither started nor
+	// stopped by subscribing to or unsubscribing from them
+	n := &namespaceInformers{
+		podsInformer:      newTestIndexInformer(&corev1.Pod{}),
+		endpointsInformer: newTestIndexInformer(&corev1.Endpoints{}),
+		subscriptions:     map[*activationSubscription]struct{}{},
+		retained:          true,
+	}
+	a := &activator{
+		workloadsClient: &fakeWorkloadsClient{
+			scale: &autoscalingv1.Scale{
+				Status: autoscalingv1.ScaleStatus{
+					Selector: "app=my-app",
+				},
+			},
+			scaleErr: errors.New("boom"),
+		},
+		defaults: &k8s.ResourceDefaults{
+			Namespaces: &k8s.NamespaceDefaults{
+				SharedIndexInformer: newTestIndexInformer(&corev1.Namespace{}),
+			},
+		},
+		hpasInformer: newTestIndexInformer(
+			&autoscalingv1.HorizontalPodAutoscaler{},
+		),
+		informers: &sharedInformers{
+			namespaces: map[string]*namespaceInformers{
+				"default": n,
+			},
+		},
+		eventRecorder: eventRecorder,
+	}
+	app := &app{
+		namespace:         "default",
+		serviceName:       "my-app",
+		workload:          k8s.DeploymentReference("my-app"),
+		activationTimeout: 10 * time.Millisecond,
+	}
+
+	aa, err := a.activateApp(app, time.Now())
+	require.Error(t, err)
+	assert.Nil(t, aa)
+	// The activation no longer follows the app's pods and endpoints
+	assert.Empty(t, n.subscriptions)
+
+	// Once the activation would have timed out, only the failure has been
+	// recorded, on both the workload and the service
+	time.Sleep(5 * app.activationTimeout)
+	close(eventRecorder.Events)
+	var events []string
+	for event := range eventRecorder.Events {
+		events = append(events, event)
+	}
+	expectedEvent := "Warning ActivationFailed Error scaling deployment my-app " +
+		"from zero to 1 replica(s): boom"
+	assert.Equal(t, []string{expectedEvent, expectedEvent}, events)
+}
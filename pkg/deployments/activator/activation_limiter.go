@@ -0,0 +1,145 @@
+package activator
+
+import (
+	"sync"
+	"time"
+)
+
+// activationLimiter caps how many apps may be activated at once. Apps over the
+// limit are queued and admitted in the order in which they were first
+// requested. Each app holds a single place in the queue, however many requests
+// for it are waiting, so that an app receiving a flood of requests can't
+// starve others.
+type activationLimiter struct {
+	// maxInFlight is the maximum number of concurrent activations. If it isn't
+	// greater than zero, there is no limit.
+	maxInFlight int
+	inFlight    int
+	// queue holds the keys of the apps waiting to be admitted, in order
+	queue []string
+	// waiters holds, for each queued app, a channel that is closed once the app
+	// is admitted
+	waiters map[string]chan struct{}
+	// queuedTimes holds, for each queued app, when it was queued
+	queuedTimes map[string]time.Time
+	// waiting holds, for each queued app and for each app that was admitted,
+	// but whose activation hasn't been started yet, how many callers are
+	// waiting for it to be admitted and haven't abandoned it
+	waiting map[string]int
+	// admitted holds the keys of apps that were admitted from the queue, but
+	// whose activation hasn't been started yet
+	admitted map[string]struct{}
+	lock     sync.Mutex
+}
+
+func newActivationLimiter(maxInFlight int) *activationLimiter {
+	return &activationLimiter{
+		maxInFlight: maxInFlight,
+		waiters:     map[string]chan struct{}{},
+		queuedTimes: map[string]time.Time{},
+		waiting:     map[string]int{},
+		admitted:    map[string]struct{}{},
+	}
+}
+
+// tryAcquire reserves one of the available activation slots for the app with
+// the given key and returns true, unless there is none or other apps are ahead
+// of it in the queue. In that case, it queues the app, if it isn't queued
+// already, and returns a channel that is closed once the app is admitted.
+// Callers must then call tryAcquire again or, if they stop waiting before the
+// app is admitted, abandon. Every successful call must eventually be followed
+// by a call to release.
+func (l *activationLimiter) tryAcquire(appKey string) (bool, <-chan struct{}) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if _, ok := l.admitted[appKey]; ok {
+		// The slot was already counted as in flight upon admission
+		delete(l.admitted, appKey)
+		delete(l.waiting, appKey)
+		return true, nil
+	}
+	if waiter, ok := l.waiters[appKey]; ok {
+		l.waiting[appKey]++
+		return false, waiter
+	}
+	if l.maxInFlight <= 0 ||
+		(l.inFlight < l.maxInFlight && len(l.queue) == 0) {
+		l.inFlight++
+		activationsInFlight.Set(float64(l.inFlight))
+		return true, nil
+	}
+	waiter := make(chan struct{})
+	l.waiters[appKey] = waiter
+	l.queuedTimes[appKey] = time.Now()
+	l.waiting[appKey] = 1
+	l.queue = append(l.queue, appKey)
+	activationsQueued.Set(float64(len(l.queue)))
+	activationsQueuedTotal.Inc()
+	return false, waiter
+}
+
+// queued returns true if the app with the given key is waiting to be admitted.
+func (l *activationLimiter) queued(appKey string) bool {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	_, ok := l.waiters[appKey]
+	return ok
+}
+
+// abandon is called by a caller that stops waiting for the app with the given
+// key to be admitted. Once all callers waiting for the app have abandoned it,
+// it leaves the queue or, if it was admitted already, frees its slot, lest the
+// slot be held by an activation that is never started.
+func (l *activationLimiter) abandon(appKey string) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if l.waiting[appKey] > 1 {
+		l.waiting[appKey]--
+		return
+	}
+	delete(l.waiting, appKey)
+	if _, ok := l.admitted[appKey]; ok {
+		delete(l.admitted, appKey)
+		l.releaseLocked()
+		return
+	}
+	if _, ok := l.waiters[appKey]; !ok {
+		return
+	}
+	for i, queuedAppKey := range l.queue {
+		if queuedAppKey == appKey {
+			l.queue = append(l.queue[:i], l.queue[i+1:]...)
+			break
+		}
+	}
+	delete(l.waiters, appKey)
+	delete(l.queuedTimes, appKey)
+	activationsQueued.Set(float64(len(l.queue)))
+}
+
+// release frees an activation slot and admits as many queued apps as there are
+// slots available.
+func (l *activationLimiter) release() {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.releaseLocked()
+}
+
+// releaseLocked is like release, but callers must hold l.lock.
+func (l *activationLimiter) releaseLocked() {
+	l.inFlight--
+	for len(l.queue) > 0 && l.inFlight < l.maxInFlight {
+		appKey := l.queue[0]
+		l.queue = l.queue[1:]
+		l.inFlight++
+		l.admitted[appKey] = struct{}{}
+		close(l.waiters[appKey])
+		delete(l.waiters, appKey)
+		activationQueueWaitSeconds.Observe(
+			time.Since(l.queuedTimes[appKey]).Seconds(),
+		)
+		delete(l.queuedTimes, appKey)
+	}
+	activationsInFlight.Set(float64(l.inFlight))
+	activationsQueued.Set(float64(len(l.queue)))
+}
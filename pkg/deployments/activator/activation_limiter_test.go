@@ -0,0 +1,87 @@
+package activator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func isClosed(ch <-chan struct{}) bool {
+	select {
+	case <-ch:
+		return true
+	default:
+		return false
+	}
+}
+
+func TestActivationLimiterUnlimited(t *testing.T) {
+	l := newActivationLimiter(0)
+	for _, appKey := range []string{"a", "b", "c"} {
+		admitted, admittedCh := l.tryAcquire(appKey)
+		assert.True(t, admitted)
+		assert.Nil(t, admittedCh)
+	}
+}
+
+func TestActivationLimiterQueuesAppsInOrder(t *testing.T) {
+	l := newActivationLimiter(1)
+
+	admitted, _ := l.tryAcquire("a")
+	require.True(t, admitted)
+
+	admitted, bCh := l.tryAcquire("b")
+	require.False(t, admitted)
+	// Further requests for a queued app share its place in the queue
+	admitted, bCh2 := l.tryAcquire("b")
+	require.False(t, admitted)
+	assert.Equal(t, bCh, bCh2)
+	admitted, cCh := l.tryAcquire("c")
+	require.False(t, admitted)
+
+	l.release()
+	assert.True(t, isClosed(bCh))
+	assert.False(t, isClosed(cCh))
+	// c is still queued behind b, which now holds the only slot
+	admitted, _ = l.tryAcquire("c")
+	assert.False(t, admitted)
+	admitted, _ = l.tryAcquire("b")
+	assert.True(t, admitted)
+
+	l.release()
+	assert.True(t, isClosed(cCh))
+	admitted, _ = l.tryAcquire("c")
+	assert.True(t, admitted)
+	// A new app must wait for c's activation to complete
+	admitted, _ = l.tryAcquire("d")
+	assert.False(t, admitted)
+}
+
+func TestActivationLimiterAbandon(t *testing.T) {
+	l := newActivationLimiter(1)
+
+	admitted, _ := l.tryAcquire("a")
+	require.True(t, admitted)
+	admitted, bCh := l.tryAcquire("b")
+	require.False(t, admitted)
+	admitted, _ = l.tryAcquire("b")
+	require.False(t, admitted)
+	admitted, cCh := l.tryAcquire("c")
+	require.False(t, admitted)
+
+	// b remains queued until both requests waiting for it abandon it
+	l.abandon("b")
+	assert.True(t, l.queued("b"))
+	l.abandon("b")
+	assert.False(t, l.queued("b"))
+
+	l.release()
+	assert.False(t, isClosed(bCh))
+	assert.True(t, isClosed(cCh))
+	// c was admitted, but the request waiting for it abandoned it anyway, so
+	// its slot is freed for others
+	l.abandon("c")
+	admitted, _ = l.tryAcquire("d")
+	assert.True(t, admitted)
+}
@@ -0,0 +1,103 @@
+package activator
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/golang/glog"
+)
+
+// Possible states of an app's activation, as reported by the activation status
+// endpoint
+const (
+	// activationStateInactive indicates the app hasn't been activated by this
+	// activator since it was started
+	activationStateInactive = "inactive"
+	// activationStateQueued indicates the app is waiting to be activated
+	// because too many apps are being activated already
+	activationStateQueued = "queued"
+	// activationStateActivating indicates the app is being activated
+	activationStateActivating = "activating"
+	// activationStateSucceeded indicates the app's last activation succeeded
+	activationStateSucceeded = "succeeded"
+	// activationStateTimedOut indicates the app's last activation timed out
+	activationStateTimedOut = "timedOut"
+)
+
+// appActivationResult records how an app's last activation turned out
+type appActivationResult struct {
+	state     string
+	startTime time.Time
+	endTime   time.Time
+}
+
+// activationStatus is the body of responses from the activation status
+// endpoint
+type activationStatus struct {
+	Host           string     `json:"host"`
+	Namespace      string     `json:"namespace"`
+	Service        string     `json:"service"`
+	Workload       string     `json:"workload"`
+	State          string     `json:"state"`
+	StartTime      *time.Time `json:"startTime,omitempty"`
+	EndTime        *time.Time `json:"endTime,omitempty"`
+	ReadyPods      int        `json:"readyPods"`
+	TimeoutSeconds int        `json:"timeoutSeconds"`
+}
+
+// getActivationStatus returns the status of the activation of the app
+// addressed by the given host, or false if no app is.
+func (a *activator) getActivationStatus(
+	hostname string,
+) (activationStatus, bool) {
+	a.indicesLock.RLock()
+	app, ok := a.appsByHost[hostname]
+	a.indicesLock.RUnlock()
+	if !ok {
+		return activationStatus{}, false
+	}
+	status := activationStatus{
+		Host:           hostname,
+		Namespace:      app.namespace,
+		Service:        app.serviceName,
+		Workload:       app.workload.String(),
+		State:          activationStateInactive,
+		TimeoutSeconds: int(app.activationTimeout.Seconds()),
+	}
+	appKey := getAppKey(app)
+	a.appActivationsLock.Lock()
+	appActivation, inProgress := a.appActivations[appKey]
+	result, done := a.appActivationResults[appKey]
+	a.appActivationsLock.Unlock()
+	switch {
+	case inProgress:
+		status.State = activationStateActivating
+		status.StartTime = &appActivation.startTime
+		status.ReadyPods = appActivation.readyPods()
+	case a.activationLimiter.queued(appKey):
+		status.State = activationStateQueued
+	case done:
+		status.State = result.state
+		status.StartTime = &result.startTime
+		status.EndTime = &result.endTime
+	}
+	return status, true
+}
+
+// handleActivationStatusRequest reports the status of the activation of the
+// app addressed by the host in the request's host query parameter.
+func (a *activator) handleActivationStatusRequest(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	status, ok := a.getActivationStatus(r.URL.Query().Get("host"))
+	if !ok {
+		http.Error(w, "", http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(status); err != nil {
+		glog.Errorf("Error writing activation status response: %s", err)
+	}
+}
@@ -0,0 +1,109 @@
+package activator
+
+import (
+	"testing"
+	"time"
+
+	k8s "github.com/deislabs/osiris/pkg/kubernetes"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetActivationStatus(t *testing.T) {
+	startTime := time.Date(2019, 1, 7, 17, 0, 0, 0, time.UTC)
+	endTime := startTime.Add(time.Minute)
+	newApp := func(name string) *app {
+		return &app{
+			namespace:         "default",
+			serviceName:       name,
+			workload:          k8s.DeploymentReference(name),
+			activationTimeout: 2 * time.Minute,
+		}
+	}
+	activatingApp := newApp("activating")
+	succeededApp := newApp("succeeded")
+	a := &activator{
+		appsByHost: map[string]*app{
+			"activating": activatingApp,
+			"succeeded":  succeededApp,
+			"inactive":   newApp("inactive"),
+		},
+		appActivations: map[string]*appActivation{
+			getAppKey(activatingApp): {
+				startTime: startTime,
+				readyAppPodIPs: map[string]struct{}{
+					"10.0.0.1": {},
+				},
+			},
+		},
+		appActivationResults: map[string]appActivationResult{
+			getAppKey(succeededApp): {
+				state:     activationStateSucceeded,
+				startTime: startTime,
+				endTime:   endTime,
+			},
+		},
+		activationLimiter: newActivationLimiter(0),
+	}
+	testcases := []struct {
+		name           string
+		host           string
+		expectedOK     bool
+		expectedStatus activationStatus
+	}{
+		{
+			name:       "unknown host",
+			host:       "unknown",
+			expectedOK: false,
+		},
+		{
+			name:       "inactive app",
+			host:       "inactive",
+			expectedOK: true,
+			expectedStatus: activationStatus{
+				Host:           "inactive",
+				Namespace:      "default",
+				Service:        "inactive",
+				Workload:       "deployment inactive",
+				State:          activationStateInactive,
+				TimeoutSeconds: 120,
+			},
+		},
+		{
+			name:       "app being activated",
+			host:       "activating",
+			expectedOK: true,
+			expectedStatus: activationStatus{
+				Host:           "activating",
+				Namespace:      "default",
+				Service:        "activating",
+				Workload:       "deployment activating",
+				State:          activationStateActivating,
+				StartTime:      &startTime,
+				ReadyPods:      1,
+				TimeoutSeconds: 120,
+			},
+		},
+		{
+			name:       "app activated",
+			host:       "succeeded",
+			expectedOK: true,
+			expectedStatus: activationStatus{
+				Host:           "succeeded",
+				Namespace:      "default",
+				Service:        "succeeded",
+				Workload:       "deployment succeeded",
+				State:          activationStateSucceeded,
+				StartTime:      &startTime,
+				EndTime:        &endTime,
+				TimeoutSeconds: 120,
+			},
+		},
+	}
+	for _, test := range testcases {
+		t.Run(test.name, func(t *testing.T) {
+			status, ok := a.getActivationStatus(test.host)
+			assert.Equal(t, test.expectedOK, ok)
+			assert.Equal(t, test.expectedStatus, status)
+		})
+	}
+}
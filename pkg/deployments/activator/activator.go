@@ -3,8 +3,10 @@ package activator
 import (
 	"context"
 	"fmt"
+	"html/template"
 	"net/http"
 	"sync"
+	"time"
 
 	"github.com/deislabs/osiris/pkg/healthz"
 	k8s "github.com/deislabs/osiris/pkg/kubernetes"
@@ -12,8 +14,10 @@ import (
 	"github.com/golang/glog"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
 )
 
 type Activator interface {
@@ -22,22 +26,40 @@ type Activator interface {
 
 type activator struct {
 	kubeClient                kubernetes.Interface
+	workloadsClient           k8s.WorkloadsClient
+	eventRecorder             record.EventRecorder
 	servicesInformer          cache.SharedIndexInformer
 	nodeInformer              cache.SharedIndexInformer
+	hpasInformer              cache.SharedIndexInformer
+	informers                 *sharedInformers
+	defaults                  *k8s.ResourceDefaults
 	services                  map[string]*corev1.Service
 	nodeAddresses             map[string]struct{}
 	appsByHost                map[string]*app
 	indicesLock               sync.RWMutex
-	deploymentActivations     map[string]*deploymentActivation
-	deploymentActivationsLock sync.Mutex
+	appActivations            map[string]*appActivation
+	appActivationResults      map[string]appActivationResult
+	backgroundActivations     map[string]struct{}
+	appActivationsLock        sync.Mutex
+	activationLimiter         *activationLimiter
+	activationTimeout         time.Duration
+	pendingRequests           *pendingRequests
+	wakingUpPageTemplate      *template.Template
 	dynamicProxyListenAddrStr string
 	dynamicProxy              tcp.DynamicProxy
 }
 
-func NewActivator(kubeClient kubernetes.Interface) (Activator, error) {
+func NewActivator(
+	cfg Config,
+	kubeClient kubernetes.Interface,
+	dynamicClient dynamic.Interface,
+	workloadsClient k8s.WorkloadsClient,
+) (Activator, error) {
 	const port = 5000
 	a := &activator{
-		kubeClient: kubeClient,
+		kubeClient:      kubeClient,
+		workloadsClient: workloadsClient,
+		eventRecorder:   k8s.NewEventRecorder(kubeClient, "osiris-activator"),
 		servicesInformer: k8s.ServicesIndexInformer(
 			kubeClient,
 			metav1.NamespaceAll,
@@ -50,23 +72,50 @@ func NewActivator(kubeClient kubernetes.Interface) (Activator, error) {
 			nil,
 			nil,
 		),
+		hpasInformer: k8s.HorizontalPodAutoscalersIndexInformer(
+			kubeClient,
+			metav1.NamespaceAll,
+			nil,
+			nil,
+		),
+		informers: newSharedInformers(kubeClient),
+		defaults: k8s.NewResourceDefaults(
+			kubeClient,
+			dynamicClient,
+		),
 		dynamicProxyListenAddrStr: fmt.Sprintf(":%d", port),
 		services:                  map[string]*corev1.Service{},
 		nodeAddresses:             map[string]struct{}{},
 		appsByHost:                map[string]*app{},
-		deploymentActivations:     map[string]*deploymentActivation{},
+		appActivations:            map[string]*appActivation{},
+		appActivationResults:      map[string]appActivationResult{},
+		backgroundActivations:     map[string]struct{}{},
+		activationLimiter: newActivationLimiter(
+			cfg.MaxConcurrentActivations,
+		),
+		activationTimeout: cfg.ActivationTimeout,
+		pendingRequests: newPendingRequests(
+			cfg.MaxPendingRequests,
+			cfg.MaxPendingRequestsPerApp,
+		),
 	}
 	var err error
+	a.wakingUpPageTemplate, err =
+		getWakingUpPageTemplate(cfg.WakingUpPageTemplateFile)
+	if err != nil {
+		return nil, err
+	}
 	a.dynamicProxy, err = tcp.NewDynamicProxy(
 		a.dynamicProxyListenAddrStr,
-		func(r *http.Request) (string, int, error) {
-			return a.activateAndWait(r.Host)
-		},
+		a.handleHTTPRequest,
+		nil,
 		nil,
 		func(serverName string) (string, int, error) {
+			requestsTotal.WithLabelValues(requestLayerL4).Inc()
 			return a.activateAndWait(fmt.Sprintf("%s:tls", serverName))
 		},
 		nil,
+		nil,
 	)
 	if err != nil {
 		return nil, err
@@ -85,6 +134,7 @@ func NewActivator(kubeClient kubernetes.Interface) (Activator, error) {
 		},
 		DeleteFunc: a.syncDeletedNode,
 	})
+	a.defaults.AddNamespaceEventHandler(a.syncNamespace)
 	return a, nil
 }
 
@@ -103,6 +153,16 @@ func (a *activator) Run(ctx context.Context) {
 		a.nodeInformer.Run(ctx.Done())
 		cancel()
 	}()
+	go func() {
+		a.hpasInformer.Run(ctx.Done())
+		cancel()
+	}()
+	for _, informer := range a.defaults.Informers() {
+		go func(informer cache.SharedIndexInformer) {
+			informer.Run(ctx.Done())
+			cancel()
+		}(informer)
+	}
 	go func() {
 		glog.Infof(
 			"Activator server is listening on %s, proxying all deactivated, "+
@@ -114,7 +174,13 @@ func (a *activator) Run(ctx context.Context) {
 		}
 		cancel()
 	}()
-	healthz.RunServer(ctx, 5001)
+	healthz.RunServerWithHandlers(
+		ctx,
+		5001,
+		map[string]http.Handler{
+			"/activations": http.HandlerFunc(a.handleActivationStatusRequest),
+		},
+	)
 	cancel()
 }
 
@@ -123,7 +189,12 @@ func (a *activator) syncService(obj interface{}) {
 	defer a.indicesLock.Unlock()
 	svc := obj.(*corev1.Service)
 	svcKey := getKey(svc.Namespace, svc.Name)
-	if k8s.ResourceIsOsirisEnabled(svc.Annotations) {
+	annotations := a.defaults.ApplyToService(svc.Namespace, svc)
+	if k8s.ResourceIsOsirisEnabled(annotations) {
+		// Index the service by its effective annotations, so that hostnames
+		// inherited from its scale to zero policy are honored
+		svc = svc.DeepCopy()
+		svc.Annotations = annotations
 		a.services[svcKey] = svc
 	} else {
 		delete(a.services, svcKey)
@@ -140,6 +211,19 @@ func (a *activator) syncDeletedService(obj interface{}) {
 	a.updateIndex()
 }
 
+// syncNamespace is notified of every namespace in which the defaults that
+// services inherit from their namespace or scale to zero policy may have
+// changed. Whether the services in such a namespace are Osiris-enabled, and
+// how they are addressed, may depend on those defaults, so they are synced
+// again.
+func (a *activator) syncNamespace(namespace string) {
+	for _, svcObj := range a.servicesInformer.GetStore().List() {
+		if svcObj.(*corev1.Service).Namespace == namespace {
+			a.syncService(svcObj)
+		}
+	}
+}
+
 func (a *activator) syncNode(obj interface{}) {
 	a.indicesLock.Lock()
 	defer a.indicesLock.Unlock()
@@ -1,9 +1,34 @@
 package activator
 
+import (
+	"time"
+
+	k8s "github.com/deislabs/osiris/pkg/kubernetes"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
 type app struct {
-	namespace      string
-	serviceName    string
-	deploymentName string
-	targetHost     string
-	targetPort     int
+	namespace   string
+	serviceName string
+	serviceUID  types.UID
+	workload    k8s.WorkloadReference
+	targetHost  string
+	targetPort  int
+	// activationTimeout is how long activating the app's workload may take
+	// before requests waiting for it are turned away
+	activationTimeout time.Duration
+	// wakingUpPage indicates whether browsers are shown a waking up page while
+	// the app is activated
+	wakingUpPage bool
+}
+
+func (a *app) serviceObjectRef() *corev1.ObjectReference {
+	return &corev1.ObjectReference{
+		APIVersion: "v1",
+		Kind:       "Service",
+		Namespace:  a.namespace,
+		Name:       a.serviceName,
+		UID:        a.serviceUID,
+	}
 }
@@ -0,0 +1,184 @@
+package activator
+
+import (
+	"sync"
+	"time"
+
+	k8s "github.com/deislabs/osiris/pkg/kubernetes"
+	"github.com/golang/glog"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+const (
+	activationStartedEventReason   = "ActivationStarted"
+	activationSucceededEventReason = "ActivationSucceeded"
+	activationTimedOutEventReason  = "ActivationTimedOut"
+	activationFailedEventReason    = "ActivationFailed"
+)
+
+type appActivation struct {
+	readyAppPodIPs  map[string]struct{}
+	endpoints       *corev1.Endpoints
+	lock            sync.Mutex
+	successCh       chan struct{}
+	timeoutCh       chan struct{}
+	requestTime     time.Time
+	startTime       time.Time
+	eventRecorder   record.EventRecorder
+	workloadsClient k8s.WorkloadsClient
+	involvedObjects []*corev1.ObjectReference
+}
+
+// watchForCompletion waits for the activation to succeed or to time out, then
+// ends the activation's subscription to its app's pods and endpoints.
+func (a *appActivation) watchForCompletion(
+	informers *sharedInformers,
+	sub *activationSubscription,
+	app *app,
+) {
+	defer informers.unsubscribe(sub)
+	timer := time.NewTimer(app.activationTimeout)
+	defer timer.Stop()
+	for {
+		select {
+		case <-a.successCh:
+			duration := time.Since(a.startTime)
+			a.recordEvent(
+				corev1.EventTypeNormal,
+				activationSucceededEventReason,
+				"Activated %s in %s",
+				app.workload,
+				duration,
+			)
+			a.annotateWorkload(app, duration)
+			return
+		case <-timer.C:
+			glog.Errorf(
+				"Activation of %s in namespace %s timed out",
+				app.workload,
+				app.namespace,
+			)
+			a.recordEvent(
+				corev1.EventTypeWarning,
+				activationTimedOutEventReason,
+				"Activation of %s timed out after %s",
+				app.workload,
+				time.Since(a.startTime),
+			)
+			close(a.timeoutCh)
+			return
+		}
+	}
+}
+
+// recordEvent records an event pertaining to this activation on both the
+// workload being activated and the service in front of it.
+func (a *appActivation) recordEvent(
+	eventType string,
+	reason string,
+	messageFmt string,
+	args ...interface{},
+) {
+	for _, obj := range a.involvedObjects {
+		a.eventRecorder.Eventf(obj, eventType, reason, messageFmt, args...)
+	}
+}
+
+// annotateWorkload records when the app's workload was last activated and how
+// long that took, and removes any checkpoint of the zeroscaler's idle tracking
+// state, which predates the activation. These annotations are informational
+// only, so errors are logged, but otherwise ignored.
+func (a *appActivation) annotateWorkload(app *app, duration time.Duration) {
+	if err := a.workloadsClient.Annotate(
+		app.namespace,
+		app.workload,
+		map[string]string{
+			k8s.LastActivationAnnotationName: a.startTime.UTC().Format(
+				time.RFC3339,
+			),
+			k8s.LastActivationDurationAnnotationName: duration.String(),
+		},
+	); err != nil {
+		glog.Errorf(
+			"Error annotating %s in namespace %s: %s",
+			app.workload,
+			app.namespace,
+			err,
+		)
+	}
+	if err := a.workloadsClient.RemoveAnnotations(
+		app.namespace,
+		app.workload,
+		k8s.IdleStateAnnotationName,
+	); err != nil {
+		glog.Errorf(
+			"Error removing annotations from %s in namespace %s: %s",
+			app.workload,
+			app.namespace,
+			err,
+		)
+	}
+}
+
+func (a *appActivation) syncPod(obj interface{}) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	pod := obj.(*corev1.Pod)
+	var ready bool
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == corev1.PodReady {
+			if condition.Status == corev1.ConditionTrue {
+				ready = true
+			}
+			break
+		}
+	}
+	// Keep track of which pods are ready
+	if ready {
+		a.readyAppPodIPs[pod.Status.PodIP] = struct{}{}
+	} else {
+		delete(a.readyAppPodIPs, pod.Status.PodIP)
+	}
+	a.checkActivationComplete()
+}
+
+func (a *appActivation) syncDeletedPod(pod *corev1.Pod) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	delete(a.readyAppPodIPs, pod.Status.PodIP)
+}
+
+// readyPods returns the number of the app's pods known to be ready.
+func (a *appActivation) readyPods() int {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	return len(a.readyAppPodIPs)
+}
+
+func (a *appActivation) syncEndpoints(obj interface{}) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	a.endpoints = obj.(*corev1.Endpoints)
+	a.checkActivationComplete()
+}
+
+func (a *appActivation) checkActivationComplete() {
+	select {
+	case <-a.successCh:
+		// Notifications may keep coming until the activation unsubscribes
+		return
+	default:
+	}
+	if a.endpoints != nil {
+		for _, subset := range a.endpoints.Subsets {
+			for _, address := range subset.Addresses {
+				if _, ok := a.readyAppPodIPs[address.IP]; ok {
+					glog.Infof("App pod with ip %s is in service", address.IP)
+					close(a.successCh)
+					return
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package activator
+
+import (
+	"time"
+
+	"github.com/kelseyhightower/envconfig"
+)
+
+const envconfigPrefix = "OSIRIS_ACTIVATOR"
+
+// Config represents configuration options for the Osiris activator
+type Config struct {
+	// MaxConcurrentActivations, if greater than zero, caps how many apps may be
+	// activated at once. Apps over the limit are queued until an activation in
+	// progress completes or times out.
+	MaxConcurrentActivations int `envconfig:"MAX_CONCURRENT_ACTIVATIONS"`
+	// ActivationTimeout is how long activating an app may take before requests
+	// waiting for it are turned away. This can be overridden for individual
+	// services with an annotation.
+	ActivationTimeout time.Duration `envconfig:"ACTIVATION_TIMEOUT"`
+	// MaxPendingRequests, if greater than zero, caps how many requests may be
+	// waiting for apps to be activated at once. Requests over the limit are
+	// rejected.
+	MaxPendingRequests int `envconfig:"MAX_PENDING_REQUESTS"`
+	// MaxPendingRequestsPerApp, if greater than zero, caps how many requests
+	// may be waiting for any one app to be activated at once. Requests over the
+	// limit are rejected.
+	MaxPendingRequestsPerApp int `envconfig:"MAX_PENDING_REQUESTS_PER_APP"`
+	// WakingUpPageTemplateFile, if set, is the path to an HTML template for the
+	// page shown to browsers while apps that opted into it are activated
+	WakingUpPageTemplateFile string `envconfig:"WAKING_UP_PAGE_TEMPLATE_FILE"`
+}
+
+// NewConfigWithDefaults returns a Config object with default values already
+// applied. Callers are then free to set custom values for the remaining fields
+// and/or override default values.
+func NewConfigWithDefaults() Config {
+	return Config{
+		ActivationTimeout: 2 * time.Minute,
+	}
+}
+
+// GetConfigFromEnvironment returns configuration derived from environment
+// variables
+func GetConfigFromEnvironment() (Config, error) {
+	c := NewConfigWithDefaults()
+	err := envconfig.Process(envconfigPrefix, &c)
+	return c, err
+}
@@ -3,6 +3,9 @@ package activator
 import (
 	"fmt"
 	"regexp"
+
+	k8s "github.com/deislabs/osiris/pkg/kubernetes"
+	"github.com/golang/glog"
 )
 
 // nolint: lll
@@ -12,14 +15,25 @@ var (
 )
 
 // updateIndex builds an index that maps all the possible ways a service can be
-// addressed to application info that encapsulates details like which deployment
-// to activate and where to relay requests to after successful activation. The
-// new index replaces any old/existing index.
+// addressed to application info that encapsulates details like which
+// workload to activate and where to relay requests to after
+// successful activation. The new index replaces any old/existing index.
 func (a *activator) updateIndex() {
 	appsByHost := map[string]*app{}
+	namespaces := map[string]struct{}{}
 	for _, svc := range a.services {
-		if deploymentName, ok :=
-			svc.Annotations["osiris.deislabs.io/deployment"]; ok {
+		workload, ok, err := k8s.GetServiceWorkloadReference(svc.Annotations)
+		if err != nil {
+			glog.Errorf(
+				"Error determining workload for service %s in namespace %s: %s",
+				svc.Name,
+				svc.Namespace,
+				err,
+			)
+			continue
+		}
+		if ok {
+			namespaces[svc.Namespace] = struct{}{}
 			svcShortDNSName := fmt.Sprintf("%s.%s", svc.Name, svc.Namespace)
 			svcFullDNSName := fmt.Sprintf("%s.svc.cluster.local", svcShortDNSName)
 			// Determine the "default" ingress port. When a request arrives at the
@@ -87,14 +101,20 @@ func (a *activator) updateIndex() {
 					}
 				}
 			}
+			activationTimeout :=
+				k8s.GetActivationTimeout(svc.Annotations, a.activationTimeout)
+			wakingUpPage := k8s.WakingUpPageIsEnabled(svc.Annotations)
 			// For every port...
 			for _, port := range svc.Spec.Ports {
 				app := &app{
-					namespace:      svc.Namespace,
-					serviceName:    svc.Name,
-					deploymentName: deploymentName,
-					targetHost:     svc.Spec.ClusterIP,
-					targetPort:     int(port.Port),
+					namespace:         svc.Namespace,
+					serviceName:       svc.Name,
+					serviceUID:        svc.UID,
+					workload:          workload,
+					targetHost:        svc.Spec.ClusterIP,
+					targetPort:        int(port.Port),
+					activationTimeout: activationTimeout,
+					wakingUpPage:      wakingUpPage,
 				}
 				// If the port is 80, also index by hostname/IP sans port number...
 				if port.Port == 80 {
@@ -169,4 +189,7 @@ func (a *activator) updateIndex() {
 		}
 	}
 	a.appsByHost = appsByHost
+	// Keep informing activations about pods and endpoints in every namespace
+	// containing apps that may need to be activated
+	a.informers.syncNamespaces(namespaces)
 }
@@ -7,3 +7,11 @@ import (
 func getKey(namespace, name string) string {
 	return fmt.Sprintf("%s:%s", namespace, name)
 }
+
+func getAppKey(app *app) string {
+	return fmt.Sprintf(
+		"%s:%s",
+		app.workload.GroupVersionKind().GroupKind(),
+		getKey(app.namespace, app.workload.Name),
+	)
+}
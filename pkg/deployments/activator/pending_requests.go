@@ -0,0 +1,87 @@
+package activator
+
+import (
+	"sync"
+)
+
+// pendingRequests keeps count of the requests waiting for apps to be
+// activated, so that their number can be capped, both per app and overall.
+// For monitoring, requests are also counted by the host they were addressed
+// to.
+type pendingRequests struct {
+	// maxTotal is the maximum number of pending requests overall. If it isn't
+	// greater than zero, there is no limit.
+	maxTotal int
+	// maxPerApp is the maximum number of pending requests for any one app. If
+	// it isn't greater than zero, there is no limit.
+	maxPerApp int
+	total     int
+	byApp     map[string]int
+	byAppHost map[pendingRequestsKey]int
+	lock      sync.Mutex
+}
+
+// pendingRequestsKey identifies the requests for a single app that were
+// addressed to a single host
+type pendingRequestsKey struct {
+	appKey string
+	host   string
+}
+
+func newPendingRequests(maxTotal, maxPerApp int) *pendingRequests {
+	return &pendingRequests{
+		maxTotal:  maxTotal,
+		maxPerApp: maxPerApp,
+		byApp:     map[string]int{},
+		byAppHost: map[pendingRequestsKey]int{},
+	}
+}
+
+// add counts a new request for the given host waiting for the app, unless that
+// would exceed either limit, in which case it returns false. Every successful
+// call must eventually be followed by a call to done.
+func (p *pendingRequests) add(app *app, host string) bool {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	appKey := getAppKey(app)
+	if (p.maxTotal > 0 && p.total >= p.maxTotal) ||
+		(p.maxPerApp > 0 && p.byApp[appKey] >= p.maxPerApp) {
+		pendingRequestsRejectedTotal.WithLabelValues(
+			getWorkloadLabelValues(app)...,
+		).Inc()
+		return false
+	}
+	p.total++
+	p.byApp[appKey]++
+	key := pendingRequestsKey{appKey: appKey, host: host}
+	p.byAppHost[key]++
+	pendingRequestsGauge.WithLabelValues(
+		append(getWorkloadLabelValues(app), host)...,
+	).Set(float64(p.byAppHost[key]))
+	return true
+}
+
+// done stops counting a request for the given host that was waiting for the
+// app.
+func (p *pendingRequests) done(app *app, host string) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	appKey := getAppKey(app)
+	p.total--
+	p.byApp[appKey]--
+	if p.byApp[appKey] == 0 {
+		delete(p.byApp, appKey)
+	}
+	key := pendingRequestsKey{appKey: appKey, host: host}
+	p.byAppHost[key]--
+	labelValues := append(getWorkloadLabelValues(app), host)
+	if p.byAppHost[key] == 0 {
+		delete(p.byAppHost, key)
+		// Don't keep a series for every host ever requested
+		pendingRequestsGauge.DeleteLabelValues(labelValues...)
+		return
+	}
+	pendingRequestsGauge.WithLabelValues(labelValues...).Set(
+		float64(p.byAppHost[key]),
+	)
+}
@@ -0,0 +1,87 @@
+package activator
+
+import (
+	"testing"
+
+	k8s "github.com/deislabs/osiris/pkg/kubernetes"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPendingRequests(t *testing.T) {
+	newApp := func(name string) *app {
+		return &app{
+			namespace: "default",
+			workload:  k8s.DeploymentReference(name),
+		}
+	}
+	appA, appB, appC := newApp("a"), newApp("b"), newApp("c")
+	p := newPendingRequests(3, 2)
+
+	assert.True(t, p.add(appA, "my-host"))
+	assert.True(t, p.add(appA, "my-host"))
+	// Over the limit per app
+	assert.False(t, p.add(appA, "my-host"))
+	assert.True(t, p.add(appB, "my-host"))
+	// Over the overall limit
+	assert.False(t, p.add(appC, "my-host"))
+
+	p.done(appA, "my-host")
+	assert.True(t, p.add(appC, "my-host"))
+	assert.False(t, p.add(appB, "my-host"))
+	p.done(appB, "my-host")
+	p.done(appC, "my-host")
+	assert.True(t, p.add(appA, "my-host"))
+}
+
+func TestPendingRequestsUnlimited(t *testing.T) {
+	app := &app{
+		namespace: "default",
+		workload:  k8s.DeploymentReference("a"),
+	}
+	p := newPendingRequests(0, 0)
+	for i := 0; i < 100; i++ {
+		assert.True(t, p.add(app, "my-host"))
+	}
+}
+
+func TestPendingRequestsByHost(t *testing.T) {
+	app := &app{
+		namespace: "default",
+		workload:  k8s.DeploymentReference("a"),
+	}
+	appKey := getAppKey(app)
+	p := newPendingRequests(0, 0)
+	assert.True(t, p.add(app, "a.example.com"))
+	assert.True(t, p.add(app, "a.example.com"))
+	assert.True(t, p.add(app, "a.default.svc.cluster.local"))
+	assert.Equal(
+		t,
+		map[pendingRequestsKey]int{
+			{appKey: appKey, host: "a.example.com"}:               2,
+			{appKey: appKey, host: "a.default.svc.cluster.local"}: 1,
+		},
+		p.byAppHost,
+	)
+	p.done(app, "a.example.com")
+	p.done(app, "a.default.svc.cluster.local")
+	assert.Equal(
+		t,
+		map[pendingRequestsKey]int{
+			{appKey: appKey, host: "a.example.com"}: 1,
+		},
+		p.byAppHost,
+	)
+	// The series of a host with no more pending requests is gone
+	assert.False(
+		t,
+		pendingRequestsGauge.DeleteLabelValues(
+			append(getWorkloadLabelValues(app), "a.default.svc.cluster.local")...,
+		),
+	)
+	assert.True(
+		t,
+		pendingRequestsGauge.DeleteLabelValues(
+			append(getWorkloadLabelValues(app), "a.example.com")...,
+		),
+	)
+}
@@ -0,0 +1,143 @@
+package activator
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	metricsNamespace = "osiris"
+	metricsSubsystem = "activator"
+)
+
+// Possible results of an activation
+const (
+	activationResultSuccess = "success"
+	activationResultTimeout = "timeout"
+	activationResultError   = "error"
+)
+
+// Layers at which the activator may handle requests
+const (
+	requestLayerL4 = "l4"
+	requestLayerL7 = "l7"
+)
+
+var (
+	workloadLabels = []string{"namespace", "kind", "name"}
+
+	activationsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Subsystem: metricsSubsystem,
+			Name:      "activations_total",
+			Help:      "Number of activations, by result",
+		},
+		append(workloadLabels, "result"),
+	)
+	activationDurationSeconds = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Subsystem: metricsSubsystem,
+			Name:      "activation_duration_seconds",
+			Help: "Time from the first request for the workload to its first " +
+				"ready endpoint, for successful activations",
+			Buckets: []float64{.5, 1, 2.5, 5, 10, 20, 30, 60, 120, 300},
+		},
+		workloadLabels,
+	)
+	activationsInFlight = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Subsystem: metricsSubsystem,
+			Name:      "activations_in_flight",
+			Help:      "Number of apps being activated",
+		},
+	)
+	activationsQueued = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Subsystem: metricsSubsystem,
+			Name:      "activations_queued",
+			Help: "Number of apps waiting to be activated because the limit on " +
+				"concurrent activations was reached",
+		},
+	)
+	activationsQueuedTotal = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Subsystem: metricsSubsystem,
+			Name:      "activations_queued_total",
+			Help: "Number of activations that were queued because the limit on " +
+				"concurrent activations was reached",
+		},
+	)
+	activationQueueWaitSeconds = prometheus.NewHistogram(
+		prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Subsystem: metricsSubsystem,
+			Name:      "activation_queue_wait_seconds",
+			Help:      "Time queued activations spent waiting to be started",
+			Buckets:   []float64{.1, .5, 1, 2.5, 5, 10, 30, 60, 120, 300},
+		},
+	)
+	pendingRequestsGauge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Subsystem: metricsSubsystem,
+			Name:      "pending_requests",
+			Help: "Number of requests for the host waiting for the workload to " +
+				"be activated",
+		},
+		append(workloadLabels, "host"),
+	)
+	pendingRequestsRejectedTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Subsystem: metricsSubsystem,
+			Name:      "pending_requests_rejected_total",
+			Help: "Number of requests rejected because too many requests were " +
+				"already waiting for workloads to be activated",
+		},
+		workloadLabels,
+	)
+	unknownHostsTotal = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Subsystem: metricsSubsystem,
+			Name:      "unknown_hosts_total",
+			Help:      "Number of requests for hosts that match no known app",
+		},
+	)
+	requestsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Subsystem: metricsSubsystem,
+			Name:      "requests_total",
+			Help: "Number of requests handled, by layer (l4 for TLS connections " +
+				"or l7 for HTTP requests)",
+		},
+		[]string{"layer"},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(
+		activationsTotal,
+		activationDurationSeconds,
+		activationsInFlight,
+		activationsQueued,
+		activationsQueuedTotal,
+		activationQueueWaitSeconds,
+		pendingRequestsGauge,
+		pendingRequestsRejectedTotal,
+		unknownHostsTotal,
+		requestsTotal,
+	)
+}
+
+// getWorkloadLabelValues returns the values for workloadLabels that identify
+// the app's workload
+func getWorkloadLabelValues(app *app) []string {
+	groupKind := app.workload.GroupVersionKind().GroupKind()
+	return []string{app.namespace, groupKind.String(), app.workload.Name}
+}
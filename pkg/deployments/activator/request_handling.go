@@ -1,11 +1,37 @@
 package activator
 
 import (
+	"encoding/json"
 	"fmt"
+	"net/http"
+	"strconv"
+	"time"
 
+	mynethttp "github.com/deislabs/osiris/pkg/net/http"
 	"github.com/golang/glog"
 )
 
+// activationRetryAfter is how long clients are asked to wait before retrying
+// requests that were turned away while an app was being activated
+const activationRetryAfter = 10 * time.Second
+
+// Possible errors in the bodies of responses to requests that were turned away
+// while an app was being activated
+const (
+	errorActivationTimedOut     = "ActivationTimedOut"
+	errorTooManyPendingRequests = "TooManyPendingRequests"
+)
+
+// serviceUnavailableResponse is the body of responses to requests that were
+// turned away while an app was being activated
+type serviceUnavailableResponse struct {
+	Error             string `json:"error"`
+	Message           string `json:"message"`
+	Namespace         string `json:"namespace"`
+	Service           string `json:"service"`
+	RetryAfterSeconds int    `json:"retryAfterSeconds"`
+}
+
 func (a *activator) activateAndWait(hostname string) (string, int, error) {
 	glog.Infof("Request received for for host %s", hostname)
 
@@ -13,90 +39,207 @@ func (a *activator) activateAndWait(hostname string) (string, int, error) {
 	app, ok := a.appsByHost[hostname]
 	a.indicesLock.RUnlock()
 	if !ok {
+		unknownHostsTotal.Inc()
 		return "", 0, fmt.Errorf("No deployment found for host %s", hostname)
 	}
 
 	glog.Infof(
-		"Deployment %s in namespace %s may require activation",
-		app.deploymentName,
+		"%s in namespace %s may require activation",
+		app.workload,
 		app.namespace,
 	)
 
-	// Are we already activating the deployment in question?
-	var err error
-	deploymentKey := getKey(app.namespace, app.deploymentName)
-	deploymentActivation, ok := a.deploymentActivations[deploymentKey]
-	if ok {
-		glog.Infof(
-			"Found activation in-progress for deployment %s in namespace %s",
-			app.deploymentName,
+	// Turn the request away right away if too many requests are waiting
+	// already
+	if !a.pendingRequests.add(app, hostname) {
+		return "", 0, newTooManyPendingRequestsError(app)
+	}
+	defer a.pendingRequests.done(app, hostname)
+
+	appActivation, err := a.startActivation(app)
+	if _, ok := err.(*mynethttp.ResponseError); ok {
+		return "", 0, err
+	}
+	if err != nil {
+		return "", 0, fmt.Errorf(
+			"Error activating %s in namespace %s: %s",
+			app.workload,
 			app.namespace,
+			err,
 		)
-	} else {
-		func() {
-			a.deploymentActivationsLock.Lock()
-			defer a.deploymentActivationsLock.Unlock()
-			// Some other goroutine could have initiated activation of this deployment
-			// while we were waiting for the lock. Now that we have the lock, do we
-			// still need to do this?
-			deploymentActivation, ok = a.deploymentActivations[deploymentKey]
-			if ok {
-				glog.Infof(
-					"Found activation in-progress for deployment %s in namespace %s",
-					app.deploymentName,
-					app.namespace,
-				)
-				return
-			}
-			glog.Infof(
-				"Found NO activation in-progress for deployment %s in namespace %s",
-				app.deploymentName,
-				app.namespace,
-			)
-			// Initiate activation (or discover that it may already have been started
-			// by another activator process)
-			if deploymentActivation, err = a.activateDeployment(app); err != nil {
-				return
-			}
-			// Add it to the index of in-flight activation
-			a.deploymentActivations[deploymentKey] = deploymentActivation
-			// But remove it from that index when it's complete
-			go func() {
-				deleteActivation := func() {
-					a.deploymentActivationsLock.Lock()
-					defer a.deploymentActivationsLock.Unlock()
-					delete(a.deploymentActivations, deploymentKey)
-				}
-				select {
-				case <-deploymentActivation.successCh:
-					deleteActivation()
-				case <-deploymentActivation.timeoutCh:
-					deleteActivation()
-				}
-			}()
-		}()
-		if err != nil {
-			return "", 0, fmt.Errorf(
-				"Error activating deployment %s in namespace %s: %s",
-				app.deploymentName,
-				app.namespace,
-				err,
-			)
-		}
 	}
 
 	// Regardless of whether we just started an activation or found one already in
 	// progress, we need to wait for that activation to be completed... or fail...
 	// or time out.
 	select {
-	case <-deploymentActivation.successCh:
+	case <-appActivation.successCh:
 		return app.targetHost, app.targetPort, nil
-	case <-deploymentActivation.timeoutCh:
-		return "", 0, fmt.Errorf(
-			"Timed out waiting for activation of deployment %s in namespace %s: %s",
-			app.deploymentName,
+	case <-appActivation.timeoutCh:
+		return "", 0, newServiceUnavailableError(
+			app,
+			errorActivationTimedOut,
+			fmt.Errorf(
+				"Timed out waiting for activation of %s in namespace %s",
+				app.workload,
+				app.namespace,
+			),
+		)
+	}
+}
+
+// newServiceUnavailableError wraps an error that caused a request to be turned
+// away while the app was being activated. For HTTP requests, it results in a
+// 503 response that asks the client to retry later.
+func newServiceUnavailableError(app *app, errorName string, err error) error {
+	body, jsonErr := json.Marshal(serviceUnavailableResponse{
+		Error:             errorName,
+		Message:           err.Error(),
+		Namespace:         app.namespace,
+		Service:           app.serviceName,
+		RetryAfterSeconds: int(activationRetryAfter.Seconds()),
+	})
+	if jsonErr != nil {
+		return err
+	}
+	return &mynethttp.ResponseError{
+		Err:        err,
+		StatusCode: http.StatusServiceUnavailable,
+		Header: http.Header{
+			"Content-Type": []string{"application/json"},
+			"Retry-After": []string{
+				strconv.Itoa(int(activationRetryAfter.Seconds())),
+			},
+		},
+		Body: body,
+	}
+}
+
+// newTooManyPendingRequestsError returns the error that a request is turned
+// away with when too many requests are waiting for activation already.
+func newTooManyPendingRequestsError(app *app) error {
+	return newServiceUnavailableError(
+		app,
+		errorTooManyPendingRequests,
+		fmt.Errorf(
+			"Too many requests are waiting for activation of %s in namespace %s",
+			app.workload,
+			app.namespace,
+		),
+	)
+}
+
+// startActivation returns the activation in progress for the app, initiating
+// one if there is none. If too many apps are being activated already, it waits
+// for the app's turn first, but only for as long as activating the app may
+// take. If the app's turn doesn't come by then, the request is turned away.
+func (a *activator) startActivation(app *app) (*appActivation, error) {
+	requestTime := time.Now()
+	timer := time.NewTimer(app.activationTimeout)
+	defer timer.Stop()
+	for {
+		appActivation, admittedCh, err := a.initiateActivation(app, requestTime)
+		if admittedCh == nil {
+			return appActivation, err
+		}
+		// Too many apps are being activated already, so we wait our turn. By
+		// then, another request may have initiated activation of this app, so we
+		// check again.
+		glog.Infof(
+			"Activation of %s in namespace %s is queued",
+			app.workload,
 			app.namespace,
-			err,
 		)
+		select {
+		case <-admittedCh:
+		case <-timer.C:
+			a.activationLimiter.abandon(getAppKey(app))
+			return nil, newServiceUnavailableError(
+				app,
+				errorActivationTimedOut,
+				fmt.Errorf(
+					"Timed out waiting for activation of %s in namespace %s to be "+
+						"started",
+					app.workload,
+					app.namespace,
+				),
+			)
+		}
 	}
 }
+
+// initiateActivation returns the activation in progress for the app,
+// initiating one if there is none. If there is none and too many apps are
+// being activated already, the app is queued instead and a channel is returned
+// that is closed once the app's turn comes. The request time is when the
+// activation was first requested, for the purpose of measuring its duration.
+func (a *activator) initiateActivation(
+	app *app,
+	requestTime time.Time,
+) (*appActivation, <-chan struct{}, error) {
+	a.appActivationsLock.Lock()
+	defer a.appActivationsLock.Unlock()
+	appKey := getAppKey(app)
+	// Some other goroutine could have initiated activation of this app while we
+	// were waiting for the lock. Now that we have the lock, do we still need to
+	// do this?
+	appActivation, ok := a.appActivations[appKey]
+	if ok {
+		glog.Infof(
+			"Found activation in-progress for %s in namespace %s",
+			app.workload,
+			app.namespace,
+		)
+		return appActivation, nil, nil
+	}
+	if admitted, admittedCh := a.activationLimiter.tryAcquire(
+		appKey,
+	); !admitted {
+		return nil, admittedCh, nil
+	}
+	glog.Infof(
+		"Found NO activation in-progress for %s in namespace %s",
+		app.workload,
+		app.namespace,
+	)
+	// Initiate activation (or discover that it may already have been started by
+	// another activator process)
+	appActivation, err := a.activateApp(app, requestTime)
+	if err != nil {
+		activationsTotal.WithLabelValues(
+			append(getWorkloadLabelValues(app), activationResultError)...,
+		).Inc()
+		a.activationLimiter.release()
+		return nil, nil, err
+	}
+	// Add it to the index of in-flight activation
+	a.appActivations[appKey] = appActivation
+	// But remove it from that index when it's complete, remembering how it
+	// turned out
+	go func() {
+		deleteActivation := func(state string, result string) {
+			activationsTotal.WithLabelValues(
+				append(getWorkloadLabelValues(app), result)...,
+			).Inc()
+			a.appActivationsLock.Lock()
+			defer a.appActivationsLock.Unlock()
+			delete(a.appActivations, appKey)
+			a.appActivationResults[appKey] = appActivationResult{
+				state:     state,
+				startTime: appActivation.startTime,
+				endTime:   time.Now(),
+			}
+			a.activationLimiter.release()
+		}
+		select {
+		case <-appActivation.successCh:
+			activationDurationSeconds.WithLabelValues(
+				getWorkloadLabelValues(app)...,
+			).Observe(time.Since(appActivation.requestTime).Seconds())
+			deleteActivation(activationStateSucceeded, activationResultSuccess)
+		case <-appActivation.timeoutCh:
+			deleteActivation(activationStateTimedOut, activationResultTimeout)
+		}
+	}()
+	return appActivation, nil, nil
+}
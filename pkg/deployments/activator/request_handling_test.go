@@ -0,0 +1,35 @@
+package activator
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	k8s "github.com/deislabs/osiris/pkg/kubernetes"
+	mynethttp "github.com/deislabs/osiris/pkg/net/http"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStartActivationTimesOutWhileQueued(t *testing.T) {
+	a := &activator{
+		appActivations:    map[string]*appActivation{},
+		activationLimiter: newActivationLimiter(1),
+	}
+	app := &app{
+		namespace:         "default",
+		serviceName:       "my-app",
+		workload:          k8s.DeploymentReference("my-app"),
+		activationTimeout: 10 * time.Millisecond,
+	}
+	// Another app holds the only activation slot
+	admitted, _ := a.activationLimiter.tryAcquire("other-app")
+	require.True(t, admitted)
+
+	_, err := a.startActivation(app)
+	respErr, ok := err.(*mynethttp.ResponseError)
+	require.True(t, ok)
+	assert.Equal(t, http.StatusServiceUnavailable, respErr.StatusCode)
+	// The app no longer holds a place in the queue
+	assert.False(t, a.activationLimiter.queued(getAppKey(app)))
+}
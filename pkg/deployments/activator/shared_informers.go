@@ -0,0 +1,250 @@
+package activator
+
+import (
+	"sync"
+
+	k8s "github.com/deislabs/osiris/pkg/kubernetes"
+	"github.com/golang/glog"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+)
+
+// sharedInformers informs all of the activator's activations about their apps'
+// pods and endpoints. Instead of each activation watching its own app's pods
+// and endpoints, one pods informer and one endpoints informer are kept running
+// for every namespace that contains Osiris-enabled services. Their caches are
+// therefore already warm when an activation starts, which then only has to
+// subscribe to be notified about its own app.
+type sharedInformers struct {
+	kubeClient kubernetes.Interface
+	namespaces map[string]*namespaceInformers
+	lock       sync.Mutex
+}
+
+// namespaceInformers informs all subscribed activations about pods and
+// endpoints in a single namespace
+type namespaceInformers struct {
+	podsInformer      cache.SharedIndexInformer
+	endpointsInformer cache.SharedIndexInformer
+	subscriptions     map[*activationSubscription]struct{}
+	// retained indicates whether the namespace contains Osiris-enabled services,
+	// in which case its informers are kept running even while no activations
+	// are subscribed
+	retained bool
+	stopCh   chan struct{}
+	lock     sync.RWMutex
+}
+
+// activationSubscription is an activation's subscription to the pods and the
+// endpoints of a single app. The app's pods are identified by the label
+// selector of its workload and its endpoints by the name of its service.
+type activationSubscription struct {
+	namespace   string
+	serviceName string
+	selector    labels.Selector
+	activation  *appActivation
+}
+
+func newSharedInformers(kubeClient kubernetes.Interface) *sharedInformers {
+	return &sharedInformers{
+		kubeClient: kubeClient,
+		namespaces: map[string]*namespaceInformers{},
+	}
+}
+
+// syncNamespaces keeps informers running for exactly the given namespaces,
+// starting those that aren't running yet. Informers for other namespaces are
+// stopped, unless activations are still subscribed to them.
+func (s *sharedInformers) syncNamespaces(namespaces map[string]struct{}) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for namespace := range namespaces {
+		s.getOrStartNamespaceInformers(namespace).setRetained(true)
+	}
+	for namespace, n := range s.namespaces {
+		if _, ok := namespaces[namespace]; !ok && !n.setRetained(false) {
+			s.stopNamespaceInformers(namespace, n)
+		}
+	}
+}
+
+// subscribe causes the given activation to be notified about all pods
+// belonging to its app and about the app's endpoints, including those that
+// already exist.
+func (s *sharedInformers) subscribe(
+	app *app,
+	selector labels.Selector,
+	activation *appActivation,
+) *activationSubscription {
+	sub := &activationSubscription{
+		namespace:   app.namespace,
+		serviceName: app.serviceName,
+		selector:    selector,
+		activation:  activation,
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	// Informers normally run already, but the namespace may have just been
+	// found to contain Osiris-enabled services
+	n := s.getOrStartNamespaceInformers(app.namespace)
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	n.subscriptions[sub] = struct{}{}
+	// Catch the new subscriber up on what the informers already know about. If
+	// they were only just started, they don't know about anything yet and the
+	// subscriber will be informed in due course.
+	for _, obj := range n.podsInformer.GetStore().List() {
+		if sub.matchesPod(obj.(*corev1.Pod)) {
+			activation.syncPod(obj)
+		}
+	}
+	obj, ok, err := n.endpointsInformer.GetStore().GetByKey(
+		getKey(app.namespace, app.serviceName),
+	)
+	if err != nil {
+		glog.Errorf(
+			"Error getting endpoints for service %s in namespace %s: %s",
+			app.serviceName,
+			app.namespace,
+			err,
+		)
+	} else if ok {
+		activation.syncEndpoints(obj)
+	}
+	return sub
+}
+
+// unsubscribe stops the given subscription's activation from being notified
+// about any more pods or endpoints.
+func (s *sharedInformers) unsubscribe(sub *activationSubscription) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	n, ok := s.namespaces[sub.namespace]
+	if !ok {
+		return
+	}
+	n.lock.Lock()
+	delete(n.subscriptions, sub)
+	stop := len(n.subscriptions) == 0 && !n.retained
+	n.lock.Unlock()
+	if stop {
+		s.stopNamespaceInformers(sub.namespace, n)
+	}
+}
+
+// getOrStartNamespaceInformers returns the informers for the given namespace,
+// starting them if they aren't running yet. Callers must hold s.lock.
+func (s *sharedInformers) getOrStartNamespaceInformers(
+	namespace string,
+) *namespaceInformers {
+	if n, ok := s.namespaces[namespace]; ok {
+		return n
+	}
+	glog.Infof(
+		"Starting pods and endpoints informers for namespace %s",
+		namespace,
+	)
+	n := &namespaceInformers{
+		podsInformer: k8s.PodsIndexInformer(
+			s.kubeClient,
+			namespace,
+			nil,
+			nil,
+		),
+		endpointsInformer: k8s.EndpointsIndexInformer(
+			s.kubeClient,
+			namespace,
+			nil,
+			nil,
+		),
+		subscriptions: map[*activationSubscription]struct{}{},
+		stopCh:        make(chan struct{}),
+	}
+	n.podsInformer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: n.syncPod,
+		UpdateFunc: func(_, newObj interface{}) {
+			n.syncPod(newObj)
+		},
+		DeleteFunc: n.syncDeletedPod,
+	})
+	n.endpointsInformer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: n.syncEndpoints,
+		UpdateFunc: func(_, newObj interface{}) {
+			n.syncEndpoints(newObj)
+		},
+	})
+	s.namespaces[namespace] = n
+	go n.podsInformer.Run(n.stopCh)
+	go n.endpointsInformer.Run(n.stopCh)
+	return n
+}
+
+// stopNamespaceInformers stops the given informers for the given namespace.
+// Callers must hold s.lock.
+func (s *sharedInformers) stopNamespaceInformers(
+	namespace string,
+	n *namespaceInformers,
+) {
+	glog.Infof(
+		"Stopping pods and endpoints informers for namespace %s",
+		namespace,
+	)
+	close(n.stopCh)
+	delete(s.namespaces, namespace)
+}
+
+// setRetained records whether the namespace contains Osiris-enabled services
+// and returns whether its informers should keep running.
+func (n *namespaceInformers) setRetained(retained bool) bool {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	n.retained = retained
+	return retained || len(n.subscriptions) > 0
+}
+
+func (n *namespaceInformers) syncPod(obj interface{}) {
+	n.lock.RLock()
+	defer n.lock.RUnlock()
+	for sub := range n.subscriptions {
+		if sub.matchesPod(obj.(*corev1.Pod)) {
+			sub.activation.syncPod(obj)
+		}
+	}
+}
+
+func (n *namespaceInformers) syncDeletedPod(obj interface{}) {
+	pod, ok := obj.(*corev1.Pod)
+	if !ok {
+		tombstone, ok := obj.(cache.DeletedFinalStateUnknown)
+		if !ok {
+			return
+		}
+		if pod, ok = tombstone.Obj.(*corev1.Pod); !ok {
+			return
+		}
+	}
+	n.lock.RLock()
+	defer n.lock.RUnlock()
+	for sub := range n.subscriptions {
+		if sub.matchesPod(pod) {
+			sub.activation.syncDeletedPod(pod)
+		}
+	}
+}
+
+func (n *namespaceInformers) syncEndpoints(obj interface{}) {
+	n.lock.RLock()
+	defer n.lock.RUnlock()
+	for sub := range n.subscriptions {
+		if obj.(*corev1.Endpoints).Name == sub.serviceName {
+			sub.activation.syncEndpoints(obj)
+		}
+	}
+}
+
+// matchesPod returns whether the given pod belongs to the subscriber's app
+func (a *activationSubscription) matchesPod(pod *corev1.Pod) bool {
+	return a.selector.Matches(labels.Set(pod.Labels))
+}
@@ -0,0 +1,104 @@
+package activator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+func newReadyPod(
+	name string,
+	podLabels map[string]string,
+	ip string,
+) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   name,
+			Labels: podLabels,
+		},
+		Status: corev1.PodStatus{
+			PodIP: ip,
+			Conditions: []corev1.PodCondition{
+				{
+					Type:   corev1.PodReady,
+					Status: corev1.ConditionTrue,
+				},
+			},
+		},
+	}
+}
+
+func newEndpoints(name string, ip string) *corev1.Endpoints {
+	return &corev1.Endpoints{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: name,
+		},
+		Subsets: []corev1.EndpointSubset{
+			{
+				Addresses: []corev1.EndpointAddress{
+					{
+						IP: ip,
+					},
+				},
+			},
+		},
+	}
+}
+
+func TestNamespaceInformersNotifySubscribers(t *testing.T) {
+	newSubscription := func(serviceName string) *activationSubscription {
+		selector, err := labels.Parse("app=" + serviceName)
+		require.NoError(t, err)
+		return &activationSubscription{
+			serviceName: serviceName,
+			selector:    selector,
+			activation: &appActivation{
+				readyAppPodIPs: map[string]struct{}{},
+				successCh:      make(chan struct{}),
+			},
+		}
+	}
+	fooSub := newSubscription("foo")
+	barSub := newSubscription("bar")
+	n := &namespaceInformers{
+		subscriptions: map[*activationSubscription]struct{}{
+			fooSub: {},
+			barSub: {},
+		},
+	}
+
+	n.syncPod(newReadyPod("foo-1", map[string]string{"app": "foo"}, "10.0.0.1"))
+	assert.Equal(t, 1, fooSub.activation.readyPods())
+	assert.Equal(t, 0, barSub.activation.readyPods())
+
+	// Endpoints of another service don't complete the activation
+	n.syncEndpoints(newEndpoints("bar", "10.0.0.1"))
+	assert.False(t, isClosed(fooSub.activation.successCh))
+	assert.False(t, isClosed(barSub.activation.successCh))
+
+	n.syncEndpoints(newEndpoints("foo", "10.0.0.1"))
+	assert.True(t, isClosed(fooSub.activation.successCh))
+	assert.False(t, isClosed(barSub.activation.successCh))
+
+	// Further notifications about a completed activation are harmless
+	n.syncEndpoints(newEndpoints("foo", "10.0.0.1"))
+
+	n.syncDeletedPod(
+		newReadyPod("foo-1", map[string]string{"app": "foo"}, "10.0.0.1"),
+	)
+	assert.Equal(t, 0, fooSub.activation.readyPods())
+}
+
+func TestNamespaceInformersSetRetained(t *testing.T) {
+	n := &namespaceInformers{
+		subscriptions: map[*activationSubscription]struct{}{},
+	}
+	assert.True(t, n.setRetained(true))
+	assert.False(t, n.setRetained(false))
+	n.subscriptions[&activationSubscription{}] = struct{}{}
+	assert.True(t, n.setRetained(false))
+}
@@ -0,0 +1,164 @@
+package activator
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	mynethttp "github.com/deislabs/osiris/pkg/net/http"
+	"github.com/golang/glog"
+)
+
+// wakingUpPageRefreshInterval is how often the waking up page reloads itself
+const wakingUpPageRefreshInterval = 5 * time.Second
+
+// defaultWakingUpPageTemplate is the template of the waking up page, unless a
+// custom one is configured
+const defaultWakingUpPageTemplate = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<meta http-equiv="refresh" content="{{ .RefreshSeconds }}">
+<title>Starting up</title>
+</head>
+<body>
+<h1>Starting up</h1>
+<p>
+{{ .Host }} is waking up. This page will reload by itself once it's ready.
+</p>
+</body>
+</html>
+`
+
+// wakingUpPageData is what the waking up page template is executed with
+type wakingUpPageData struct {
+	Host           string
+	Namespace      string
+	Service        string
+	RefreshSeconds int
+}
+
+// getWakingUpPageTemplate returns the waking up page template in the given
+// file, or the default one if no file is given.
+func getWakingUpPageTemplate(file string) (*template.Template, error) {
+	text := defaultWakingUpPageTemplate
+	if file != "" {
+		textBytes, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf(
+				"Error reading waking up page template %s: %s",
+				file,
+				err,
+			)
+		}
+		text = string(textBytes)
+	}
+	return template.New("waking-up-page").Parse(text)
+}
+
+// isHTMLGetRequest returns true if the request is a GET request for an HTML
+// document-- e.g. a browser navigating to a page.
+func isHTMLGetRequest(r *http.Request) bool {
+	return r.Method == http.MethodGet &&
+		strings.Contains(r.Header.Get("Accept"), "text/html")
+}
+
+// handleHTTPRequest is the activator's L7 start proxy callback. It activates
+// the app addressed by the request and returns where to proxy the request to
+// once the app is ready. Browsers navigating to an app that opted into it are
+// shown a waking up page right away instead, while the app is activated in the
+// background.
+func (a *activator) handleHTTPRequest(r *http.Request) (string, int, error) {
+	requestsTotal.WithLabelValues(requestLayerL7).Inc()
+	if isHTMLGetRequest(r) {
+		a.indicesLock.RLock()
+		app, ok := a.appsByHost[r.Host]
+		a.indicesLock.RUnlock()
+		if ok && app.wakingUpPage {
+			return "", 0, a.newWakingUpPageResponse(app, r.Host)
+		}
+	}
+	return a.activateAndWait(r.Host)
+}
+
+// newWakingUpPageResponse starts activating the app in the background and
+// returns a response, in the form of an error, with the waking up page.
+func (a *activator) newWakingUpPageResponse(app *app, hostname string) error {
+	if !a.startBackgroundActivation(app, hostname) {
+		return newTooManyPendingRequestsError(app)
+	}
+	body := &bytes.Buffer{}
+	if err := a.wakingUpPageTemplate.Execute(body, wakingUpPageData{
+		Host:           hostname,
+		Namespace:      app.namespace,
+		Service:        app.serviceName,
+		RefreshSeconds: int(wakingUpPageRefreshInterval.Seconds()),
+	}); err != nil {
+		return fmt.Errorf("Error executing waking up page template: %s", err)
+	}
+	return &mynethttp.ResponseError{
+		Err: fmt.Errorf(
+			"Showing waking up page while %s in namespace %s is activated",
+			app.workload,
+			app.namespace,
+		),
+		StatusCode: http.StatusServiceUnavailable,
+		Header: http.Header{
+			"Content-Type":  []string{"text/html; charset=utf-8"},
+			"Cache-Control": []string{"no-store"},
+			"Retry-After": []string{
+				strconv.Itoa(int(wakingUpPageRefreshInterval.Seconds())),
+			},
+		},
+		Body: body.Bytes(),
+	}
+}
+
+// startBackgroundActivation starts activating the app in the background,
+// unless it is being activated already, is queued for activation, or a
+// background activation was started for it already-- e.g. by an earlier
+// refresh of the waking up page. Until a background activation has started
+// activating the app, it counts as a request waiting for the app. If too many
+// requests are waiting already, it isn't started and false is returned. The
+// hostname is the one the request that prompted the activation was addressed
+// to.
+func (a *activator) startBackgroundActivation(app *app, hostname string) bool {
+	appKey := getAppKey(app)
+	a.appActivationsLock.Lock()
+	defer a.appActivationsLock.Unlock()
+	if _, ok := a.appActivations[appKey]; ok {
+		return true
+	}
+	if _, ok := a.backgroundActivations[appKey]; ok {
+		return true
+	}
+	if a.activationLimiter.queued(appKey) {
+		return true
+	}
+	if !a.pendingRequests.add(app, hostname) {
+		return false
+	}
+	a.backgroundActivations[appKey] = struct{}{}
+	go func() {
+		defer func() {
+			a.appActivationsLock.Lock()
+			defer a.appActivationsLock.Unlock()
+			delete(a.backgroundActivations, appKey)
+		}()
+		defer a.pendingRequests.done(app, hostname)
+		if _, err := a.startActivation(app); err != nil {
+			glog.Errorf(
+				"Error activating %s in namespace %s: %s",
+				app.workload,
+				app.namespace,
+				err,
+			)
+		}
+	}()
+	return true
+}
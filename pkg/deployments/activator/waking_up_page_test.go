@@ -0,0 +1,113 @@
+package activator
+
+import (
+	"bytes"
+	"net/http"
+	"testing"
+	"time"
+
+	k8s "github.com/deislabs/osiris/pkg/kubernetes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsHTMLGetRequest(t *testing.T) {
+	testcases := []struct {
+		name           string
+		method         string
+		accept         string
+		expectedResult bool
+	}{
+		{
+			name:           "browser navigation",
+			method:         http.MethodGet,
+			accept:         "text/html,application/xhtml+xml,*/*;q=0.8",
+			expectedResult: true,
+		},
+		{
+			name:           "API request",
+			method:         http.MethodGet,
+			accept:         "application/json",
+			expectedResult: false,
+		},
+		{
+			name:           "request without accept header",
+			method:         http.MethodGet,
+			expectedResult: false,
+		},
+		{
+			name:           "form submission",
+			method:         http.MethodPost,
+			accept:         "text/html",
+			expectedResult: false,
+		},
+	}
+	for _, test := range testcases {
+		t.Run(test.name, func(t *testing.T) {
+			r, err := http.NewRequest(test.method, "/", nil)
+			require.NoError(t, err)
+			if test.accept != "" {
+				r.Header.Set("Accept", test.accept)
+			}
+			assert.Equal(t, test.expectedResult, isHTMLGetRequest(r))
+		})
+	}
+}
+
+func TestDefaultWakingUpPageTemplate(t *testing.T) {
+	tmpl, err := getWakingUpPageTemplate("")
+	require.NoError(t, err)
+	body := &bytes.Buffer{}
+	err = tmpl.Execute(body, wakingUpPageData{
+		Host:           "my-app.example.com",
+		RefreshSeconds: 5,
+	})
+	require.NoError(t, err)
+	assert.Contains(t, body.String(), `content="5"`)
+	assert.Contains(t, body.String(), "my-app.example.com")
+}
+
+func TestStartBackgroundActivation(t *testing.T) {
+	newApp := func(name string) *app {
+		return &app{
+			namespace:         "default",
+			serviceName:       name,
+			workload:          k8s.DeploymentReference(name),
+			activationTimeout: 50 * time.Millisecond,
+		}
+	}
+	appA, appB, appC := newApp("a"), newApp("b"), newApp("c")
+	a := &activator{
+		appActivations:        map[string]*appActivation{},
+		backgroundActivations: map[string]struct{}{},
+		activationLimiter:     newActivationLimiter(1),
+		pendingRequests:       newPendingRequests(2, 0),
+	}
+	getTotalPending := func() int {
+		a.pendingRequests.lock.Lock()
+		defer a.pendingRequests.lock.Unlock()
+		return a.pendingRequests.total
+	}
+	// Another app holds the only activation slot, so background activations
+	// remain queued until they time out
+	admitted, _ := a.activationLimiter.tryAcquire("other-app")
+	require.True(t, admitted)
+
+	assert.True(t, a.startBackgroundActivation(appA, appA.serviceName))
+	assert.Equal(t, 1, getTotalPending())
+	// Refreshing the page doesn't start another background activation
+	assert.True(t, a.startBackgroundActivation(appA, appA.serviceName))
+	assert.Equal(t, 1, getTotalPending())
+	assert.True(t, a.startBackgroundActivation(appB, appB.serviceName))
+	assert.Equal(t, 2, getTotalPending())
+	// Too many requests are waiting already
+	assert.False(t, a.startBackgroundActivation(appC, appC.serviceName))
+
+	// Background activations stop counting once they time out
+	deadline := time.Now().Add(5 * time.Second)
+	for getTotalPending() > 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.Equal(t, 0, getTotalPending())
+	assert.False(t, a.activationLimiter.queued(getAppKey(appA)))
+}
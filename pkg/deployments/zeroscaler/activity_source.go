@@ -0,0 +1,376 @@
+package zeroscaler
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	k8s "github.com/deislabs/osiris/pkg/kubernetes"
+	"github.com/deislabs/osiris/pkg/metrics"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// Supported values of the k8s.ActivitySourceAnnotationName annotation
+const (
+	activitySourceProxy      = "proxy"
+	activitySourcePrometheus = "prometheus"
+)
+
+// activitySource is a source of information about the activity of an app's
+// pods. Implementations must be comparable so that a change in an app's
+// activity source can be detected.
+type activitySource interface {
+	// getActivityStats retrieves a snapshot of the given pod's activity
+	getActivityStats(
+		httpClient *http.Client,
+		pod *corev1.Pod,
+	) (activityStats, error)
+}
+
+// activityStats is a snapshot of a pod's activity, as retrieved from an
+// activitySource
+type activityStats interface {
+	// comparableTo returns whether these stats can be meaningfully compared to
+	// an earlier snapshot-- e.g. they cannot if the counters they're based on
+	// were reset in the meantime.
+	comparableTo(prev activityStats) bool
+	// activitySince returns whether these stats indicate that there was any
+	// activity since the given, earlier snapshot. It must only be called if
+	// the two snapshots are comparable.
+	activitySince(prev activityStats) bool
+	// startedWithin returns whether the counters these stats are based on
+	// started from zero within the given period, which ends when the stats were
+	// retrieved-- e.g. because the pod or its proxy sidecar was (re)started. If
+	// so, these stats can be compared to an implicit snapshot of zeroes taken at
+	// the start of the period, much as Prometheus handles counter resets.
+	startedWithin(period time.Duration) bool
+	// activitySinceStart returns whether these stats indicate that there was
+	// any activity since the counters they're based on started from zero
+	activitySinceStart() bool
+}
+
+// getActivitySource returns the activity source indicated by an app's effective
+// annotations, i.e. including those inherited from its scale to zero policy or
+// its namespace. By default, the activity source is the app's Osiris proxy
+// sidecars.
+func getActivitySource(
+	annotations map[string]string,
+) (activitySource, error) {
+	switch strings.ToLower(annotations[k8s.ActivitySourceAnnotationName]) {
+	case "", activitySourceProxy:
+		return getProxyActivitySource(annotations)
+	case activitySourcePrometheus:
+		return getPrometheusActivitySource(annotations)
+	default:
+		return nil, fmt.Errorf(
+			"Unknown activity source %q",
+			annotations[k8s.ActivitySourceAnnotationName],
+		)
+	}
+}
+
+// httpGet requests the given URL and returns the response if, and only if, its
+// status code is 200. The caller is responsible for closing the response body.
+func httpGet(httpClient *http.Client, url string) (*http.Response, error) {
+	resp, err := httpClient.Get(url)
+	if err != nil {
+		return nil, fmt.Errorf("Error requesting metrics from %s: %s", url, err)
+	}
+	if resp.StatusCode != 200 {
+		resp.Body.Close()
+		return nil, fmt.Errorf(
+			"Received unexpected HTTP response code %d when requesting metrics "+
+				"from %s",
+			resp.StatusCode,
+			url,
+		)
+	}
+	return resp, nil
+}
+
+// proxyActivitySource retrieves connection stats from the Osiris proxy sidecar
+// in each of an app's pods.
+type proxyActivitySource struct {
+	// openConnectionQuietPeriod, if non-zero, is how long connections may remain
+	// open without any bytes being transferred over them before they no longer
+	// count as activity
+	openConnectionQuietPeriod time.Duration
+}
+
+func getProxyActivitySource(
+	annotations map[string]string,
+) (proxyActivitySource, error) {
+	source := proxyActivitySource{}
+	quietPeriodStr, ok := annotations[k8s.OpenConnectionQuietPeriodAnnotationName]
+	if !ok {
+		return source, nil
+	}
+	quietPeriod, err := time.ParseDuration(quietPeriodStr)
+	if err != nil || quietPeriod <= 0 {
+		return source, fmt.Errorf(
+			"The %s annotation must specify a positive duration",
+			k8s.OpenConnectionQuietPeriodAnnotationName,
+		)
+	}
+	source.openConnectionQuietPeriod = quietPeriod
+	return source, nil
+}
+
+func (p proxyActivitySource) getActivityStats(
+	httpClient *http.Client,
+	pod *corev1.Pod,
+) (activityStats, error) {
+	podMetricsPort, ok := getMetricsPort(pod)
+	if !ok {
+		return nil, fmt.Errorf(
+			"Pod %s does not expose metrics from an Osiris proxy",
+			pod.Name,
+		)
+	}
+	url := fmt.Sprintf(
+		"http://%s:%d/metrics",
+		pod.Status.PodIP,
+		podMetricsPort,
+	)
+	resp, err := httpGet(httpClient, url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	bodyBytes, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"Error reading metrics request response from %s: %s",
+			url,
+			err,
+		)
+	}
+	var pcs metrics.ProxyConnectionStats
+	if err := json.Unmarshal(bodyBytes, &pcs); err != nil {
+		return nil, fmt.Errorf(
+			"Error umarshaling metrics request response from %s: %s",
+			url,
+			err,
+		)
+	}
+	return proxyActivityStats{
+		ProxyConnectionStats:      pcs,
+		openConnectionQuietPeriod: p.openConnectionQuietPeriod,
+	}, nil
+}
+
+func getMetricsPort(pod *corev1.Pod) (int32, bool) {
+	for _, c := range pod.Spec.Containers {
+		if c.Name == proxyContainerName && len(c.Ports) > 0 {
+			for _, port := range c.Ports {
+				if port.Name == proxyPortName {
+					return port.ContainerPort, true
+				}
+			}
+		}
+	}
+	return 0, false
+}
+
+// proxyActivityStats are the connection stats reported by an Osiris proxy
+// sidecar, along with the app's quiet period for open connections, if any.
+type proxyActivityStats struct {
+	metrics.ProxyConnectionStats
+	openConnectionQuietPeriod time.Duration
+}
+
+// comparableTo returns false if the pod's metrics-collecting proxy sidecar
+// died and was replaced since the previous snapshot
+func (p proxyActivityStats) comparableTo(prev activityStats) bool {
+	prevProxyStats, ok := prev.(proxyActivityStats)
+	return ok && p.ProxyID == prevProxyStats.ProxyID
+}
+
+// nolint: lll
+func (p proxyActivityStats) activitySince(prev activityStats) bool {
+	prevProxyStats := prev.(proxyActivityStats)
+	return p.ConnectionsOpened > prevProxyStats.ConnectionsOpened || // New connections have been opened
+		p.ConnectionsClosed > prevProxyStats.ConnectionsClosed || // Opened connections were closed
+		p.BytesRead > prevProxyStats.BytesRead || // Bytes were received from clients
+		p.BytesWritten > prevProxyStats.BytesWritten || // Bytes were sent to clients
+		p.openConnectionsActive() || // Some connections remain open and aren't silent
+		p.lastActivityAfter(prevProxyStats.LastActivity) // The proxy saw activity more recently
+}
+
+// openConnectionsActive returns whether any connections remain open. If the
+// app has a quiet period for open connections, open connections only count if
+// the proxy saw any activity within that period.
+func (p proxyActivityStats) openConnectionsActive() bool {
+	if p.ConnectionsOpened <= p.ConnectionsClosed {
+		return false
+	}
+	if p.openConnectionQuietPeriod == 0 ||
+		p.Timestamp == nil ||
+		p.LastActivity == nil {
+		return true
+	}
+	return p.Timestamp.Sub(*p.LastActivity) < p.openConnectionQuietPeriod
+}
+
+// lastActivityAfter returns whether the proxy's last activity is more recent
+// than the given time, which may be nil if there was no earlier activity
+func (p proxyActivityStats) lastActivityAfter(t *time.Time) bool {
+	return p.LastActivity != nil && (t == nil || p.LastActivity.After(*t))
+}
+
+// startedWithin relies on the start time and the timestamp reported by the
+// proxy. Both are according to the proxy's clock, which may not agree with
+// the zeroscaler's, so only the proxy's uptime derived from them is compared
+// to the period. Proxies that don't report both are never assumed to have
+// started within any given period.
+func (p proxyActivityStats) startedWithin(period time.Duration) bool {
+	return p.ProxyStartTime != nil && p.Timestamp != nil &&
+		p.Timestamp.Sub(*p.ProxyStartTime) < period
+}
+
+func (p proxyActivityStats) activitySinceStart() bool {
+	return p.activitySince(proxyActivityStats{
+		ProxyConnectionStats: metrics.ProxyConnectionStats{ProxyID: p.ProxyID},
+	})
+}
+
+// prometheusActivitySource retrieves the value of a named metric from the
+// Prometheus metrics endpoint of each of an app's pods.
+type prometheusActivitySource struct {
+	port       int
+	path       string
+	metricName string
+}
+
+func getPrometheusActivitySource(
+	annotations map[string]string,
+) (prometheusActivitySource, error) {
+	source := prometheusActivitySource{
+		path:       "/metrics",
+		metricName: annotations[k8s.PrometheusMetricAnnotationName],
+	}
+	if source.metricName == "" {
+		return source, fmt.Errorf(
+			"The %s annotation is required by the %s activity source",
+			k8s.PrometheusMetricAnnotationName,
+			activitySourcePrometheus,
+		)
+	}
+	var err error
+	source.port, err =
+		strconv.Atoi(annotations[k8s.PrometheusMetricsPortAnnotationName])
+	if err != nil || source.port <= 0 || source.port > 65535 {
+		return source, fmt.Errorf(
+			"The %s annotation must specify a valid port for the %s activity "+
+				"source",
+			k8s.PrometheusMetricsPortAnnotationName,
+			activitySourcePrometheus,
+		)
+	}
+	if path, ok := annotations[k8s.PrometheusMetricsPathAnnotationName]; ok {
+		if !strings.HasPrefix(path, "/") {
+			path = "/" + path
+		}
+		source.path = path
+	}
+	return source, nil
+}
+
+func (p prometheusActivitySource) getActivityStats(
+	httpClient *http.Client,
+	pod *corev1.Pod,
+) (activityStats, error) {
+	url := fmt.Sprintf("http://%s:%d%s", pod.Status.PodIP, p.port, p.path)
+	resp, err := httpGet(httpClient, url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	decoder := expfmt.NewDecoder(resp.Body, expfmt.ResponseFormat(resp.Header))
+	for {
+		mf := &dto.MetricFamily{}
+		if err := decoder.Decode(mf); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("Error decoding metrics from %s: %s", url, err)
+		}
+		if mf.GetName() != p.metricName {
+			continue
+		}
+		return getPrometheusActivityStats(mf), nil
+	}
+	return nil, fmt.Errorf(
+		"Metric %s was not found in metrics from %s",
+		p.metricName,
+		url,
+	)
+}
+
+// prometheusActivityStats is the value of a Prometheus metric, summed across
+// all of its label values. For counters, any increase in value indicates
+// activity. For gauges, any non-zero value indicates activity-- e.g. a
+// non-empty queue or open streams. Histograms and summaries are treated as
+// counters of the number of observations they've made.
+type prometheusActivityStats struct {
+	counter bool
+	value   float64
+}
+
+func getPrometheusActivityStats(
+	mf *dto.MetricFamily,
+) prometheusActivityStats {
+	stats := prometheusActivityStats{}
+	switch mf.GetType() {
+	case dto.MetricType_COUNTER, dto.MetricType_HISTOGRAM, dto.MetricType_SUMMARY:
+		stats.counter = true
+	}
+	for _, m := range mf.GetMetric() {
+		switch mf.GetType() {
+		case dto.MetricType_COUNTER:
+			stats.value += m.GetCounter().GetValue()
+		case dto.MetricType_GAUGE:
+			stats.value += m.GetGauge().GetValue()
+		case dto.MetricType_HISTOGRAM:
+			stats.value += float64(m.GetHistogram().GetSampleCount())
+		case dto.MetricType_SUMMARY:
+			stats.value += float64(m.GetSummary().GetSampleCount())
+		default:
+			stats.value += m.GetUntyped().GetValue()
+		}
+	}
+	return stats
+}
+
+// comparableTo returns false if a counter was reset since the previous
+// snapshot-- e.g. because the app restarted
+func (p prometheusActivityStats) comparableTo(prev activityStats) bool {
+	prevPrometheusStats, ok := prev.(prometheusActivityStats)
+	if !ok || p.counter != prevPrometheusStats.counter {
+		return false
+	}
+	return !p.counter || p.value >= prevPrometheusStats.value
+}
+
+func (p prometheusActivityStats) activitySince(prev activityStats) bool {
+	if p.counter {
+		return p.value > prev.(prometheusActivityStats).value
+	}
+	return p.value != 0
+}
+
+// startedWithin always returns false because Prometheus metrics carry no
+// indication of when the process exposing them started
+func (p prometheusActivityStats) startedWithin(time.Duration) bool {
+	return false
+}
+
+func (p prometheusActivityStats) activitySinceStart() bool {
+	return p.value != 0
+}
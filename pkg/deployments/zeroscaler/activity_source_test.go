@@ -0,0 +1,475 @@
+package zeroscaler
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	k8s "github.com/deislabs/osiris/pkg/kubernetes"
+	"github.com/deislabs/osiris/pkg/metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestGetActivitySource(t *testing.T) {
+	testcases := []struct {
+		name           string
+		annotations    map[string]string
+		expectedSource activitySource
+		expectError    bool
+	}{
+		{
+			name:           "no annotations",
+			annotations:    map[string]string{},
+			expectedSource: proxyActivitySource{},
+		},
+		{
+			name: "proxy activity source",
+			annotations: map[string]string{
+				k8s.ActivitySourceAnnotationName: "proxy",
+			},
+			expectedSource: proxyActivitySource{},
+		},
+		{
+			name: "proxy activity source with open connection quiet period",
+			annotations: map[string]string{
+				k8s.OpenConnectionQuietPeriodAnnotationName: "10m",
+			},
+			expectedSource: proxyActivitySource{
+				openConnectionQuietPeriod: 10 * time.Minute,
+			},
+		},
+		{
+			name: "proxy activity source with invalid open connection quiet period",
+			annotations: map[string]string{
+				k8s.OpenConnectionQuietPeriodAnnotationName: "a while",
+			},
+			expectError: true,
+		},
+		{
+			name: "prometheus activity source",
+			annotations: map[string]string{
+				k8s.ActivitySourceAnnotationName:        "prometheus",
+				k8s.PrometheusMetricAnnotationName:      "jobs_processed_total",
+				k8s.PrometheusMetricsPortAnnotationName: "9102",
+			},
+			expectedSource: prometheusActivitySource{
+				port:       9102,
+				path:       "/metrics",
+				metricName: "jobs_processed_total",
+			},
+		},
+		{
+			name: "prometheus activity source with custom path",
+			annotations: map[string]string{
+				k8s.ActivitySourceAnnotationName:        "prometheus",
+				k8s.PrometheusMetricAnnotationName:      "jobs_processed_total",
+				k8s.PrometheusMetricsPortAnnotationName: "9102",
+				k8s.PrometheusMetricsPathAnnotationName: "stats",
+			},
+			expectedSource: prometheusActivitySource{
+				port:       9102,
+				path:       "/stats",
+				metricName: "jobs_processed_total",
+			},
+		},
+		{
+			name: "prometheus activity source without metric",
+			annotations: map[string]string{
+				k8s.ActivitySourceAnnotationName:        "prometheus",
+				k8s.PrometheusMetricsPortAnnotationName: "9102",
+			},
+			expectError: true,
+		},
+		{
+			name: "prometheus activity source with invalid port",
+			annotations: map[string]string{
+				k8s.ActivitySourceAnnotationName:        "prometheus",
+				k8s.PrometheusMetricAnnotationName:      "jobs_processed_total",
+				k8s.PrometheusMetricsPortAnnotationName: "http",
+			},
+			expectError: true,
+		},
+		{
+			name: "unknown activity source",
+			annotations: map[string]string{
+				k8s.ActivitySourceAnnotationName: "carrier-pigeon",
+			},
+			expectError: true,
+		},
+	}
+
+	for _, test := range testcases {
+		t.Run(test.name, func(t *testing.T) {
+			source, err := getActivitySource(test.annotations)
+			if test.expectError {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, test.expectedSource, source)
+		})
+	}
+}
+
+func TestPrometheusActivitySourceGetActivityStats(t *testing.T) {
+	server := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			fmt.Fprint(
+				w,
+				"# TYPE jobs_processed_total counter\n"+
+					"jobs_processed_total{queue=\"a\"} 3\n"+
+					"jobs_processed_total{queue=\"b\"} 4\n"+
+					"# TYPE open_streams gauge\n"+
+					"open_streams 2\n",
+			)
+		}),
+	)
+	defer server.Close()
+	host, portStr, err := net.SplitHostPort(server.Listener.Addr().String())
+	require.NoError(t, err)
+	port, err := strconv.Atoi(portStr)
+	require.NoError(t, err)
+	pod := &corev1.Pod{
+		Status: corev1.PodStatus{
+			PodIP: host,
+		},
+	}
+
+	testcases := []struct {
+		name          string
+		metricName    string
+		expectedStats activityStats
+		expectError   bool
+	}{
+		{
+			name:       "counter",
+			metricName: "jobs_processed_total",
+			expectedStats: prometheusActivityStats{
+				counter: true,
+				value:   7,
+			},
+		},
+		{
+			name:       "gauge",
+			metricName: "open_streams",
+			expectedStats: prometheusActivityStats{
+				value: 2,
+			},
+		},
+		{
+			name:        "missing metric",
+			metricName:  "requests_total",
+			expectError: true,
+		},
+	}
+
+	for _, test := range testcases {
+		t.Run(test.name, func(t *testing.T) {
+			stats, err := prometheusActivitySource{
+				port:       port,
+				path:       "/metrics",
+				metricName: test.metricName,
+			}.getActivityStats(http.DefaultClient, pod)
+			if test.expectError {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, test.expectedStats, stats)
+		})
+	}
+}
+
+func TestActivityStats(t *testing.T) {
+	earlier := time.Date(2019, 1, 7, 17, 0, 0, 0, time.UTC)
+	later := earlier.Add(time.Minute)
+	testcases := []struct {
+		name               string
+		prev               activityStats
+		recent             activityStats
+		expectedComparable bool
+		expectedActivity   bool
+	}{
+		{
+			name: "proxy, no activity",
+			prev: proxyActivityStats{
+				ProxyConnectionStats: metrics.ProxyConnectionStats{ProxyID: "a"},
+			},
+			recent: proxyActivityStats{
+				ProxyConnectionStats: metrics.ProxyConnectionStats{ProxyID: "a"},
+			},
+			expectedComparable: true,
+		},
+		{
+			name: "proxy, connections opened",
+			prev: proxyActivityStats{
+				ProxyConnectionStats: metrics.ProxyConnectionStats{ProxyID: "a"},
+			},
+			recent: proxyActivityStats{
+				ProxyConnectionStats: metrics.ProxyConnectionStats{
+					ProxyID:           "a",
+					ConnectionsOpened: 1,
+				},
+			},
+			expectedComparable: true,
+			expectedActivity:   true,
+		},
+		{
+			name: "proxy, more recent activity",
+			prev: proxyActivityStats{
+				ProxyConnectionStats: metrics.ProxyConnectionStats{
+					ProxyID:      "a",
+					LastActivity: &earlier,
+				},
+			},
+			recent: proxyActivityStats{
+				ProxyConnectionStats: metrics.ProxyConnectionStats{
+					ProxyID:      "a",
+					LastActivity: &later,
+				},
+			},
+			expectedComparable: true,
+			expectedActivity:   true,
+		},
+		{
+			name: "proxy, bytes transferred",
+			prev: proxyActivityStats{
+				ProxyConnectionStats: metrics.ProxyConnectionStats{
+					ProxyID:           "a",
+					ConnectionsOpened: 1,
+					ConnectionsClosed: 1,
+				},
+			},
+			recent: proxyActivityStats{
+				ProxyConnectionStats: metrics.ProxyConnectionStats{
+					ProxyID:           "a",
+					ConnectionsOpened: 1,
+					ConnectionsClosed: 1,
+					BytesWritten:      512,
+				},
+			},
+			expectedComparable: true,
+			expectedActivity:   true,
+		},
+		{
+			name: "proxy, open connection",
+			prev: proxyActivityStats{
+				ProxyConnectionStats: metrics.ProxyConnectionStats{
+					ProxyID:           "a",
+					ConnectionsOpened: 1,
+					LastActivity:      &earlier,
+				},
+			},
+			recent: proxyActivityStats{
+				ProxyConnectionStats: metrics.ProxyConnectionStats{
+					ProxyID:           "a",
+					ConnectionsOpened: 1,
+					LastActivity:      &earlier,
+					Timestamp:         &later,
+				},
+			},
+			expectedComparable: true,
+			expectedActivity:   true,
+		},
+		{
+			name: "proxy, open connection silent within quiet period",
+			prev: proxyActivityStats{
+				ProxyConnectionStats: metrics.ProxyConnectionStats{
+					ProxyID:           "a",
+					ConnectionsOpened: 1,
+					LastActivity:      &earlier,
+				},
+			},
+			recent: proxyActivityStats{
+				ProxyConnectionStats: metrics.ProxyConnectionStats{
+					ProxyID:           "a",
+					ConnectionsOpened: 1,
+					LastActivity:      &earlier,
+					Timestamp:         &later,
+				},
+				openConnectionQuietPeriod: 5 * time.Minute,
+			},
+			expectedComparable: true,
+			expectedActivity:   true,
+		},
+		{
+			name: "proxy, open connection silent beyond quiet period",
+			prev: proxyActivityStats{
+				ProxyConnectionStats: metrics.ProxyConnectionStats{
+					ProxyID:           "a",
+					ConnectionsOpened: 1,
+					LastActivity:      &earlier,
+				},
+			},
+			recent: proxyActivityStats{
+				ProxyConnectionStats: metrics.ProxyConnectionStats{
+					ProxyID:           "a",
+					ConnectionsOpened: 1,
+					LastActivity:      &earlier,
+					Timestamp:         &later,
+				},
+				openConnectionQuietPeriod: 30 * time.Second,
+			},
+			expectedComparable: true,
+		},
+		{
+			name: "proxy, replaced",
+			prev: proxyActivityStats{
+				ProxyConnectionStats: metrics.ProxyConnectionStats{ProxyID: "a"},
+			},
+			recent: proxyActivityStats{
+				ProxyConnectionStats: metrics.ProxyConnectionStats{ProxyID: "b"},
+			},
+		},
+		{
+			name:               "counter, no increase",
+			prev:               prometheusActivityStats{counter: true, value: 3},
+			recent:             prometheusActivityStats{counter: true, value: 3},
+			expectedComparable: true,
+		},
+		{
+			name:               "counter, increase",
+			prev:               prometheusActivityStats{counter: true, value: 3},
+			recent:             prometheusActivityStats{counter: true, value: 4},
+			expectedComparable: true,
+			expectedActivity:   true,
+		},
+		{
+			name:   "counter, reset",
+			prev:   prometheusActivityStats{counter: true, value: 3},
+			recent: prometheusActivityStats{counter: true, value: 1},
+		},
+		{
+			name:               "gauge, zero",
+			prev:               prometheusActivityStats{value: 3},
+			recent:             prometheusActivityStats{value: 0},
+			expectedComparable: true,
+		},
+		{
+			name:               "gauge, non-zero",
+			prev:               prometheusActivityStats{value: 0},
+			recent:             prometheusActivityStats{value: 1},
+			expectedComparable: true,
+			expectedActivity:   true,
+		},
+		{
+			name: "different activity sources",
+			prev: proxyActivityStats{
+				ProxyConnectionStats: metrics.ProxyConnectionStats{ProxyID: "a"},
+			},
+			recent: prometheusActivityStats{value: 1},
+		},
+	}
+
+	for _, test := range testcases {
+		t.Run(test.name, func(t *testing.T) {
+			comparable := test.recent.comparableTo(test.prev)
+			assert.Equal(t, test.expectedComparable, comparable)
+			if comparable {
+				assert.Equal(
+					t,
+					test.expectedActivity,
+					test.recent.activitySince(test.prev),
+				)
+			}
+		})
+	}
+}
+
+func TestActivityStatsAfterRestart(t *testing.T) {
+	const period = 2 * time.Minute
+	// The proxy's clock needn't agree with the zeroscaler's
+	proxyNow := time.Date(2019, 1, 7, 18, 0, 0, 0, time.UTC)
+	before := proxyNow.Add(-period - time.Minute)
+	after := proxyNow.Add(-time.Minute)
+	testcases := []struct {
+		name                  string
+		stats                 activityStats
+		expectedStartedWithin bool
+		expectedActivity      bool
+	}{
+		{
+			name: "proxy without start time",
+			stats: proxyActivityStats{
+				ProxyConnectionStats: metrics.ProxyConnectionStats{
+					ProxyID:   "a",
+					Timestamp: &proxyNow,
+				},
+			},
+			expectedStartedWithin: false,
+		},
+		{
+			name: "proxy without timestamp",
+			stats: proxyActivityStats{
+				ProxyConnectionStats: metrics.ProxyConnectionStats{
+					ProxyID:        "a",
+					ProxyStartTime: &after,
+				},
+			},
+			expectedStartedWithin: false,
+		},
+		{
+			name: "proxy started before the period",
+			stats: proxyActivityStats{
+				ProxyConnectionStats: metrics.ProxyConnectionStats{
+					ProxyID:        "a",
+					ProxyStartTime: &before,
+					Timestamp:      &proxyNow,
+				},
+			},
+			expectedStartedWithin: false,
+		},
+		{
+			name: "proxy started during the period, no activity",
+			stats: proxyActivityStats{
+				ProxyConnectionStats: metrics.ProxyConnectionStats{
+					ProxyID:        "a",
+					ProxyStartTime: &after,
+					Timestamp:      &proxyNow,
+				},
+			},
+			expectedStartedWithin: true,
+			expectedActivity:      false,
+		},
+		{
+			name: "proxy started during the period, activity",
+			stats: proxyActivityStats{
+				ProxyConnectionStats: metrics.ProxyConnectionStats{
+					ProxyID:           "a",
+					ProxyStartTime:    &after,
+					Timestamp:         &proxyNow,
+					ConnectionsOpened: 2,
+					ConnectionsClosed: 2,
+					LastActivity:      &after,
+				},
+			},
+			expectedStartedWithin: true,
+			expectedActivity:      true,
+		},
+		{
+			name:                  "prometheus",
+			stats:                 prometheusActivityStats{counter: true, value: 1},
+			expectedStartedWithin: false,
+		},
+	}
+
+	for _, test := range testcases {
+		t.Run(test.name, func(t *testing.T) {
+			startedWithin := test.stats.startedWithin(period)
+			assert.Equal(t, test.expectedStartedWithin, startedWithin)
+			if startedWithin {
+				assert.Equal(
+					t,
+					test.expectedActivity,
+					test.stats.activitySinceStart(),
+				)
+			}
+		})
+	}
+}
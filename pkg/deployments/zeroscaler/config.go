@@ -1,6 +1,9 @@
 package zeroscaler
 
-import "github.com/kelseyhightower/envconfig"
+import (
+	k8s "github.com/deislabs/osiris/pkg/kubernetes"
+	"github.com/kelseyhightower/envconfig"
+)
 
 const envconfigPrefix = "ZEROSCALER"
 
@@ -8,13 +11,35 @@ const envconfigPrefix = "ZEROSCALER"
 // nolint: lll
 type Config struct {
 	MetricsCheckInterval int `envconfig:"METRICS_CHECK_INTERVAL" required:"true"`
+	// IdleThreshold is the default number of consecutive idle metrics check
+	// intervals (e.g. 3) or the wall-clock idle duration (e.g. 10m) that must
+	// elapse before an app is scaled to zero
+	IdleThreshold string `envconfig:"IDLE_THRESHOLD"`
+	// WorkloadKinds are additional kinds of workloads, of the form
+	// group/version/kind, that implement the scale subresource and should be
+	// considered for scaling to zero-- e.g. argoproj.io/v1alpha1/Rollout
+	WorkloadKinds []string `envconfig:"WORKLOAD_KINDS"`
+	// DryRun, if true, causes the zeroscaler to only report, for all apps, when
+	// it would have scaled them to zero instead of doing so. This can be
+	// overridden for individual apps with an annotation.
+	DryRun bool `envconfig:"DRY_RUN"`
+	// MaxScaleToZeroPerMinute, if greater than zero, caps how many apps may be
+	// scaled to zero per minute, across the whole cluster. Idle apps over the
+	// limit are scaled to zero in a later metrics check interval instead.
+	MaxScaleToZeroPerMinute int `envconfig:"MAX_SCALE_TO_ZERO_PER_MINUTE"`
+	k8s.LeaderElectionConfig
 }
 
 // NewConfigWithDefaults returns a Config object with default values already
 // applied. Callers are then free to set custom values for the remaining fields
 // and/or override default values.
 func NewConfigWithDefaults() Config {
-	return Config{}
+	return Config{
+		IdleThreshold: "1",
+		LeaderElectionConfig: k8s.NewLeaderElectionConfigWithDefaults(
+			"osiris-zeroscaler",
+		),
+	}
 }
 
 // GetConfigFromEnvironment returns configuration derived from environment
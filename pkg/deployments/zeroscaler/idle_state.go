@@ -0,0 +1,177 @@
+package zeroscaler
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	k8s "github.com/deislabs/osiris/pkg/kubernetes"
+	"github.com/deislabs/osiris/pkg/metrics"
+	"github.com/golang/glog"
+)
+
+const (
+	// idleStateCheckpointChecks is how many metrics checks may pass, while an
+	// app remains idle, before its idle tracking state is checkpointed again
+	idleStateCheckpointChecks = 3
+	// maxIdleStateCheckpointAge is how many metrics check intervals old a
+	// checkpoint may be to be restored. Older checkpoints no longer reflect the
+	// app's recent activity.
+	maxIdleStateCheckpointAge = idleStateCheckpointChecks + 2
+)
+
+// idleState is a checkpoint of a metrics collector's idle tracking state. It
+// is persisted in an annotation on the app's workload whenever the app starts
+// or stops being idle and, while it remains idle, every few metrics checks, so
+// that, if the zeroscaler is restarted or leadership changes hands, idle
+// tracking can continue where it left off instead of starting over. Writing it
+// more often would mean updating every workload after every metrics check. The
+// checkpoint is removed when the app is scaled to zero or activated.
+type idleState struct {
+	CheckTime     time.Time                   `json:"checkTime"`
+	IdleSince     *time.Time                  `json:"idleSince,omitempty"`
+	IdleIntervals int                         `json:"idleIntervals"`
+	Pods          map[string]podActivityState `json:"pods,omitempty"`
+}
+
+// podActivityState is a checkpoint of the most recent activity stats of a
+// single pod. Exactly one of its fields is set, depending on the app's
+// activity source.
+type podActivityState struct {
+	Proxy      *metrics.ProxyConnectionStats `json:"proxy,omitempty"`
+	Prometheus *prometheusActivityState      `json:"prometheus,omitempty"`
+}
+
+type prometheusActivityState struct {
+	Counter bool    `json:"counter"`
+	Value   float64 `json:"value"`
+}
+
+func newPodActivityState(stats activityStats) (podActivityState, bool) {
+	switch s := stats.(type) {
+	case proxyActivityStats:
+		pcs := s.ProxyConnectionStats
+		return podActivityState{Proxy: &pcs}, true
+	case prometheusActivityStats:
+		return podActivityState{
+			Prometheus: &prometheusActivityState{
+				Counter: s.counter,
+				Value:   s.value,
+			},
+		}, true
+	default:
+		return podActivityState{}, false
+	}
+}
+
+func (p podActivityState) activityStats() (activityStats, bool) {
+	switch {
+	case p.Proxy != nil:
+		return proxyActivityStats{ProxyConnectionStats: *p.Proxy}, true
+	case p.Prometheus != nil:
+		return prometheusActivityStats{
+			counter: p.Prometheus.Counter,
+			value:   p.Prometheus.Value,
+		}, true
+	default:
+		return nil, false
+	}
+}
+
+// getIdleState returns a checkpoint of the collector's idle tracking state as
+// of the given check time. Only the stats of pods that were successfully
+// scraped at that time are included. The caller must hold the app pods lock.
+func (m *metricsCollector) getIdleState(checkTime time.Time) idleState {
+	state := idleState{
+		CheckTime:     checkTime,
+		IdleSince:     m.idleSince,
+		IdleIntervals: m.idleIntervals,
+		Pods:          map[string]podActivityState{},
+	}
+	for podName, ps := range m.allAppPodStats {
+		if ps.recentStatTime == nil || !ps.recentStatTime.Equal(checkTime) {
+			continue
+		}
+		if pas, ok := newPodActivityState(ps.recentStats); ok {
+			state.Pods[podName] = pas
+		}
+	}
+	return state
+}
+
+// shouldCheckpointIdleState counts a metrics check and returns whether the
+// collector's idle tracking state must be checkpointed after it, i.e. whether
+// the app started or stopped being idle since the last checkpoint or has
+// remained idle for idleStateCheckpointChecks checks since.
+func (m *metricsCollector) shouldCheckpointIdleState() bool {
+	m.checksSinceCheckpoint++
+	if (m.idleSince == nil) != (m.checkpointedIdleSince == nil) ||
+		(m.idleSince != nil && !m.idleSince.Equal(*m.checkpointedIdleSince)) {
+		return true
+	}
+	return m.idleSince != nil &&
+		m.checksSinceCheckpoint >= idleStateCheckpointChecks
+}
+
+// checkpointIdleState persists the collector's idle tracking state as of the
+// given check time.
+func (m *metricsCollector) checkpointIdleState(checkTime time.Time) {
+	m.checkpointedIdleSince = m.idleSince
+	m.checksSinceCheckpoint = 0
+	m.appPodsLock.Lock()
+	state := m.getIdleState(checkTime)
+	m.appPodsLock.Unlock()
+	stateBytes, err := json.Marshal(state)
+	if err != nil {
+		glog.Errorf("Error marshaling idle state: %s", err)
+		return
+	}
+	m.annotate(map[string]string{
+		k8s.IdleStateAnnotationName: string(stateBytes),
+	})
+}
+
+// restoreIdleState restores idle tracking state from the checkpoint found in
+// the given annotations, if any, and returns the time of the check at which
+// that checkpoint was taken. Pods that were checkpointed are presumed deleted
+// until the collector is informed of them again.
+func (m *metricsCollector) restoreIdleState(
+	annotations map[string]string,
+) (*time.Time, error) {
+	stateStr, ok := annotations[k8s.IdleStateAnnotationName]
+	if !ok || stateStr == "" {
+		return nil, nil
+	}
+	state := idleState{}
+	if err := json.Unmarshal([]byte(stateStr), &state); err != nil {
+		return nil, fmt.Errorf("Error unmarshaling idle state: %s", err)
+	}
+	if state.CheckTime.IsZero() || state.CheckTime.After(time.Now()) {
+		return nil, fmt.Errorf("Invalid check time in idle state")
+	}
+	if time.Since(state.CheckTime) >
+		maxIdleStateCheckpointAge*m.metricsCheckInterval {
+		return nil, fmt.Errorf(
+			"Idle state checkpointed at %s is stale",
+			state.CheckTime.Format(time.RFC3339),
+		)
+	}
+	m.appPodsLock.Lock()
+	defer m.appPodsLock.Unlock()
+	m.idleSince = state.IdleSince
+	m.idleIntervals = state.IdleIntervals
+	m.checkpointedIdleSince = state.IdleSince
+	checkTime := state.CheckTime
+	for podName, pas := range state.Pods {
+		stats, ok := pas.activityStats()
+		if !ok {
+			continue
+		}
+		m.allAppPodStats[podName] = &podStats{
+			podDeletedTime: &checkTime,
+			recentStatTime: &checkTime,
+			recentStats:    stats,
+		}
+	}
+	return &checkTime, nil
+}
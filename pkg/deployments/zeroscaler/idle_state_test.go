@@ -0,0 +1,157 @@
+package zeroscaler
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	k8s "github.com/deislabs/osiris/pkg/kubernetes"
+	"github.com/deislabs/osiris/pkg/metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIdleStateCheckpointAndRestore(t *testing.T) {
+	checkTime := time.Now().UTC().Add(-time.Minute).Truncate(time.Second)
+	staleTime := checkTime.Add(-time.Minute)
+	idleSince := checkTime.Add(-5 * time.Minute)
+	workloadsClient := &fakeWorkloadsClient{
+		annotations: map[string]string{},
+	}
+	m := &metricsCollector{
+		workloadsClient: workloadsClient,
+		workload:        k8s.DeploymentReference("my-app"),
+		appNamespace:    "my-namespace",
+		idleIntervals:   2,
+		idleSince:       &idleSince,
+		allAppPodStats: map[string]*podStats{
+			"proxied-pod": {
+				recentStatTime: &checkTime,
+				recentStats: proxyActivityStats{
+					ProxyConnectionStats: metrics.ProxyConnectionStats{
+						ProxyID:           "abc",
+						ConnectionsOpened: 3,
+						ConnectionsClosed: 3,
+					},
+				},
+			},
+			"instrumented-pod": {
+				recentStatTime: &checkTime,
+				recentStats: prometheusActivityStats{
+					counter: true,
+					value:   42,
+				},
+			},
+			// Stats that weren't retrieved in the most recent check must not be
+			// checkpointed
+			"unreachable-pod": {
+				recentStatTime: &staleTime,
+				recentStats: proxyActivityStats{
+					ProxyConnectionStats: metrics.ProxyConnectionStats{ProxyID: "def"},
+				},
+			},
+		},
+	}
+	m.checkpointIdleState(checkTime)
+	require.Contains(t, workloadsClient.annotations, k8s.IdleStateAnnotationName)
+
+	restored := &metricsCollector{
+		metricsCheckInterval: time.Minute,
+		allAppPodStats:       map[string]*podStats{},
+	}
+	lastCheckTime, err := restored.restoreIdleState(workloadsClient.annotations)
+	require.NoError(t, err)
+	require.NotNil(t, lastCheckTime)
+	assert.True(t, checkTime.Equal(*lastCheckTime))
+	assert.Equal(t, 2, restored.idleIntervals)
+	require.NotNil(t, restored.idleSince)
+	assert.True(t, idleSince.Equal(*restored.idleSince))
+	// The restored state is already checkpointed
+	assert.False(t, restored.shouldCheckpointIdleState())
+	assert.Len(t, restored.allAppPodStats, 2)
+	assert.Equal(
+		t,
+		m.allAppPodStats["proxied-pod"].recentStats,
+		restored.allAppPodStats["proxied-pod"].recentStats,
+	)
+	assert.Equal(
+		t,
+		m.allAppPodStats["instrumented-pod"].recentStats,
+		restored.allAppPodStats["instrumented-pod"].recentStats,
+	)
+	// Restored pods are presumed deleted until the collector is informed of them
+	assert.NotNil(t, restored.allAppPodStats["proxied-pod"].podDeletedTime)
+}
+
+func TestRestoreIdleStateErrors(t *testing.T) {
+	testcases := []struct {
+		name        string
+		annotations map[string]string
+		expectError bool
+	}{
+		{
+			name:        "no checkpoint",
+			annotations: map[string]string{},
+		},
+		{
+			name: "invalid checkpoint",
+			annotations: map[string]string{
+				k8s.IdleStateAnnotationName: "idle",
+			},
+			expectError: true,
+		},
+		{
+			name: "checkpoint without check time",
+			annotations: map[string]string{
+				k8s.IdleStateAnnotationName: `{"idleIntervals":2}`,
+			},
+			expectError: true,
+		},
+		{
+			name: "stale checkpoint",
+			annotations: map[string]string{
+				k8s.IdleStateAnnotationName: fmt.Sprintf(
+					`{"checkTime":%q,"idleIntervals":2}`,
+					time.Now().Add(-time.Hour).UTC().Format(time.RFC3339),
+				),
+			},
+			expectError: true,
+		},
+	}
+	for _, test := range testcases {
+		t.Run(test.name, func(t *testing.T) {
+			m := &metricsCollector{
+				metricsCheckInterval: time.Minute,
+				allAppPodStats:       map[string]*podStats{},
+			}
+			lastCheckTime, err := m.restoreIdleState(test.annotations)
+			if test.expectError {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Nil(t, lastCheckTime)
+		})
+	}
+}
+
+func TestShouldCheckpointIdleState(t *testing.T) {
+	idleSince := time.Now()
+	m := &metricsCollector{}
+	// Nothing changed while the app remains active
+	assert.False(t, m.shouldCheckpointIdleState())
+	// The app became idle
+	m.idleSince = &idleSince
+	assert.True(t, m.shouldCheckpointIdleState())
+	m.checkpointedIdleSince = m.idleSince
+	m.checksSinceCheckpoint = 0
+	// While the app remains idle, the state is only checkpointed every few checks
+	for i := 1; i < idleStateCheckpointChecks; i++ {
+		assert.False(t, m.shouldCheckpointIdleState())
+	}
+	assert.True(t, m.shouldCheckpointIdleState())
+	m.checksSinceCheckpoint = 0
+	// The app became active again
+	m.idleSince = nil
+	assert.True(t, m.shouldCheckpointIdleState())
+}
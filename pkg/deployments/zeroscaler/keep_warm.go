@@ -0,0 +1,164 @@
+package zeroscaler
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	k8s "github.com/deislabs/osiris/pkg/kubernetes"
+	"github.com/golang/glog"
+	"github.com/robfig/cron"
+)
+
+// nolint: lll
+var keepWarmAnnotationRegex = regexp.MustCompile(`^osiris\.deislabs\.io/keepWarm(?:-\d+)?$`)
+
+// keepWarmWindow is a recurring window of time during which an app must not
+// be scaled to zero. Each window opens according to a cron schedule, evaluated
+// in a specific time zone, and remains open for a fixed duration.
+type keepWarmWindow struct {
+	spec     string
+	location *time.Location
+	schedule cron.Schedule
+	duration time.Duration
+}
+
+// parseKeepWarmWindow parses a window of the form "<schedule>; <duration>",
+// where the schedule is a standard, five field cron expression optionally
+// prefixed with a time zone, e.g. "TZ=Europe/Berlin 0 8 * * MON-FRI; 10h".
+// When no time zone is specified, UTC is assumed.
+func parseKeepWarmWindow(spec string) (keepWarmWindow, error) {
+	window := keepWarmWindow{
+		spec:     spec,
+		location: time.UTC,
+	}
+	tokens := strings.Split(spec, ";")
+	if len(tokens) != 2 {
+		return window, fmt.Errorf(
+			"Invalid keep warm window %q; expected <schedule>; <duration>",
+			spec,
+		)
+	}
+	scheduleStr := strings.TrimSpace(tokens[0])
+	if strings.HasPrefix(scheduleStr, "TZ=") {
+		fields := strings.SplitN(scheduleStr, " ", 2)
+		var err error
+		window.location, err =
+			time.LoadLocation(strings.TrimPrefix(fields[0], "TZ="))
+		if err != nil {
+			return window, fmt.Errorf(
+				"Invalid time zone in keep warm window %q: %s",
+				spec,
+				err,
+			)
+		}
+		if len(fields) > 1 {
+			scheduleStr = fields[1]
+		} else {
+			scheduleStr = ""
+		}
+	}
+	var err error
+	if window.schedule, err = cron.ParseStandard(scheduleStr); err != nil {
+		return window, fmt.Errorf(
+			"Invalid schedule in keep warm window %q: %s",
+			spec,
+			err,
+		)
+	}
+	window.duration, err = time.ParseDuration(strings.TrimSpace(tokens[1]))
+	if err != nil || window.duration <= 0 {
+		return window, fmt.Errorf(
+			"Invalid duration in keep warm window %q; expected a positive duration",
+			spec,
+		)
+	}
+	return window, nil
+}
+
+// isOpen returns true if the window is open at the given time, i.e. if the
+// schedule was activated no longer ago than the window's duration.
+func (k keepWarmWindow) isOpen(t time.Time) bool {
+	t = t.In(k.location)
+	return !k.schedule.Next(t.Add(-k.duration)).After(t)
+}
+
+// keepWarmPolicy encapsulates all the reasons an otherwise idle app may have
+// for not being scaled to zero.
+type keepWarmPolicy struct {
+	windows []keepWarmWindow
+	until   *time.Time
+}
+
+// getKeepWarmPolicy builds a keepWarmPolicy from an app's effective
+// annotations, i.e. including those inherited from its scale to zero policy or
+// its namespace. Invalid annotations are logged and ignored.
+func getKeepWarmPolicy(
+	appName string,
+	annotations map[string]string,
+) keepWarmPolicy {
+	policy := keepWarmPolicy{}
+	for k, v := range annotations {
+		if keepWarmAnnotationRegex.MatchString(k) {
+			window, err := parseKeepWarmWindow(v)
+			if err != nil {
+				glog.Warningf(
+					"Ignoring annotation %s in %s; error: %s",
+					k,
+					appName,
+					err,
+				)
+				continue
+			}
+			policy.windows = append(policy.windows, window)
+		}
+	}
+	if rawUntil, ok := annotations[k8s.KeepWarmUntilAnnotationName]; ok {
+		until, err := time.Parse(time.RFC3339, rawUntil)
+		if err != nil {
+			glog.Warningf(
+				"Ignoring annotation %s in %s; error: %s",
+				k8s.KeepWarmUntilAnnotationName,
+				appName,
+				err,
+			)
+		} else {
+			policy.until = &until
+		}
+	}
+	return policy
+}
+
+// keepWarm returns true, along with an explanation, if an app must be kept
+// warm at the given time.
+func (k keepWarmPolicy) keepWarm(t time.Time) (bool, string) {
+	if k.until != nil && t.Before(*k.until) {
+		return true, fmt.Sprintf("until %s", k.until.Format(time.RFC3339))
+	}
+	for _, window := range k.windows {
+		if window.isOpen(t) {
+			return true, fmt.Sprintf("during window %q", window.spec)
+		}
+	}
+	return false, ""
+}
+
+// equals compares two policies by the annotation values they were built from.
+func (k keepWarmPolicy) equals(other keepWarmPolicy) bool {
+	if (k.until == nil) != (other.until == nil) ||
+		(k.until != nil && !k.until.Equal(*other.until)) ||
+		len(k.windows) != len(other.windows) {
+		return false
+	}
+	specs := map[string]struct{}{}
+	for _, window := range k.windows {
+		specs[window.spec] = struct{}{}
+	}
+	for _, window := range other.windows {
+		if _, ok := specs[window.spec]; !ok {
+			return false
+		}
+	}
+	return true
+}
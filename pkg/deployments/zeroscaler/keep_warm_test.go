@@ -0,0 +1,165 @@
+package zeroscaler
+
+import (
+	"testing"
+	"time"
+
+	k8s "github.com/deislabs/osiris/pkg/kubernetes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseKeepWarmWindow(t *testing.T) {
+	testcases := []struct {
+		name        string
+		spec        string
+		expectError bool
+	}{
+		{
+			name: "schedule and duration",
+			spec: "0 8 * * MON-FRI; 10h",
+		},
+		{
+			name: "schedule with time zone and duration",
+			spec: "TZ=America/New_York 0 8 * * MON-FRI; 10h",
+		},
+		{
+			name:        "missing duration",
+			spec:        "0 8 * * MON-FRI",
+			expectError: true,
+		},
+		{
+			name:        "invalid time zone",
+			spec:        "TZ=Nowhere/Special 0 8 * * MON-FRI; 10h",
+			expectError: true,
+		},
+		{
+			name:        "invalid schedule",
+			spec:        "every morning; 10h",
+			expectError: true,
+		},
+		{
+			name:        "negative duration",
+			spec:        "0 8 * * MON-FRI; -10h",
+			expectError: true,
+		},
+	}
+
+	for _, test := range testcases {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := parseKeepWarmWindow(test.spec)
+			if test.expectError {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestKeepWarmWindowIsOpen(t *testing.T) {
+	// Weekdays from 08:00 until 18:00, New York time
+	window, err := parseKeepWarmWindow(
+		"TZ=America/New_York 0 8 * * MON-FRI; 10h",
+	)
+	require.NoError(t, err)
+	newYork, err := time.LoadLocation("America/New_York")
+	require.NoError(t, err)
+
+	testcases := []struct {
+		name           string
+		t              time.Time
+		expectedResult bool
+	}{
+		{
+			name:           "weekday, before the window opens",
+			t:              time.Date(2019, 1, 7, 7, 59, 0, 0, newYork),
+			expectedResult: false,
+		},
+		{
+			name:           "weekday, when the window opens",
+			t:              time.Date(2019, 1, 7, 8, 0, 0, 0, newYork),
+			expectedResult: true,
+		},
+		{
+			name:           "weekday, during the window, in another time zone",
+			t:              time.Date(2019, 1, 7, 20, 0, 0, 0, time.UTC),
+			expectedResult: true,
+		},
+		{
+			name:           "weekday, after the window closes",
+			t:              time.Date(2019, 1, 7, 18, 1, 0, 0, newYork),
+			expectedResult: false,
+		},
+		{
+			name:           "weekend",
+			t:              time.Date(2019, 1, 6, 12, 0, 0, 0, newYork),
+			expectedResult: false,
+		},
+	}
+
+	for _, test := range testcases {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expectedResult, window.isOpen(test.t))
+		})
+	}
+}
+
+func TestKeepWarmPolicy(t *testing.T) {
+	now := time.Date(2019, 1, 6, 12, 0, 0, 0, time.UTC)
+	testcases := []struct {
+		name           string
+		annotations    map[string]string
+		expectedResult bool
+	}{
+		{
+			name:           "no annotations",
+			annotations:    map[string]string{},
+			expectedResult: false,
+		},
+		{
+			name: "kept warm until later",
+			annotations: map[string]string{
+				k8s.KeepWarmUntilAnnotationName: "2019-01-06T13:00:00Z",
+			},
+			expectedResult: true,
+		},
+		{
+			name: "kept warm until earlier",
+			annotations: map[string]string{
+				k8s.KeepWarmUntilAnnotationName: "2019-01-06T11:00:00Z",
+			},
+			expectedResult: false,
+		},
+		{
+			name: "invalid keep warm until",
+			annotations: map[string]string{
+				k8s.KeepWarmUntilAnnotationName: "tomorrow",
+			},
+			expectedResult: false,
+		},
+		{
+			name: "one of several windows is open",
+			annotations: map[string]string{
+				"osiris.deislabs.io/keepWarm-1": "0 8 * * MON-FRI; 10h",
+				"osiris.deislabs.io/keepWarm-2": "0 10 * * SAT,SUN; 4h",
+			},
+			expectedResult: true,
+		},
+		{
+			name: "no window is open",
+			annotations: map[string]string{
+				"osiris.deislabs.io/keepWarm": "0 8 * * MON-FRI; 10h",
+			},
+			expectedResult: false,
+		},
+	}
+
+	for _, test := range testcases {
+		t.Run(test.name, func(t *testing.T) {
+			policy := getKeepWarmPolicy("my-app", test.annotations)
+			actual, _ := policy.keepWarm(now)
+			assert.Equal(t, test.expectedResult, actual)
+		})
+	}
+}
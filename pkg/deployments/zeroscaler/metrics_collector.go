@@ -3,62 +3,99 @@ package zeroscaler
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io/ioutil"
 	"net/http"
 	"sync"
 	"time"
 
 	k8s "github.com/deislabs/osiris/pkg/kubernetes"
-	"github.com/deislabs/osiris/pkg/metrics"
 	"github.com/golang/glog"
+	"golang.org/x/time/rate"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/labels"
-	k8s_types "k8s.io/apimachinery/pkg/types"
-	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
 )
 
 const (
 	proxyContainerName = "osiris-proxy"
 	proxyPortName      = "osiris-metrics"
+
+	scaledToZeroEventReason           = "ScaledToZero"
+	scaleToZeroFailedEventReason      = "ScaleToZeroFailed"
+	scaleToZeroVetoedEventReason      = "ScaleToZeroVetoed"
+	scaleToZeroPostponedEventReason   = "ScaleToZeroPostponed"
+	scaleToZeroRateLimitedEventReason = "ScaleToZeroRateLimited"
+	preScaleDownHookFailedEventReason = "PreScaleDownHookFailed"
 )
 
 type metricsCollector struct {
-	kubeClient           kubernetes.Interface
-	deploymentName       string
-	deploymentNamespace  string
+	podsInformer         *sharedPodsInformer
+	workloadsClient      k8s.WorkloadsClient
+	scaleToZeroLimiter   *rate.Limiter
+	workload             k8s.WorkloadReference
+	appNamespace         string
+	appObjectRef         *corev1.ObjectReference
+	eventRecorder        record.EventRecorder
 	selector             labels.Selector
 	metricsCheckInterval time.Duration
-	podsInformer         cache.SharedIndexInformer
+	idleThreshold        k8s.IdleThreshold
+	keepWarm             keepWarmPolicy
+	dryRun               bool
+	activitySource       activitySource
+	preScaleDownHook     preScaleDownHook
+	idleIntervals        int
+	idleSince            *time.Time
+	postponedUntil       *time.Time
 	currentAppPods       map[string]*corev1.Pod
 	allAppPodStats       map[string]*podStats
 	appPodsLock          sync.Mutex
 	httpClient           *http.Client
 	cancelFunc           func()
+
+	// checkpointedIdleSince and checksSinceCheckpoint describe the most recent
+	// checkpoint of idle tracking state
+	checkpointedIdleSince *time.Time
+	checksSinceCheckpoint int
+	// lastActivityAnnotationTime is when the app's last activity was most
+	// recently recorded on its workload
+	lastActivityAnnotationTime *time.Time
 }
 
 func newMetricsCollector(
-	kubeClient kubernetes.Interface,
-	deploymentName string,
-	deploymentNamespace string,
+	podsInformer *sharedPodsInformer,
+	workloadsClient k8s.WorkloadsClient,
+	scaleToZeroLimiter *rate.Limiter,
+	workload k8s.WorkloadReference,
+	appNamespace string,
+	appObjectRef *corev1.ObjectReference,
+	eventRecorder record.EventRecorder,
 	selector labels.Selector,
 	metricsCheckInterval time.Duration,
+	idleThreshold k8s.IdleThreshold,
+	keepWarm keepWarmPolicy,
+	dryRun bool,
+	activitySource activitySource,
+	preScaleDownHook preScaleDownHook,
 ) *metricsCollector {
-	m := &metricsCollector{
-		kubeClient:           kubeClient,
-		deploymentName:       deploymentName,
-		deploymentNamespace:  deploymentNamespace,
+	return &metricsCollector{
+		podsInformer:         podsInformer,
+		workloadsClient:      workloadsClient,
+		scaleToZeroLimiter:   scaleToZeroLimiter,
+		workload:             workload,
+		appNamespace:         appNamespace,
+		appObjectRef:         appObjectRef,
+		eventRecorder:        eventRecorder,
 		selector:             selector,
 		metricsCheckInterval: metricsCheckInterval,
-		podsInformer: k8s.PodsIndexInformer(
-			kubeClient,
-			deploymentNamespace,
-			nil,
-			selector,
-		),
-		currentAppPods: map[string]*corev1.Pod{},
-		allAppPodStats: map[string]*podStats{},
+		idleThreshold:        idleThreshold,
+		keepWarm:             keepWarm,
+		dryRun:               dryRun,
+		activitySource:       activitySource,
+		preScaleDownHook:     preScaleDownHook,
+		currentAppPods:       map[string]*corev1.Pod{},
+		allAppPodStats:       map[string]*podStats{},
 		// A very aggressive timeout. When collecting metrics, we want to do it very
 		// quickly to minimize the possibility that some pods we've checked on have
 		// served requests while we've been checking on OTHER pods.
@@ -66,14 +103,6 @@ func newMetricsCollector(
 			Timeout: 2 * time.Second,
 		},
 	}
-	m.podsInformer.AddEventHandler(cache.ResourceEventHandlerFuncs{
-		AddFunc: m.syncAppPod,
-		UpdateFunc: func(_, newObj interface{}) {
-			m.syncAppPod(newObj)
-		},
-		DeleteFunc: m.syncDeletedAppPod,
-	})
-	return m
 }
 
 func (m *metricsCollector) run(ctx context.Context) {
@@ -82,19 +111,74 @@ func (m *metricsCollector) run(ctx context.Context) {
 	go func() {
 		<-ctx.Done()
 		glog.Infof(
-			"Stopping metrics collection for deployment %s in namespace %s",
-			m.deploymentName,
-			m.deploymentNamespace,
+			"Stopping metrics collection for %s in namespace %s",
+			m.workload,
+			m.appNamespace,
 		)
 	}()
 	glog.Infof(
-		"Starting metrics collection for deployment %s in namespace %s",
-		m.deploymentName,
-		m.deploymentNamespace,
+		"Starting metrics collection for %s in namespace %s",
+		m.workload,
+		m.appNamespace,
+	)
+	activeMetricsCollectors.Inc()
+	defer func() {
+		activeMetricsCollectors.Dec()
+		labelValues := getWorkloadLabelValues(m.appNamespace, m.workload)
+		workloadIdle.DeleteLabelValues(labelValues...)
+		consecutiveIdleIntervals.DeleteLabelValues(labelValues...)
+	}()
+	lastCheckTime := m.restoreIdleStateFromWorkload()
+	subscription := m.podsInformer.subscribe(
+		m.appNamespace,
+		m.workload,
+		m.selector,
+		cache.ResourceEventHandlerFuncs{
+			AddFunc: m.syncAppPod,
+			UpdateFunc: func(_, newObj interface{}) {
+				m.syncAppPod(newObj)
+			},
+			DeleteFunc: m.syncDeletedAppPod,
+		},
 	)
-	go m.podsInformer.Run(ctx.Done())
-	// When this exits, the cancel func will stop the informer
-	m.collectMetrics(ctx)
+	defer m.podsInformer.unsubscribe(subscription)
+	m.collectMetrics(ctx, lastCheckTime)
+}
+
+// restoreIdleStateFromWorkload restores idle tracking state from the checkpoint
+// in the app's workload's annotations, if any, and returns the time of the
+// check at which that checkpoint was taken.
+func (m *metricsCollector) restoreIdleStateFromWorkload() *time.Time {
+	workload, err := m.workloadsClient.Get(m.appNamespace, m.workload)
+	if err != nil {
+		glog.Errorf(
+			"Error getting %s in namespace %s; idle tracking will start over: %s",
+			m.workload,
+			m.appNamespace,
+			err,
+		)
+		return nil
+	}
+	lastCheckTime, err := m.restoreIdleState(workload.GetAnnotations())
+	if err != nil {
+		glog.Warningf(
+			"Error restoring idle state of %s in namespace %s; idle tracking will "+
+				"start over: %s",
+			m.workload,
+			m.appNamespace,
+			err,
+		)
+		return nil
+	}
+	if lastCheckTime != nil {
+		glog.Infof(
+			"Restored idle state of %s in namespace %s as of %s",
+			m.workload,
+			m.appNamespace,
+			lastCheckTime,
+		)
+	}
+	return lastCheckTime
 }
 
 func (m *metricsCollector) stop() {
@@ -106,8 +190,11 @@ func (m *metricsCollector) syncAppPod(obj interface{}) {
 	defer m.appPodsLock.Unlock()
 	pod := obj.(*corev1.Pod)
 	m.currentAppPods[pod.Name] = pod
-	if _, ok := m.allAppPodStats[pod.Name]; !ok {
+	if ps, ok := m.allAppPodStats[pod.Name]; !ok {
 		m.allAppPodStats[pod.Name] = &podStats{}
+	} else {
+		// The pod may have been presumed deleted when idle state was restored
+		ps.podDeletedTime = nil
 	}
 }
 
@@ -122,10 +209,18 @@ func (m *metricsCollector) syncDeletedAppPod(obj interface{}) {
 	}
 }
 
-func (m *metricsCollector) collectMetrics(ctx context.Context) {
+// collectMetrics periodically checks on the app's activity. If idle state was
+// restored, lastCheckTime is the time of the check at which it was
+// checkpointed; otherwise it is nil.
+func (m *metricsCollector) collectMetrics(
+	ctx context.Context,
+	lastCheckTime *time.Time,
+) {
+	labelValues := getWorkloadLabelValues(m.appNamespace, m.workload)
 	ticker := time.NewTicker(m.metricsCheckInterval)
 	defer ticker.Stop()
-	var periodStartTime, periodEndTime *time.Time
+	periodEndTime := lastCheckTime
+	var periodStartTime *time.Time
 	for {
 		select {
 		case <-ticker.C:
@@ -136,89 +231,121 @@ func (m *metricsCollector) collectMetrics(ctx context.Context) {
 			// to be executed regardless of which condition causes us to continue to
 			// the next iteration of the loop.
 			func() {
-				m.appPodsLock.Lock()
-				defer m.appPodsLock.Unlock()
-				// An aggressively small timeout. We make the decision fast or not at
-				// all.
-				timer := time.NewTimer(3 * time.Second)
-				defer timer.Stop()
-				var timedOut bool
-				// Get metrics for all of the deployment's CURRENT pods.
-				var scrapeWG sync.WaitGroup
-				for _, pod := range m.currentAppPods {
-					podMetricsPort, ok := getMetricsPort(pod)
-					if !ok {
-						continue
+				// However this check turns out, persist the resulting state if it
+				// changed, unless the app was scaled to zero, which ends idle tracking
+				var scaledToZero bool
+				defer func() {
+					if !scaledToZero && m.shouldCheckpointIdleState() {
+						m.checkpointIdleState(*periodEndTime)
 					}
-					url := fmt.Sprintf(
-						"http://%s:%d/metrics",
-						pod.Status.PodIP,
-						podMetricsPort,
-					)
-					scrapeWG.Add(1)
-					go func(podName string) {
-						defer scrapeWG.Done()
-						// Get the results
-						pcs, ok := m.scrape(url)
-						if ok {
-							ps := m.allAppPodStats[podName]
-							ps.prevStatTime = ps.recentStatTime
-							ps.prevStats = ps.recentStats
-							ps.recentStatTime = periodEndTime
-							ps.recentStats = &pcs
-						}
-					}(pod.Name)
-				}
-				// Wait until we're done checking all pods.
-				scrapeWG.Wait()
+				}()
+				timedOut, foundActivity, assumedActivity :=
+					m.checkActivity(periodStartTime, periodEndTime)
 				// If this is our first check, we're done because we will have no
 				// previous stats to compare recent stats to.
 				if periodStartTime == nil {
 					return
 				}
-				// Now iterate over stats for ALL of the deployment's pods-- this may
-				// include pods that died since the last check-- their stats should
-				// still count, but since we won't have stats for those, we'll have to
-				// err on the side of caution and assume activity in such cases.
-				var foundActivity, assumedActivity bool
-				for podName, ps := range m.allAppPodStats {
-					if ps.podDeletedTime != nil &&
-						ps.podDeletedTime.Before(*periodStartTime) {
-						// This pod was deleted before the period we'red concerned with, so
-						// stats from this pod are not relevant anymore or ever again.
-						delete(m.allAppPodStats, podName)
-						continue
+				if timedOut || foundActivity || assumedActivity {
+					// Idleness must be continuous, so start counting over
+					m.idleIntervals = 0
+					m.idleSince = nil
+					workloadIdle.WithLabelValues(labelValues...).Set(0)
+					consecutiveIdleIntervals.WithLabelValues(labelValues...).Set(0)
+					var decision string
+					switch {
+					case timedOut:
+						decision = decisionTimedOut
+					case assumedActivity:
+						decision = decisionAssumedActivity
+					default:
+						decision = decisionActivity
 					}
-					// If we already assumed some activity or found some, fast forward to
-					// the next pod. We don't simply break out of the whole loop because
-					// the logic above removes stats for pods that are no longer relevant
-					// and we still want that to happen promptly.
-					if assumedActivity || foundActivity {
-						continue
-					}
-					// nolint: lll
-					if (ps.prevStatTime == nil || ps.recentStatTime == nil) || // We cannot make meaningful comparisons for this pod
-						ps.recentStatTime.Before(*periodEndTime) || // We don't have up-to-date stats for this pod
-						ps.recentStats.ProxyID != ps.prevStats.ProxyID { // The pod's metrics-collecting proxy sidecar died and was replaced
-						assumedActivity = true
-						continue
-					}
-					// nolint: lll
-					if ps.recentStats.ConnectionsOpened > ps.prevStats.ConnectionsOpened || // New connections have been opened
-						ps.recentStats.ConnectionsClosed > ps.prevStats.ConnectionsClosed || // Opened connections were closed
-						ps.recentStats.ConnectionsOpened > ps.recentStats.ConnectionsClosed { // Some connections remain open
-						foundActivity = true
-						continue
+					scaleToZeroDecisionsTotal.WithLabelValues(
+						append(labelValues, decision)...,
+					).Inc()
+					if foundActivity {
+						// Only activity actually observed is recorded
+						m.recordLastActivity(*periodEndTime)
 					}
+					return
 				}
-				select {
-				case <-timer.C:
-					timedOut = true
-				default:
+				if m.idleSince == nil {
+					m.idleSince = periodStartTime
 				}
-				if !(timedOut || foundActivity || assumedActivity) {
-					m.scaleToZero()
+				m.idleIntervals++
+				workloadIdle.WithLabelValues(labelValues...).Set(1)
+				consecutiveIdleIntervals.WithLabelValues(labelValues...).Set(
+					float64(m.idleIntervals),
+				)
+				idleDuration := periodEndTime.Sub(*m.idleSince)
+				if !m.idleThreshold.Reached(m.idleIntervals, idleDuration) {
+					glog.Infof(
+						"%s in namespace %s has been idle for %d consecutive "+
+							"interval(s); idle threshold of %s not yet reached",
+						m.workload,
+						m.appNamespace,
+						m.idleIntervals,
+						m.idleThreshold,
+					)
+					scaleToZeroDecisionsTotal.WithLabelValues(
+						append(labelValues, decisionIdleThresholdUnmet)...,
+					).Inc()
+					return
 				}
+				if keepWarm, reason := m.keepWarm.keepWarm(*periodEndTime); keepWarm {
+					glog.Infof(
+						"%s in namespace %s is idle, but is being kept warm %s",
+						m.workload,
+						m.appNamespace,
+						reason,
+					)
+					scaleToZeroDecisionsTotal.WithLabelValues(
+						append(labelValues, decisionKeptWarm)...,
+					).Inc()
+					return
+				}
+				if m.dryRun {
+					scaleToZeroDecisionsTotal.WithLabelValues(
+						append(labelValues, decisionDryRun)...,
+					).Inc()
+					m.reportDryRunScaleToZero(*periodEndTime, idleDuration)
+					// Start counting over so that the next report reflects another
+					// full idle threshold's worth of idleness
+					m.idleIntervals = 0
+					m.idleSince = nil
+					return
+				}
+				if m.postponedUntil != nil && periodEndTime.Before(*m.postponedUntil) {
+					glog.Infof(
+						"%s in namespace %s is idle, but its pre-scale-down hook "+
+							"postponed scaling it to zero until %s",
+						m.workload,
+						m.appNamespace,
+						m.postponedUntil.Format(time.RFC3339),
+					)
+					scaleToZeroDecisionsTotal.WithLabelValues(
+						append(labelValues, decisionPostponed)...,
+					).Inc()
+					return
+				}
+				m.postponedUntil = nil
+				// The cluster-wide rate limit is checked before the pre-scale-down
+				// hook is called so that the hook isn't consulted about scaling an app
+				// to zero that couldn't be scaled to zero anyway. If the hook then
+				// keeps the app from being scaled to zero, the limit errs on the side
+				// of scaling fewer apps to zero.
+				if !m.allowScaleToZero() {
+					return
+				}
+				if m.preScaleDownHook.enabled() &&
+					!m.runPreScaleDownHook(*periodEndTime, idleDuration) {
+					return
+				}
+				scaleToZeroDecisionsTotal.WithLabelValues(
+					append(labelValues, decisionScaledToZero)...,
+				).Inc()
+				scaledToZero = m.scaleToZero(idleDuration)
 			}()
 		case <-ctx.Done():
 			return
@@ -226,89 +353,425 @@ func (m *metricsCollector) collectMetrics(ctx context.Context) {
 	}
 }
 
-func getMetricsPort(pod *corev1.Pod) (int32, bool) {
-	for _, c := range pod.Spec.Containers {
-		if c.Name == proxyContainerName && len(c.Ports) > 0 {
-			for _, port := range c.Ports {
-				if port.Name == proxyPortName {
-					return port.ContainerPort, true
-				}
+// checkActivity scrapes stats from all of the app's current pods and compares
+// them to those from the start of the period ending at periodEndTime to
+// determine whether the app was active during that period. If this is the
+// first check, periodStartTime is nil and there is nothing to compare yet. The
+// app pods lock is only held while stats are updated and compared, never
+// while pods are scraped, so that the collector is promptly informed about
+// pods regardless of how long scraping takes.
+func (m *metricsCollector) checkActivity(
+	periodStartTime *time.Time,
+	periodEndTime *time.Time,
+) (timedOut bool, foundActivity bool, assumedActivity bool) {
+	// An aggressively small timeout. We make the decision fast or not at all.
+	timer := time.NewTimer(3 * time.Second)
+	defer timer.Stop()
+	m.appPodsLock.Lock()
+	pods := make([]*corev1.Pod, 0, len(m.currentAppPods))
+	for _, pod := range m.currentAppPods {
+		pods = append(pods, pod)
+	}
+	m.appPodsLock.Unlock()
+	// Get metrics for all of the app's CURRENT pods.
+	allStats := make([]activityStats, len(pods))
+	var scrapeWG sync.WaitGroup
+	for i, pod := range pods {
+		scrapeWG.Add(1)
+		go func(i int, pod *corev1.Pod) {
+			defer scrapeWG.Done()
+			if stats, ok := m.scrape(pod); ok {
+				allStats[i] = stats
 			}
+		}(i, pod)
+	}
+	// Wait until we're done checking all pods.
+	scrapeWG.Wait()
+	m.appPodsLock.Lock()
+	defer m.appPodsLock.Unlock()
+	for i, pod := range pods {
+		ps, ok := m.allAppPodStats[pod.Name]
+		if !ok || allStats[i] == nil {
+			continue
+		}
+		ps.prevStatTime = ps.recentStatTime
+		ps.prevStats = ps.recentStats
+		ps.recentStatTime = periodEndTime
+		ps.recentStats = allStats[i]
+	}
+	if periodStartTime == nil {
+		return false, false, false
+	}
+	// Now iterate over stats for ALL of the app's pods-- this may include pods
+	// that died since the last check-- their stats should still count, but
+	// since we won't have stats for those, we'll have to err on the side of
+	// caution and assume activity in such cases.
+	for podName, ps := range m.allAppPodStats {
+		if ps.podDeletedTime != nil &&
+			ps.podDeletedTime.Before(*periodStartTime) {
+			// This pod was deleted before the period we'red concerned with, so
+			// stats from this pod are not relevant anymore or ever again.
+			delete(m.allAppPodStats, podName)
+			continue
+		}
+		// If we already assumed some activity or found some, fast forward to the
+		// next pod. We don't simply break out of the whole loop because the logic
+		// above removes stats for pods that are no longer relevant and we still
+		// want that to happen promptly.
+		if assumedActivity || foundActivity {
+			continue
+		}
+		switch {
+		case ps.recentStatTime == nil ||
+			ps.recentStatTime.Before(*periodEndTime):
+			// We don't have up-to-date stats for this pod
+			assumedActivity = true
+		case ps.prevStatTime != nil &&
+			ps.recentStats.comparableTo(ps.prevStats):
+			foundActivity = ps.recentStats.activitySince(ps.prevStats)
+		case ps.recentStats.startedWithin(
+			ps.recentStatTime.Sub(*periodStartTime),
+		):
+			// We have no comparable stats from the start of the period, but only
+			// because the pod or its proxy sidecar (re)started during the period.
+			// Counting from zero accounts for everything since then.
+			foundActivity = ps.recentStats.activitySinceStart()
+		default:
+			// We cannot make meaningful comparisons for this pod
+			assumedActivity = true
 		}
 	}
-	return 0, false
+	select {
+	case <-timer.C:
+		timedOut = true
+	default:
+	}
+	return timedOut, foundActivity, assumedActivity
 }
 
 func (m *metricsCollector) scrape(
-	target string,
-) (metrics.ProxyConnectionStats, bool) {
-	pcs := metrics.ProxyConnectionStats{}
-	// Requests made with this client time out after 2 seconds
-	resp, err := m.httpClient.Get(target)
+	pod *corev1.Pod,
+) (stats activityStats, ok bool) {
+	labelValues := getWorkloadLabelValues(m.appNamespace, m.workload)
+	start := time.Now()
+	defer func() {
+		scrapeDurationSeconds.WithLabelValues(labelValues...).Observe(
+			time.Since(start).Seconds(),
+		)
+		result := "success"
+		if !ok {
+			result = "failure"
+		}
+		scrapesTotal.WithLabelValues(append(labelValues, result)...).Inc()
+	}()
+	stats, err := m.activitySource.getActivityStats(m.httpClient, pod)
 	if err != nil {
-		glog.Errorf("Error requesting metrics from %s: %s", target, err)
-		return pcs, false
-	}
-	defer resp.Body.Close()
-	if resp.StatusCode != 200 {
 		glog.Errorf(
-			"Received unexpected HTTP response code %d when requesting metrics "+
-				"from %s",
-			resp.StatusCode,
-			target,
+			"Error getting activity stats for pod %s in namespace %s: %s",
+			pod.Name,
+			m.appNamespace,
+			err,
 		)
-		return pcs, false
+		return nil, false
 	}
-	bodyBytes, err := ioutil.ReadAll(resp.Body)
+	return stats, true
+}
+
+// dryRunScaleToZeroRecord records when an app in dry-run mode would have been
+// scaled to zero and how long it had been idle for at that time.
+type dryRunScaleToZeroRecord struct {
+	Timestamp    string `json:"timestamp"`
+	IdleDuration string `json:"idleDuration"`
+}
+
+// reportDryRunScaleToZero reports, by way of logging and annotating the app's
+// workload, that the app would have been scaled to zero if it were not in
+// dry-run mode.
+func (m *metricsCollector) reportDryRunScaleToZero(
+	t time.Time,
+	idleDuration time.Duration,
+) {
+	glog.Infof(
+		"Dry run: would have scaled %s in namespace %s to zero after being "+
+			"idle for %s",
+		m.workload,
+		m.appNamespace,
+		idleDuration,
+	)
+	recordBytes, err := json.Marshal(dryRunScaleToZeroRecord{
+		Timestamp:    t.UTC().Format(time.RFC3339),
+		IdleDuration: idleDuration.String(),
+	})
 	if err != nil {
+		glog.Errorf("Error marshaling dry run record: %s", err)
+		return
+	}
+	m.annotate(map[string]string{
+		k8s.DryRunScaleToZeroAnnotationName: string(recordBytes),
+	})
+}
+
+// annotate adds the given annotations to the app's workload. These annotations
+// are informational only, so errors are logged, but otherwise ignored.
+func (m *metricsCollector) annotate(annotations map[string]string) {
+	if err := m.workloadsClient.Annotate(
+		m.appNamespace,
+		m.workload,
+		annotations,
+	); err != nil {
 		glog.Errorf(
-			"Error reading metrics request response from %s: %s",
-			target,
+			"Error annotating %s in namespace %s: %s",
+			m.workload,
+			m.appNamespace,
 			err,
 		)
-		return pcs, false
 	}
-	if err := json.Unmarshal(bodyBytes, &pcs); err != nil {
+}
+
+// removeAnnotations removes the given annotations from the app's workload.
+// Like those added by annotate, they are informational only, so errors are
+// logged, but otherwise ignored.
+func (m *metricsCollector) removeAnnotations(names ...string) {
+	if err := m.workloadsClient.RemoveAnnotations(
+		m.appNamespace,
+		m.workload,
+		names...,
+	); err != nil {
 		glog.Errorf(
-			"Error umarshaling metrics request response from %s: %s",
-			target,
+			"Error removing annotations from %s in namespace %s: %s",
+			m.workload,
+			m.appNamespace,
 			err,
 		)
-		return pcs, false
 	}
-	return pcs, true
 }
 
-func (m *metricsCollector) scaleToZero() {
+// allowScaleToZero returns whether the cluster-wide limit on scaling apps to
+// zero, if there is one, permits scaling the app to zero now, in which case
+// that counts against the limit.
+func (m *metricsCollector) allowScaleToZero() bool {
+	if m.scaleToZeroLimiter == nil || m.scaleToZeroLimiter.Allow() {
+		return true
+	}
+	// The app remains idle, so it will be considered for scaling to zero again
+	// in the next interval
 	glog.Infof(
-		"Scale to zero starting for deployment %s in namespace %s",
-		m.deploymentName,
-		m.deploymentNamespace,
+		"%s in namespace %s is idle, but the cluster-wide limit on scaling to "+
+			"zero has been reached",
+		m.workload,
+		m.appNamespace,
+	)
+	m.eventRecorder.Event(
+		m.appObjectRef,
+		corev1.EventTypeNormal,
+		scaleToZeroRateLimitedEventReason,
+		"Scaling to zero was deferred by the cluster-wide rate limit",
 	)
+	scaleToZeroDecisionsTotal.WithLabelValues(
+		append(
+			getWorkloadLabelValues(m.appNamespace, m.workload),
+			decisionRateLimited,
+		)...,
+	).Inc()
+	return false
+}
 
-	patches := []k8s.PatchOperation{{
-		Op:    "replace",
-		Path:  "/spec/replicas",
-		Value: 0,
-	}}
-	patchesBytes, _ := json.Marshal(patches)
-	if _, err := m.kubeClient.AppsV1().Deployments(m.deploymentNamespace).Patch(
-		m.deploymentName,
-		k8s_types.JSONPatchType,
-		patchesBytes,
-	); err != nil {
+// runPreScaleDownHook calls the app's pre-scale-down hook on one of the app's
+// pods and returns whether the app may be scaled to zero. If the hook vetoes
+// scaling the app to zero, the app is considered busy, so idleness must start
+// to be counted over. If the hook postpones scaling the app to zero, it is
+// not called again until the postponement expires. If the hook fails, the
+// app's failure policy applies.
+func (m *metricsCollector) runPreScaleDownHook(
+	now time.Time,
+	idleDuration time.Duration,
+) bool {
+	labelValues := getWorkloadLabelValues(m.appNamespace, m.workload)
+	action, postponeFor, err := m.callPreScaleDownHook(idleDuration)
+	if err != nil {
 		glog.Errorf(
-			"Error scaling deployment %s in namespace %s to zero: %s",
-			m.deploymentName,
-			m.deploymentNamespace,
+			"Error running pre-scale-down hook of %s in namespace %s: %s",
+			m.workload,
+			m.appNamespace,
+			err,
+		)
+		m.eventRecorder.Eventf(
+			m.appObjectRef,
+			corev1.EventTypeWarning,
+			preScaleDownHookFailedEventReason,
+			"Pre-scale-down hook failed: %s",
 			err,
 		)
+		if m.preScaleDownHook.failOpen {
+			return true
+		}
+		scaleToZeroDecisionsTotal.WithLabelValues(
+			append(labelValues, decisionPreScaleDownHookFailed)...,
+		).Inc()
+		return false
+	}
+	switch action {
+	case preScaleDownHookActionVeto:
+		glog.Infof(
+			"Pre-scale-down hook of %s in namespace %s vetoed scaling it to zero",
+			m.workload,
+			m.appNamespace,
+		)
+		m.eventRecorder.Event(
+			m.appObjectRef,
+			corev1.EventTypeNormal,
+			scaleToZeroVetoedEventReason,
+			"Pre-scale-down hook vetoed scaling to zero",
+		)
+		m.idleIntervals = 0
+		m.idleSince = nil
+		workloadIdle.WithLabelValues(labelValues...).Set(0)
+		consecutiveIdleIntervals.WithLabelValues(labelValues...).Set(0)
+		scaleToZeroDecisionsTotal.WithLabelValues(
+			append(labelValues, decisionVetoed)...,
+		).Inc()
+		return false
+	case preScaleDownHookActionPostpone:
+		postponedUntil := now.Add(postponeFor)
+		m.postponedUntil = &postponedUntil
+		glog.Infof(
+			"Pre-scale-down hook of %s in namespace %s postponed scaling it to "+
+				"zero for %s",
+			m.workload,
+			m.appNamespace,
+			postponeFor,
+		)
+		m.eventRecorder.Eventf(
+			m.appObjectRef,
+			corev1.EventTypeNormal,
+			scaleToZeroPostponedEventReason,
+			"Pre-scale-down hook postponed scaling to zero for %s",
+			postponeFor,
+		)
+		scaleToZeroDecisionsTotal.WithLabelValues(
+			append(labelValues, decisionPostponed)...,
+		).Inc()
+		return false
+	}
+	return true
+}
+
+// callPreScaleDownHook calls the app's pre-scale-down hook on one of the app's
+// running pods, chosen deterministically so that consecutive calls are likely
+// to reach the same pod.
+func (m *metricsCollector) callPreScaleDownHook(
+	idleDuration time.Duration,
+) (string, time.Duration, error) {
+	hookPod := m.getHookPod()
+	if hookPod == nil {
+		return "", 0, errors.New("No running pod to call the hook on")
+	}
+	return m.preScaleDownHook.call(
+		m.preScaleDownHook.url(hookPod),
+		preScaleDownHookRequest{
+			Namespace:    m.appNamespace,
+			Kind:         m.workload.Kind,
+			Name:         m.workload.Name,
+			IdleDuration: idleDuration.String(),
+		},
+	)
+}
+
+// getHookPod returns the running pod, if any, with the lowest name amongst the
+// app's current pods.
+func (m *metricsCollector) getHookPod() *corev1.Pod {
+	m.appPodsLock.Lock()
+	defer m.appPodsLock.Unlock()
+	var hookPod *corev1.Pod
+	for _, pod := range m.currentAppPods {
+		if pod.Status.Phase != corev1.PodRunning || pod.Status.PodIP == "" {
+			continue
+		}
+		if hookPod == nil || pod.Name < hookPod.Name {
+			hookPod = pod
+		}
+	}
+	return hookPod
+}
+
+// recordLastActivity records activity observed on the app at the given time on
+// its workload. Activity is observed in most checks of a busy app, so it is
+// only recorded every few checks, lest the workload be updated after every one.
+func (m *metricsCollector) recordLastActivity(t time.Time) {
+	if m.lastActivityAnnotationTime != nil &&
+		t.Sub(*m.lastActivityAnnotationTime) <
+			idleStateCheckpointChecks*m.metricsCheckInterval {
 		return
 	}
+	m.lastActivityAnnotationTime = &t
+	m.annotate(map[string]string{
+		k8s.LastActivityAnnotationName: t.UTC().Format(time.RFC3339),
+	})
+}
+
+// scaleToZero scales the app to zero replicas and returns whether that
+// succeeded. idleDuration is how long the app had been idle for and is
+// recorded, along with the outcome, as an event on the app's workload. Idle
+// tracking ends with the app being scaled to zero, so the checkpoint of idle
+// tracking state is cleared.
+func (m *metricsCollector) scaleToZero(idleDuration time.Duration) bool {
+	glog.Infof(
+		"Scale to zero starting for %s in namespace %s",
+		m.workload,
+		m.appNamespace,
+	)
+
+	if err := m.workloadsClient.Scale(m.appNamespace, m.workload, 0); err != nil {
+		scaleToZeroErrorsTotal.WithLabelValues(
+			getWorkloadLabelValues(m.appNamespace, m.workload)...,
+		).Inc()
+		glog.Errorf(
+			"Error scaling %s in namespace %s to zero: %s",
+			m.workload,
+			m.appNamespace,
+			err,
+		)
+		m.eventRecorder.Eventf(
+			m.appObjectRef,
+			corev1.EventTypeWarning,
+			scaleToZeroFailedEventReason,
+			"Error scaling to zero after being idle for %s (%d consecutive "+
+				"interval(s)): %s",
+			idleDuration,
+			m.idleIntervals,
+			err,
+		)
+		return false
+	}
+
+	reason := fmt.Sprintf(
+		"Idle for %s (%d consecutive interval(s)); idle threshold is %s",
+		idleDuration,
+		m.idleIntervals,
+		m.idleThreshold,
+	)
+	m.eventRecorder.Eventf(
+		m.appObjectRef,
+		corev1.EventTypeNormal,
+		scaledToZeroEventReason,
+		"Scaled to zero after being idle for %s (%d consecutive interval(s)); "+
+			"idle threshold is %s",
+		idleDuration,
+		m.idleIntervals,
+		m.idleThreshold,
+	)
+	m.annotate(map[string]string{
+		k8s.LastScaledToZeroAnnotationName: time.Now().UTC().Format(
+			time.RFC3339,
+		),
+		k8s.LastScaledToZeroReasonAnnotationName: reason,
+	})
+	m.removeAnnotations(k8s.IdleStateAnnotationName)
 
 	glog.Infof(
-		"Scaled deployment %s in namespace %s to zero",
-		m.deploymentName,
-		m.deploymentNamespace,
+		"Scaled %s in namespace %s to zero",
+		m.workload,
+		m.appNamespace,
 	)
+	return true
 }
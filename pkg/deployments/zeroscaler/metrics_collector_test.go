@@ -0,0 +1,204 @@
+package zeroscaler
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	k8s "github.com/deislabs/osiris/pkg/kubernetes"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/time/rate"
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/tools/record"
+)
+
+type fakeWorkloadsClient struct {
+	scaleErr      error
+	replicas      map[string]int32
+	selector      string
+	annotations   map[string]string
+	getScaleCalls int
+}
+
+func (f *fakeWorkloadsClient) ResourceFor(
+	schema.GroupVersionKind,
+) (schema.GroupVersionResource, error) {
+	return schema.GroupVersionResource{}, nil
+}
+
+func (f *fakeWorkloadsClient) Get(
+	string,
+	k8s.WorkloadReference,
+) (*unstructured.Unstructured, error) {
+	return &unstructured.Unstructured{}, nil
+}
+
+func (f *fakeWorkloadsClient) GetScale(
+	namespace string,
+	workload k8s.WorkloadReference,
+) (*autoscalingv1.Scale, error) {
+	f.getScaleCalls++
+	replicas := f.replicas[getAppKey(workload, namespace)]
+	return &autoscalingv1.Scale{
+		Spec: autoscalingv1.ScaleSpec{
+			Replicas: replicas,
+		},
+		Status: autoscalingv1.ScaleStatus{
+			Replicas: replicas,
+			Selector: f.selector,
+		},
+	}, nil
+}
+
+func (f *fakeWorkloadsClient) Scale(
+	namespace string,
+	workload k8s.WorkloadReference,
+	replicas int32,
+) error {
+	if f.scaleErr != nil {
+		return f.scaleErr
+	}
+	f.replicas[getAppKey(workload, namespace)] = replicas
+	return nil
+}
+
+func (f *fakeWorkloadsClient) Annotate(
+	_ string,
+	_ k8s.WorkloadReference,
+	annotations map[string]string,
+) error {
+	for k, v := range annotations {
+		f.annotations[k] = v
+	}
+	return nil
+}
+
+func (f *fakeWorkloadsClient) RemoveAnnotations(
+	_ string,
+	_ k8s.WorkloadReference,
+	names ...string,
+) error {
+	for _, name := range names {
+		delete(f.annotations, name)
+	}
+	return nil
+}
+
+func TestReportDryRunScaleToZero(t *testing.T) {
+	workloadsClient := &fakeWorkloadsClient{
+		annotations: map[string]string{},
+	}
+	m := &metricsCollector{
+		workloadsClient: workloadsClient,
+		workload:        k8s.DeploymentReference("my-app"),
+		appNamespace:    "my-namespace",
+		dryRun:          true,
+	}
+	m.reportDryRunScaleToZero(
+		time.Date(2019, 1, 7, 18, 0, 0, 0, time.UTC),
+		5*time.Minute,
+	)
+	assert.Equal(
+		t,
+		map[string]string{
+			k8s.DryRunScaleToZeroAnnotationName: `{"timestamp":` +
+				`"2019-01-07T18:00:00Z","idleDuration":"5m0s"}`,
+		},
+		workloadsClient.annotations,
+	)
+}
+
+func TestScaleToZero(t *testing.T) {
+	appKey := getAppKey(k8s.DeploymentReference("my-app"), "my-namespace")
+	testcases := []struct {
+		name             string
+		scaleErr         error
+		expectedReplicas map[string]int32
+		expectedEvent    string
+		expectedReason   string
+	}{
+		{
+			name: "success",
+			expectedReplicas: map[string]int32{
+				appKey: 0,
+			},
+			expectedEvent: "Normal ScaledToZero Scaled to zero after being idle " +
+				"for 5m0s (2 consecutive interval(s)); idle threshold is 2 " +
+				"interval(s)",
+			expectedReason: "Idle for 5m0s (2 consecutive interval(s)); idle " +
+				"threshold is 2 interval(s)",
+		},
+		{
+			name:     "failure",
+			scaleErr: errors.New("boom"),
+			expectedReplicas: map[string]int32{
+				appKey: 3,
+			},
+			expectedEvent: "Warning ScaleToZeroFailed Error scaling to zero after " +
+				"being idle for 5m0s (2 consecutive interval(s)): boom",
+		},
+	}
+
+	for _, test := range testcases {
+		t.Run(test.name, func(t *testing.T) {
+			workloadsClient := &fakeWorkloadsClient{
+				scaleErr: test.scaleErr,
+				replicas: map[string]int32{
+					appKey: 3,
+				},
+				annotations: map[string]string{
+					k8s.IdleStateAnnotationName: `{"idleIntervals":2}`,
+				},
+			}
+			eventRecorder := record.NewFakeRecorder(1)
+			workload := k8s.DeploymentReference("my-app")
+			m := &metricsCollector{
+				workloadsClient: workloadsClient,
+				workload:        workload,
+				appNamespace:    "my-namespace",
+				appObjectRef:    workload.ObjectReference("my-namespace", ""),
+				eventRecorder:   eventRecorder,
+				idleThreshold:   k8s.IdleThreshold{Intervals: 2},
+				idleIntervals:   2,
+			}
+			m.scaleToZero(5 * time.Minute)
+			assert.Equal(t, test.expectedReplicas, workloadsClient.replicas)
+			assert.Equal(t, test.expectedEvent, <-eventRecorder.Events)
+			assert.Equal(
+				t,
+				test.expectedReason,
+				workloadsClient.annotations[k8s.LastScaledToZeroReasonAnnotationName],
+			)
+			_, ok :=
+				workloadsClient.annotations[k8s.LastScaledToZeroAnnotationName]
+			assert.Equal(t, test.expectedReason != "", ok)
+			// The idle state checkpoint is removed only once scaled to zero
+			_, ok = workloadsClient.annotations[k8s.IdleStateAnnotationName]
+			assert.Equal(t, test.expectedReason == "", ok)
+		})
+	}
+}
+
+func TestAllowScaleToZero(t *testing.T) {
+	eventRecorder := record.NewFakeRecorder(1)
+	m := &metricsCollector{
+		eventRecorder: eventRecorder,
+		workload:      k8s.DeploymentReference("my-app"),
+		appNamespace:  "my-namespace",
+	}
+	// Without a limit, apps can always be scaled to zero
+	assert.True(t, m.allowScaleToZero())
+	assert.True(t, m.allowScaleToZero())
+
+	m.scaleToZeroLimiter = rate.NewLimiter(rate.Every(time.Hour), 1)
+	assert.True(t, m.allowScaleToZero())
+	assert.False(t, m.allowScaleToZero())
+	assert.Equal(
+		t,
+		"Normal ScaleToZeroRateLimited Scaling to zero was deferred by the "+
+			"cluster-wide rate limit",
+		<-eventRecorder.Events,
+	)
+}
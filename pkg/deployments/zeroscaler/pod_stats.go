@@ -2,14 +2,12 @@ package zeroscaler
 
 import (
 	"time"
-
-	"github.com/deislabs/osiris/pkg/metrics"
 )
 
 type podStats struct {
 	podDeletedTime *time.Time
 	prevStatTime   *time.Time
-	prevStats      *metrics.ProxyConnectionStats
+	prevStats      activityStats
 	recentStatTime *time.Time
-	recentStats    *metrics.ProxyConnectionStats
+	recentStats    activityStats
 }
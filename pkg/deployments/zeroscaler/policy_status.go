@@ -0,0 +1,153 @@
+package zeroscaler
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"time"
+
+	k8s "github.com/deislabs/osiris/pkg/kubernetes"
+	"github.com/golang/glog"
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/tools/cache"
+)
+
+// policyStatusInterval is how often the leading zeroscaler reports the state
+// of the workloads that scale to zero policies apply to
+const policyStatusInterval = 30 * time.Second
+
+// reportPolicyStatuses periodically updates the status of every scale to zero
+// policy with the state of the workloads it applies to, until the context is
+// canceled.
+func (z *zeroscaler) reportPolicyStatuses(ctx context.Context) {
+	ticker := time.NewTicker(policyStatusInterval)
+	defer ticker.Stop()
+	for {
+		z.updatePolicyStatuses()
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// updatePolicyStatuses updates the status of every scale to zero policy whose
+// reported state of the workloads it applies to is outdated
+func (z *zeroscaler) updatePolicyStatuses() {
+	statuses := z.getPolicyStatuses()
+	for _, obj := range z.defaults.Policies.GetStore().List() {
+		policy, err :=
+			k8s.ScaleToZeroPolicyFromUnstructured(obj.(*unstructured.Unstructured))
+		if err != nil {
+			// The error is logged whenever the policy would otherwise be applied
+			continue
+		}
+		key, err := cache.MetaNamespaceKeyFunc(policy)
+		if err != nil {
+			continue
+		}
+		status := statuses[key]
+		if reflect.DeepEqual(status, policy.Status) {
+			continue
+		}
+		if err = z.defaults.Policies.UpdateStatus(policy, status); err != nil {
+			glog.Errorf(
+				"Error updating status of scale to zero policy %s in namespace %s: "+
+					"%s",
+				policy.Name,
+				policy.Namespace,
+				err,
+			)
+		}
+	}
+}
+
+// getPolicyStatuses returns the status of every scale to zero policy that
+// applies to at least one workload, indexed by the policy's key
+func (z *zeroscaler) getPolicyStatuses() map[string]k8s.ScaleToZeroPolicyStatus { // nolint: lll
+	statuses := map[string]k8s.ScaleToZeroPolicyStatus{}
+	addWorkload := func(
+		app metav1.Object,
+		typeMeta metav1.TypeMeta,
+		replicas int32,
+	) {
+		policy := z.defaults.Policies.Select(app.GetNamespace(), app.GetLabels())
+		if policy == nil {
+			return
+		}
+		key, err := cache.MetaNamespaceKeyFunc(policy)
+		if err != nil {
+			return
+		}
+		annotations := app.GetAnnotations()
+		status := statuses[key]
+		status.Workloads = append(
+			status.Workloads,
+			k8s.ScaleToZeroPolicyWorkloadStatus{
+				APIVersion: typeMeta.APIVersion,
+				Kind:       typeMeta.Kind,
+				Name:       app.GetName(),
+				Enabled: k8s.ResourceIsOsirisEnabled(
+					z.defaults.Apply(app.GetNamespace(), app),
+				),
+				Replicas:         replicas,
+				LastActivity:     annotations[k8s.LastActivityAnnotationName],
+				LastScaledToZero: annotations[k8s.LastScaledToZeroAnnotationName],
+			},
+		)
+		statuses[key] = status
+	}
+	for _, obj := range z.deploymentsInformer.GetStore().List() {
+		deployment := obj.(*appsv1.Deployment)
+		addWorkload(
+			deployment,
+			metav1.TypeMeta{
+				APIVersion: appsv1.SchemeGroupVersion.String(),
+				Kind:       "Deployment",
+			},
+			*deployment.Spec.Replicas,
+		)
+	}
+	for _, obj := range z.statefulSetsInformer.GetStore().List() {
+		statefulSet := obj.(*appsv1.StatefulSet)
+		addWorkload(
+			statefulSet,
+			metav1.TypeMeta{
+				APIVersion: appsv1.SchemeGroupVersion.String(),
+				Kind:       "StatefulSet",
+			},
+			*statefulSet.Spec.Replicas,
+		)
+	}
+	for gvk, informer := range z.workloadsInformers {
+		for _, obj := range informer.GetStore().List() {
+			workload := obj.(*unstructured.Unstructured)
+			// Workloads of arbitrary kinds only conventionally indicate their
+			// desired number of replicas here
+			replicas, _, _ :=
+				unstructured.NestedInt64(workload.Object, "spec", "replicas")
+			addWorkload(
+				workload,
+				metav1.TypeMeta{
+					APIVersion: gvk.GroupVersion().String(),
+					Kind:       gvk.Kind,
+				},
+				int32(replicas),
+			)
+		}
+	}
+	// Informers list objects in no particular order
+	for _, status := range statuses {
+		sort.Slice(status.Workloads, func(i, j int) bool {
+			iWorkload, jWorkload := status.Workloads[i], status.Workloads[j]
+			if iWorkload.Kind != jWorkload.Kind {
+				return iWorkload.Kind < jWorkload.Kind
+			}
+			return iWorkload.Name < jWorkload.Name
+		})
+	}
+	return statuses
+}
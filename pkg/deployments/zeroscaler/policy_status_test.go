@@ -0,0 +1,119 @@
+package zeroscaler
+
+import (
+	"testing"
+
+	k8s "github.com/deislabs/osiris/pkg/kubernetes"
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/tools/cache"
+)
+
+func TestGetPolicyStatuses(t *testing.T) {
+	newDeployment := func(
+		name string,
+		lbls map[string]string,
+		annotations map[string]string,
+		replicas int32,
+	) *appsv1.Deployment {
+		return &appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace:   "default",
+				Name:        name,
+				Labels:      lbls,
+				Annotations: annotations,
+			},
+			Spec: appsv1.DeploymentSpec{
+				Replicas: &replicas,
+			},
+		}
+	}
+	deploymentsInformer := cache.NewSharedIndexInformer(
+		&cache.ListWatch{},
+		&appsv1.Deployment{},
+		0,
+		cache.Indexers{},
+	)
+	for _, deployment := range []*appsv1.Deployment{
+		newDeployment(
+			"b-app",
+			map[string]string{"tier": "dev"},
+			map[string]string{
+				k8s.LastScaledToZeroAnnotationName: "2019-01-07T18:00:00Z",
+			},
+			0,
+		),
+		newDeployment(
+			"a-app",
+			map[string]string{"tier": "dev"},
+			map[string]string{
+				"osiris.deislabs.io/enabled":   "false",
+				k8s.LastActivityAnnotationName: "2019-01-07T17:00:00Z",
+			},
+			2,
+		),
+		newDeployment("unmatched-app", map[string]string{"tier": "prod"}, nil, 1),
+	} {
+		assert.NoError(t, deploymentsInformer.GetStore().Add(deployment))
+	}
+	z := &zeroscaler{
+		deploymentsInformer: deploymentsInformer,
+		statefulSetsInformer: cache.NewSharedIndexInformer(
+			&cache.ListWatch{},
+			&appsv1.StatefulSet{},
+			0,
+			cache.Indexers{},
+		),
+		defaults: newTestResourceDefaults(
+			t,
+			nil,
+			[]*unstructured.Unstructured{
+				{
+					Object: map[string]interface{}{
+						"apiVersion": "osiris.deislabs.io/v1alpha1",
+						"kind":       "ScaleToZeroPolicy",
+						"metadata": map[string]interface{}{
+							"namespace": "default",
+							"name":      "dev",
+						},
+						"spec": map[string]interface{}{
+							"selector": map[string]interface{}{
+								"matchLabels": map[string]interface{}{
+									"tier": "dev",
+								},
+							},
+						},
+					},
+				},
+			},
+		),
+	}
+	assert.Equal(
+		t,
+		map[string]k8s.ScaleToZeroPolicyStatus{
+			"default/dev": {
+				Workloads: []k8s.ScaleToZeroPolicyWorkloadStatus{
+					{
+						APIVersion:   "apps/v1",
+						Kind:         "Deployment",
+						Name:         "a-app",
+						Enabled:      false,
+						Replicas:     2,
+						LastActivity: "2019-01-07T17:00:00Z",
+					},
+					{
+						APIVersion:       "apps/v1",
+						Kind:             "Deployment",
+						Name:             "b-app",
+						Enabled:          true,
+						Replicas:         0,
+						LastScaledToZero: "2019-01-07T18:00:00Z",
+					},
+				},
+			},
+		},
+		z.getPolicyStatuses(),
+	)
+}
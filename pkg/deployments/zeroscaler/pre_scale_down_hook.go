@@ -0,0 +1,204 @@
+package zeroscaler
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	k8s "github.com/deislabs/osiris/pkg/kubernetes"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// Supported values of the k8s.PreScaleDownHookFailurePolicyAnnotationName
+// annotation
+const (
+	// preScaleDownHookFailurePolicyIgnore indicates an app may be scaled to zero
+	// even if its pre-scale-down hook fails or times out
+	preScaleDownHookFailurePolicyIgnore = "Ignore"
+	// preScaleDownHookFailurePolicyFail indicates an app must not be scaled to
+	// zero if its pre-scale-down hook fails or times out
+	preScaleDownHookFailurePolicyFail = "Fail"
+)
+
+// Supported actions in the responses of pre-scale-down hooks
+const (
+	preScaleDownHookActionAllow    = "allow"
+	preScaleDownHookActionVeto     = "veto"
+	preScaleDownHookActionPostpone = "postpone"
+)
+
+const defaultPreScaleDownHookTimeout = 10 * time.Second
+
+// preScaleDownHook is an HTTP endpoint, exposed by an app's pods, that is
+// called on one of those pods just before the app is scaled to zero. The hook
+// may allow the app to be scaled to zero, veto it, or postpone it. The zero
+// value indicates the app has no such hook.
+type preScaleDownHook struct {
+	port     int
+	path     string
+	timeout  time.Duration
+	failOpen bool
+}
+
+// preScaleDownHookRequest is the body of requests to pre-scale-down hooks
+type preScaleDownHookRequest struct {
+	Namespace    string `json:"namespace"`
+	Kind         string `json:"kind"`
+	Name         string `json:"name"`
+	IdleDuration string `json:"idleDuration"`
+}
+
+// preScaleDownHookResponse is the body of responses from pre-scale-down hooks.
+// An empty body is equivalent to allowing the app to be scaled to zero.
+type preScaleDownHookResponse struct {
+	Action      string `json:"action"`
+	PostponeFor string `json:"postponeFor,omitempty"`
+}
+
+// getPreScaleDownHook returns the pre-scale-down hook indicated by an app's
+// annotations. By default, an app has no such hook.
+func getPreScaleDownHook(
+	annotations map[string]string,
+) (preScaleDownHook, error) {
+	hook := preScaleDownHook{}
+	path, ok := annotations[k8s.PreScaleDownHookPathAnnotationName]
+	if !ok {
+		return hook, nil
+	}
+	if !strings.HasPrefix(path, "/") {
+		return hook, fmt.Errorf(
+			"The %s annotation must specify an absolute path",
+			k8s.PreScaleDownHookPathAnnotationName,
+		)
+	}
+	port, err :=
+		strconv.Atoi(annotations[k8s.PreScaleDownHookPortAnnotationName])
+	if err != nil || port <= 0 || port > 65535 {
+		return hook, fmt.Errorf(
+			"The %s annotation must specify a valid port",
+			k8s.PreScaleDownHookPortAnnotationName,
+		)
+	}
+	timeout := defaultPreScaleDownHookTimeout
+	if timeoutStr, ok :=
+		annotations[k8s.PreScaleDownHookTimeoutAnnotationName]; ok {
+		timeout, err = time.ParseDuration(timeoutStr)
+		if err != nil || timeout <= 0 {
+			return hook, fmt.Errorf(
+				"The %s annotation must specify a positive duration",
+				k8s.PreScaleDownHookTimeoutAnnotationName,
+			)
+		}
+	}
+	var failOpen bool
+	failurePolicy :=
+		annotations[k8s.PreScaleDownHookFailurePolicyAnnotationName]
+	switch {
+	case strings.EqualFold(failurePolicy, preScaleDownHookFailurePolicyIgnore):
+		failOpen = true
+	case failurePolicy != "" &&
+		!strings.EqualFold(failurePolicy, preScaleDownHookFailurePolicyFail):
+		return hook, fmt.Errorf(
+			"Unknown pre-scale-down hook failure policy %q",
+			failurePolicy,
+		)
+	}
+	hook.port = port
+	hook.path = path
+	hook.timeout = timeout
+	hook.failOpen = failOpen
+	return hook, nil
+}
+
+// enabled returns true if the app has a pre-scale-down hook.
+func (p preScaleDownHook) enabled() bool {
+	return p.path != ""
+}
+
+// url returns the URL of the hook on the given pod.
+func (p preScaleDownHook) url(pod *corev1.Pod) string {
+	return fmt.Sprintf("http://%s:%d%s", pod.Status.PodIP, p.port, p.path)
+}
+
+// call calls the hook at the given URL and returns the action it responded
+// with and, if that action is to postpone scaling to zero, for how long.
+func (p preScaleDownHook) call(
+	url string,
+	req preScaleDownHookRequest,
+) (string, time.Duration, error) {
+	reqBytes, err := json.Marshal(req)
+	if err != nil {
+		return "", 0, err
+	}
+	httpClient := &http.Client{
+		Timeout: p.timeout,
+	}
+	resp, err := httpClient.Post(
+		url,
+		"application/json",
+		bytes.NewBuffer(reqBytes),
+	)
+	if err != nil {
+		return "", 0, fmt.Errorf(
+			"Error calling pre-scale-down hook at %s: %s",
+			url,
+			err,
+		)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", 0, fmt.Errorf(
+			"Received unexpected HTTP response code %d from pre-scale-down hook "+
+				"at %s",
+			resp.StatusCode,
+			url,
+		)
+	}
+	// Guard against unexpectedly large responses
+	respBytes, err := ioutil.ReadAll(io.LimitReader(resp.Body, 1<<16))
+	if err != nil {
+		return "", 0, fmt.Errorf(
+			"Error reading response from pre-scale-down hook at %s: %s",
+			url,
+			err,
+		)
+	}
+	if len(bytes.TrimSpace(respBytes)) == 0 {
+		return preScaleDownHookActionAllow, 0, nil
+	}
+	hookResp := preScaleDownHookResponse{}
+	if err = json.Unmarshal(respBytes, &hookResp); err != nil {
+		return "", 0, fmt.Errorf(
+			"Error decoding response from pre-scale-down hook at %s: %s",
+			url,
+			err,
+		)
+	}
+	switch action := strings.ToLower(hookResp.Action); action {
+	case preScaleDownHookActionAllow, preScaleDownHookActionVeto:
+		return action, 0, nil
+	case preScaleDownHookActionPostpone:
+		var postponeFor time.Duration
+		postponeFor, err = time.ParseDuration(hookResp.PostponeFor)
+		if err != nil || postponeFor <= 0 {
+			return "", 0, fmt.Errorf(
+				"Pre-scale-down hook at %s postponed scaling to zero without a "+
+					"positive duration",
+				url,
+			)
+		}
+		return action, postponeFor, nil
+	default:
+		return "", 0, fmt.Errorf(
+			"Unknown action %q in response from pre-scale-down hook at %s",
+			hookResp.Action,
+			url,
+		)
+	}
+}
@@ -0,0 +1,178 @@
+package zeroscaler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	k8s "github.com/deislabs/osiris/pkg/kubernetes"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetPreScaleDownHook(t *testing.T) {
+	testcases := []struct {
+		name         string
+		annotations  map[string]string
+		expectedHook preScaleDownHook
+		expectError  bool
+	}{
+		{
+			name:         "no annotations",
+			annotations:  map[string]string{},
+			expectedHook: preScaleDownHook{},
+		},
+		{
+			name: "hook with defaults",
+			annotations: map[string]string{
+				k8s.PreScaleDownHookPathAnnotationName: "/pre-scale-down",
+				k8s.PreScaleDownHookPortAnnotationName: "8080",
+			},
+			expectedHook: preScaleDownHook{
+				port:    8080,
+				path:    "/pre-scale-down",
+				timeout: defaultPreScaleDownHookTimeout,
+			},
+		},
+		{
+			name: "hook with timeout and failure policy",
+			annotations: map[string]string{
+				k8s.PreScaleDownHookPathAnnotationName:          "/pre-scale-down",
+				k8s.PreScaleDownHookPortAnnotationName:          "8080",
+				k8s.PreScaleDownHookTimeoutAnnotationName:       "2s",
+				k8s.PreScaleDownHookFailurePolicyAnnotationName: "ignore",
+			},
+			expectedHook: preScaleDownHook{
+				port:     8080,
+				path:     "/pre-scale-down",
+				timeout:  2 * time.Second,
+				failOpen: true,
+			},
+		},
+		{
+			name: "relative path",
+			annotations: map[string]string{
+				k8s.PreScaleDownHookPathAnnotationName: "pre-scale-down",
+				k8s.PreScaleDownHookPortAnnotationName: "8080",
+			},
+			expectError: true,
+		},
+		{
+			name: "missing port",
+			annotations: map[string]string{
+				k8s.PreScaleDownHookPathAnnotationName: "/pre-scale-down",
+			},
+			expectError: true,
+		},
+		{
+			name: "invalid timeout",
+			annotations: map[string]string{
+				k8s.PreScaleDownHookPathAnnotationName:    "/pre-scale-down",
+				k8s.PreScaleDownHookPortAnnotationName:    "8080",
+				k8s.PreScaleDownHookTimeoutAnnotationName: "0s",
+			},
+			expectError: true,
+		},
+		{
+			name: "unknown failure policy",
+			annotations: map[string]string{
+				k8s.PreScaleDownHookPathAnnotationName:          "/pre-scale-down",
+				k8s.PreScaleDownHookPortAnnotationName:          "8080",
+				k8s.PreScaleDownHookFailurePolicyAnnotationName: "Retry",
+			},
+			expectError: true,
+		},
+	}
+
+	for _, test := range testcases {
+		t.Run(test.name, func(t *testing.T) {
+			hook, err := getPreScaleDownHook(test.annotations)
+			if test.expectError {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, test.expectedHook, hook)
+		})
+	}
+}
+
+func TestPreScaleDownHookCall(t *testing.T) {
+	testcases := []struct {
+		name                string
+		statusCode          int
+		body                string
+		expectedAction      string
+		expectedPostponeFor time.Duration
+		expectError         bool
+	}{
+		{
+			name:           "empty body",
+			statusCode:     http.StatusOK,
+			expectedAction: preScaleDownHookActionAllow,
+		},
+		{
+			name:           "veto",
+			statusCode:     http.StatusOK,
+			body:           `{"action": "veto"}`,
+			expectedAction: preScaleDownHookActionVeto,
+		},
+		{
+			name:                "postpone",
+			statusCode:          http.StatusOK,
+			body:                `{"action": "postpone", "postponeFor": "5m"}`,
+			expectedAction:      preScaleDownHookActionPostpone,
+			expectedPostponeFor: 5 * time.Minute,
+		},
+		{
+			name:        "postpone without duration",
+			statusCode:  http.StatusOK,
+			body:        `{"action": "postpone"}`,
+			expectError: true,
+		},
+		{
+			name:        "unknown action",
+			statusCode:  http.StatusOK,
+			body:        `{"action": "maybe"}`,
+			expectError: true,
+		},
+		{
+			name:        "unexpected status code",
+			statusCode:  http.StatusInternalServerError,
+			expectError: true,
+		},
+	}
+
+	for _, test := range testcases {
+		t.Run(test.name, func(t *testing.T) {
+			var hookReq preScaleDownHookRequest
+			server := httptest.NewServer(
+				http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					assert.NoError(t, json.NewDecoder(r.Body).Decode(&hookReq))
+					w.WriteHeader(test.statusCode)
+					fmt.Fprint(w, test.body)
+				}),
+			)
+			defer server.Close()
+			req := preScaleDownHookRequest{
+				Namespace:    "default",
+				Kind:         "Deployment",
+				Name:         "my-app",
+				IdleDuration: "10m0s",
+			}
+			action, postponeFor, err := preScaleDownHook{
+				timeout: defaultPreScaleDownHookTimeout,
+			}.call(server.URL, req)
+			assert.Equal(t, req, hookReq)
+			if test.expectError {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, test.expectedAction, action)
+			assert.Equal(t, test.expectedPostponeFor, postponeFor)
+		})
+	}
+}
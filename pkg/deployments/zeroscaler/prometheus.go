@@ -0,0 +1,120 @@
+package zeroscaler
+
+import (
+	k8s "github.com/deislabs/osiris/pkg/kubernetes"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	metricsNamespace = "osiris"
+	metricsSubsystem = "zeroscaler"
+)
+
+// Possible outcomes of each scale to zero decision made by a metrics collector
+const (
+	decisionScaledToZero       = "scaled_to_zero"
+	decisionActivity           = "activity"
+	decisionAssumedActivity    = "assumed_activity"
+	decisionTimedOut           = "timed_out"
+	decisionIdleThresholdUnmet = "idle_threshold_not_reached"
+	decisionKeptWarm           = "kept_warm"
+	decisionDryRun             = "dry_run"
+	decisionVetoed             = "vetoed"
+	decisionPostponed          = "postponed"
+	decisionRateLimited        = "rate_limited"
+	// decisionPreScaleDownHookFailed is only a decision if the app's failure
+	// policy prevents it from being scaled to zero when its hook fails
+	decisionPreScaleDownHookFailed = "pre_scale_down_hook_failed"
+)
+
+var (
+	workloadLabels = []string{"namespace", "kind", "name"}
+
+	activeMetricsCollectors = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Subsystem: metricsSubsystem,
+			Name:      "active_metrics_collectors",
+			Help:      "Number of workloads for which metrics are being collected",
+		},
+	)
+	scrapesTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Subsystem: metricsSubsystem,
+			Name:      "scrapes_total",
+			Help:      "Number of scrapes of pod metrics, by result",
+		},
+		append(workloadLabels, "result"),
+	)
+	scrapeDurationSeconds = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Subsystem: metricsSubsystem,
+			Name:      "scrape_duration_seconds",
+			Help:      "Latency of scrapes of pod metrics",
+			Buckets:   []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2},
+		},
+		workloadLabels,
+	)
+	workloadIdle = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Subsystem: metricsSubsystem,
+			Name:      "workload_idle",
+			Help: "Whether the workload was found idle (1) or active (0) in the " +
+				"most recent metrics check interval",
+		},
+		workloadLabels,
+	)
+	consecutiveIdleIntervals = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Subsystem: metricsSubsystem,
+			Name:      "consecutive_idle_intervals",
+			Help: "Number of consecutive metrics check intervals in which the " +
+				"workload was found idle",
+		},
+		workloadLabels,
+	)
+	scaleToZeroDecisionsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Subsystem: metricsSubsystem,
+			Name:      "scale_to_zero_decisions_total",
+			Help:      "Number of scale to zero decisions made, by outcome",
+		},
+		append(workloadLabels, "decision"),
+	)
+	scaleToZeroErrorsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Subsystem: metricsSubsystem,
+			Name:      "scale_to_zero_errors_total",
+			Help:      "Number of failed attempts to scale a workload to zero",
+		},
+		workloadLabels,
+	)
+)
+
+func init() {
+	prometheus.MustRegister(
+		activeMetricsCollectors,
+		scrapesTotal,
+		scrapeDurationSeconds,
+		workloadIdle,
+		consecutiveIdleIntervals,
+		scaleToZeroDecisionsTotal,
+		scaleToZeroErrorsTotal,
+	)
+}
+
+// getWorkloadLabelValues returns the values for workloadLabels that identify
+// the given workload
+func getWorkloadLabelValues(
+	namespace string,
+	workload k8s.WorkloadReference,
+) []string {
+	groupKind := workload.GroupVersionKind().GroupKind()
+	return []string{namespace, groupKind.String(), workload.Name}
+}
@@ -0,0 +1,293 @@
+package zeroscaler
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	k8s "github.com/deislabs/osiris/pkg/kubernetes"
+	"github.com/golang/glog"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+)
+
+const workloadIndexName = "workload"
+
+// namespacePodsInformerGracePeriod is how long a namespace's pods informer is
+// kept running after its last subscription ends, so that an app that
+// oscillates around its minimum number of replicas doesn't cause the
+// namespace's pods to be listed all over again every time
+const namespacePodsInformerGracePeriod = 5 * time.Minute
+
+// sharedPodsInformer informs all of the zeroscaler's metrics collectors about
+// their apps' pods. Instead of each collector watching its own app's pods, one
+// pods informer is run per namespace, for only as long as at least one
+// collector is subscribed to pods in that namespace, plus a grace period. Pods
+// are indexed by the workload that owns them so that each collector can be
+// informed about its own app's pods only.
+type sharedPodsInformer struct {
+	kubeClient kubernetes.Interface
+	namespaces map[string]*namespacePodsInformer
+	lock       sync.Mutex
+}
+
+// namespacePodsInformer informs all subscribed metrics collectors about pods
+// in a single namespace
+type namespacePodsInformer struct {
+	informer      cache.SharedIndexInformer
+	subscriptions map[*podsSubscription]struct{}
+	// stopTimer, if not nil, stops the informer once the grace period that
+	// started when its last subscription ended expires
+	stopTimer *time.Timer
+	stopCh    chan struct{}
+	lock      sync.RWMutex
+}
+
+// podsSubscription is a subscription to the pods of a single app. The app's
+// pods are identified by the key of the workload that owns them. If pods of
+// the app's kind of workload cannot be attributed to it by their owner
+// references, the workload key is empty and the app's pods are identified by
+// its label selector instead.
+type podsSubscription struct {
+	namespace   string
+	workloadKey string
+	selector    labels.Selector
+	handler     cache.ResourceEventHandler
+}
+
+func newSharedPodsInformer(
+	kubeClient kubernetes.Interface,
+) *sharedPodsInformer {
+	return &sharedPodsInformer{
+		kubeClient: kubeClient,
+		namespaces: map[string]*namespacePodsInformer{},
+	}
+}
+
+// subscribe causes the given handler to be notified about all pods belonging
+// to the given workload, including those that already exist.
+func (s *sharedPodsInformer) subscribe(
+	namespace string,
+	workload k8s.WorkloadReference,
+	selector labels.Selector,
+	handler cache.ResourceEventHandler,
+) *podsSubscription {
+	sub := &podsSubscription{
+		namespace:   namespace,
+		workloadKey: getPodsWorkloadKey(workload),
+		selector:    selector,
+		handler:     handler,
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	n, ok := s.namespaces[namespace]
+	if !ok {
+		glog.Infof("Starting pods informer for namespace %s", namespace)
+		n = &namespacePodsInformer{
+			informer: k8s.PodsIndexInformer(
+				s.kubeClient,
+				namespace,
+				nil,
+				nil,
+			),
+			subscriptions: map[*podsSubscription]struct{}{},
+			stopCh:        make(chan struct{}),
+		}
+		if err := n.informer.AddIndexers(cache.Indexers{
+			workloadIndexName: podWorkloadIndexFunc,
+		}); err != nil {
+			// This can only happen if the informer was already started
+			glog.Errorf("Error indexing pods in namespace %s: %s", namespace, err)
+		}
+		n.informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+			AddFunc:    n.syncAddedPod,
+			UpdateFunc: n.syncUpdatedPod,
+			DeleteFunc: n.syncDeletedPod,
+		})
+		s.namespaces[namespace] = n
+		go n.informer.Run(n.stopCh)
+	}
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	if n.stopTimer != nil {
+		n.stopTimer.Stop()
+		n.stopTimer = nil
+	}
+	n.subscriptions[sub] = struct{}{}
+	// Catch the new subscriber up on pods the informer already knows about. If
+	// the informer was only just started, it doesn't know about any yet and the
+	// subscriber will be informed of them in due course.
+	var pods []interface{}
+	if sub.workloadKey != "" {
+		var err error
+		pods, err = n.informer.GetIndexer().ByIndex(
+			workloadIndexName,
+			sub.workloadKey,
+		)
+		if err != nil {
+			glog.Errorf(
+				"Error listing pods of %s in namespace %s: %s",
+				workload,
+				namespace,
+				err,
+			)
+		}
+	} else {
+		pods = n.informer.GetStore().List()
+	}
+	for _, obj := range pods {
+		if sub.matches(obj.(*corev1.Pod)) {
+			handler.OnAdd(obj)
+		}
+	}
+	return sub
+}
+
+// unsubscribe stops the given subscription's handler from being notified
+// about any more pods. If no subscriptions to pods in the same namespace
+// remain, the informer for that namespace is stopped once the grace period
+// expires, unless there are new subscriptions by then.
+func (s *sharedPodsInformer) unsubscribe(sub *podsSubscription) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	n, ok := s.namespaces[sub.namespace]
+	if !ok {
+		return
+	}
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	delete(n.subscriptions, sub)
+	if len(n.subscriptions) == 0 && n.stopTimer == nil {
+		n.stopTimer = time.AfterFunc(namespacePodsInformerGracePeriod, func() {
+			s.stopUnused(sub.namespace, n)
+		})
+	}
+}
+
+// stopUnused stops the given informer for the given namespace, unless it has
+// subscriptions again.
+func (s *sharedPodsInformer) stopUnused(
+	namespace string,
+	n *namespacePodsInformer,
+) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.namespaces[namespace] != n {
+		return
+	}
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	if len(n.subscriptions) > 0 {
+		return
+	}
+	glog.Infof("Stopping pods informer for namespace %s", namespace)
+	close(n.stopCh)
+	delete(s.namespaces, namespace)
+}
+
+func (n *namespacePodsInformer) syncAddedPod(obj interface{}) {
+	n.lock.RLock()
+	defer n.lock.RUnlock()
+	for sub := range n.subscriptions {
+		if sub.matches(obj.(*corev1.Pod)) {
+			sub.handler.OnAdd(obj)
+		}
+	}
+}
+
+func (n *namespacePodsInformer) syncUpdatedPod(oldObj, newObj interface{}) {
+	n.lock.RLock()
+	defer n.lock.RUnlock()
+	for sub := range n.subscriptions {
+		oldMatch := sub.matches(oldObj.(*corev1.Pod))
+		newMatch := sub.matches(newObj.(*corev1.Pod))
+		switch {
+		case oldMatch && newMatch:
+			sub.handler.OnUpdate(oldObj, newObj)
+		case newMatch:
+			sub.handler.OnAdd(newObj)
+		case oldMatch:
+			// The pod no longer belongs to the subscriber's app
+			sub.handler.OnDelete(oldObj)
+		}
+	}
+}
+
+func (n *namespacePodsInformer) syncDeletedPod(obj interface{}) {
+	pod, ok := obj.(*corev1.Pod)
+	if !ok {
+		tombstone, ok := obj.(cache.DeletedFinalStateUnknown)
+		if !ok {
+			return
+		}
+		if pod, ok = tombstone.Obj.(*corev1.Pod); !ok {
+			return
+		}
+	}
+	n.lock.RLock()
+	defer n.lock.RUnlock()
+	for sub := range n.subscriptions {
+		if sub.matches(pod) {
+			sub.handler.OnDelete(pod)
+		}
+	}
+}
+
+// matches returns whether the given pod belongs to the subscriber's app
+func (p *podsSubscription) matches(pod *corev1.Pod) bool {
+	if p.workloadKey != "" {
+		for _, key := range getPodWorkloadKeys(pod) {
+			if key == p.workloadKey {
+				return true
+			}
+		}
+		return false
+	}
+	return p.selector.Matches(labels.Set(pod.Labels))
+}
+
+// getPodsWorkloadKey returns the key by which pods belonging to the given
+// workload are indexed. An empty key is returned for kinds of workloads whose
+// pods cannot be attributed to them by their owner references.
+func getPodsWorkloadKey(workload k8s.WorkloadReference) string {
+	switch workload.GroupVersionKind().GroupKind() {
+	case k8s.DeploymentReference("").GroupVersionKind().GroupKind(),
+		k8s.StatefulSetReference("").GroupVersionKind().GroupKind():
+		return fmt.Sprintf("%s/%s", workload.Kind, workload.Name)
+	default:
+		return ""
+	}
+}
+
+// podWorkloadIndexFunc indexes pods by the workloads that own them
+func podWorkloadIndexFunc(obj interface{}) ([]string, error) {
+	return getPodWorkloadKeys(obj.(*corev1.Pod)), nil
+}
+
+// getPodWorkloadKeys returns keys for the workloads that own the given pod,
+// in a format matching that returned by getPodsWorkloadKey. Pods are owned by
+// stateful sets directly, but by deployments only indirectly, via replica
+// sets, whose names are those of their deployments suffixed with the hash of
+// their pod template.
+func getPodWorkloadKeys(pod *corev1.Pod) []string {
+	owner := metav1.GetControllerOf(pod)
+	if owner == nil {
+		return nil
+	}
+	keys := []string{fmt.Sprintf("%s/%s", owner.Kind, owner.Name)}
+	if owner.Kind == "ReplicaSet" {
+		hash, ok := pod.Labels[appsv1.DefaultDeploymentUniqueLabelKey]
+		if ok && strings.HasSuffix(owner.Name, "-"+hash) {
+			keys = append(
+				keys,
+				fmt.Sprintf("Deployment/%s", strings.TrimSuffix(owner.Name, "-"+hash)),
+			)
+		}
+	}
+	return keys
+}
@@ -0,0 +1,191 @@
+package zeroscaler
+
+import (
+	"testing"
+
+	k8s "github.com/deislabs/osiris/pkg/kubernetes"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+func newOwnedPod(
+	ownerKind string,
+	ownerName string,
+	podLabels map[string]string,
+) *corev1.Pod {
+	controller := true
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Labels: podLabels,
+			OwnerReferences: []metav1.OwnerReference{
+				{
+					Kind:       ownerKind,
+					Name:       ownerName,
+					Controller: &controller,
+				},
+			},
+		},
+	}
+}
+
+func TestGetPodWorkloadKeys(t *testing.T) {
+	testcases := []struct {
+		name         string
+		pod          *corev1.Pod
+		expectedKeys []string
+	}{
+		{
+			name:         "no owner",
+			pod:          &corev1.Pod{},
+			expectedKeys: nil,
+		},
+		{
+			name:         "owned by a stateful set",
+			pod:          newOwnedPod("StatefulSet", "my-app", nil),
+			expectedKeys: []string{"StatefulSet/my-app"},
+		},
+		{
+			name: "owned by a deployment's replica set",
+			pod: newOwnedPod(
+				"ReplicaSet",
+				"my-app-5c689d88bb",
+				map[string]string{"pod-template-hash": "5c689d88bb"},
+			),
+			expectedKeys: []string{
+				"ReplicaSet/my-app-5c689d88bb",
+				"Deployment/my-app",
+			},
+		},
+		{
+			name:         "owned by a standalone replica set",
+			pod:          newOwnedPod("ReplicaSet", "my-app", nil),
+			expectedKeys: []string{"ReplicaSet/my-app"},
+		},
+	}
+	for _, test := range testcases {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expectedKeys, getPodWorkloadKeys(test.pod))
+		})
+	}
+}
+
+func TestPodsSubscriptionMatches(t *testing.T) {
+	deploymentPod := newOwnedPod(
+		"ReplicaSet",
+		"my-app-5c689d88bb",
+		map[string]string{
+			"app":               "my-app",
+			"pod-template-hash": "5c689d88bb",
+		},
+	)
+	rolloutPod := newOwnedPod(
+		"ReplicaSet",
+		"my-rollout-6d7f8c9d5b",
+		map[string]string{"app": "my-rollout"},
+	)
+	testcases := []struct {
+		name           string
+		workload       k8s.WorkloadReference
+		selector       labels.Selector
+		pod            *corev1.Pod
+		expectedResult bool
+	}{
+		{
+			name:           "pod owned by the deployment",
+			workload:       k8s.DeploymentReference("my-app"),
+			selector:       labels.Everything(),
+			pod:            deploymentPod,
+			expectedResult: true,
+		},
+		{
+			name:           "pod owned by another deployment",
+			workload:       k8s.DeploymentReference("my-other-app"),
+			selector:       labels.Everything(),
+			pod:            deploymentPod,
+			expectedResult: false,
+		},
+		{
+			name: "pod selected by another kind of workload",
+			workload: k8s.WorkloadReference{
+				Group:   "argoproj.io",
+				Version: "v1alpha1",
+				Kind:    "Rollout",
+				Name:    "my-rollout",
+			},
+			selector: labels.SelectorFromSet(
+				map[string]string{"app": "my-rollout"},
+			),
+			pod:            rolloutPod,
+			expectedResult: true,
+		},
+		{
+			name: "pod not selected by another kind of workload",
+			workload: k8s.WorkloadReference{
+				Group:   "argoproj.io",
+				Version: "v1alpha1",
+				Kind:    "Rollout",
+				Name:    "my-rollout",
+			},
+			selector: labels.SelectorFromSet(
+				map[string]string{"app": "my-rollout"},
+			),
+			pod:            deploymentPod,
+			expectedResult: false,
+		},
+	}
+	for _, test := range testcases {
+		t.Run(test.name, func(t *testing.T) {
+			sub := &podsSubscription{
+				workloadKey: getPodsWorkloadKey(test.workload),
+				selector:    test.selector,
+			}
+			assert.Equal(t, test.expectedResult, sub.matches(test.pod))
+		})
+	}
+}
+
+func TestSharedPodsInformerStopUnused(t *testing.T) {
+	testcases := []struct {
+		name            string
+		subscriptions   map[*podsSubscription]struct{}
+		expectedStopped bool
+	}{
+		{
+			name:            "no subscriptions",
+			subscriptions:   map[*podsSubscription]struct{}{},
+			expectedStopped: true,
+		},
+		{
+			name: "subscribed to again during the grace period",
+			subscriptions: map[*podsSubscription]struct{}{
+				{namespace: "default"}: {},
+			},
+			expectedStopped: false,
+		},
+	}
+	for _, test := range testcases {
+		t.Run(test.name, func(t *testing.T) {
+			n := &namespacePodsInformer{
+				subscriptions: test.subscriptions,
+				stopCh:        make(chan struct{}),
+			}
+			s := &sharedPodsInformer{
+				namespaces: map[string]*namespacePodsInformer{
+					"default": n,
+				},
+			}
+			s.stopUnused("default", n)
+			var stopped bool
+			select {
+			case <-n.stopCh:
+				stopped = true
+			default:
+			}
+			assert.Equal(t, test.expectedStopped, stopped)
+			_, running := s.namespaces["default"]
+			assert.Equal(t, !test.expectedStopped, running)
+		})
+	}
+}
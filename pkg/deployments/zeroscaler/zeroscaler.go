@@ -11,11 +11,17 @@ import (
 	"github.com/deislabs/osiris/pkg/healthz"
 	k8s "github.com/deislabs/osiris/pkg/kubernetes"
 	"github.com/golang/glog"
+	"golang.org/x/time/rate"
 	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
 )
 
 type Zeroscaler interface {
@@ -23,25 +29,77 @@ type Zeroscaler interface {
 }
 
 type zeroscaler struct {
-	cfg                 Config
-	kubeClient          kubernetes.Interface
-	deploymentsInformer cache.SharedInformer
-	collectors          map[string]*metricsCollector
-	collectorsLock      sync.Mutex
-	ctx                 context.Context
+	cfg                  Config
+	kubeClient           kubernetes.Interface
+	workloadsClient      k8s.WorkloadsClient
+	eventRecorder        record.EventRecorder
+	podsInformer         *sharedPodsInformer
+	deploymentsInformer  cache.SharedInformer
+	statefulSetsInformer cache.SharedInformer
+	hpasInformer         cache.SharedIndexInformer
+	workloadsInformers   map[schema.GroupVersionKind]cache.SharedInformer
+	workloadScales       map[string]workloadScale
+	workloadScalesLock   sync.Mutex
+	defaults             *k8s.ResourceDefaults
+	idleThreshold        k8s.IdleThreshold
+	scaleToZeroLimiter   *rate.Limiter
+	collectors           map[string]*metricsCollector
+	collectorsLock       sync.Mutex
+	// ctx is only non-nil while this replica of the zeroscaler is the leader
+	ctx context.Context
 }
 
-func NewZeroscaler(cfg Config, kubeClient kubernetes.Interface) Zeroscaler {
+func NewZeroscaler(
+	cfg Config,
+	kubeClient kubernetes.Interface,
+	dynamicClient dynamic.Interface,
+	workloadsClient k8s.WorkloadsClient,
+) (Zeroscaler, error) {
 	z := &zeroscaler{
-		cfg:        cfg,
-		kubeClient: kubeClient,
+		cfg:             cfg,
+		kubeClient:      kubeClient,
+		workloadsClient: workloadsClient,
+		eventRecorder:   k8s.NewEventRecorder(kubeClient, "osiris-zeroscaler"),
+		podsInformer:    newSharedPodsInformer(kubeClient),
 		deploymentsInformer: k8s.DeploymentsIndexInformer(
 			kubeClient,
 			metav1.NamespaceAll,
 			nil,
 			nil,
 		),
-		collectors: map[string]*metricsCollector{},
+		statefulSetsInformer: k8s.StatefulSetsIndexInformer(
+			kubeClient,
+			metav1.NamespaceAll,
+			nil,
+			nil,
+		),
+		hpasInformer: k8s.HorizontalPodAutoscalersIndexInformer(
+			kubeClient,
+			metav1.NamespaceAll,
+			nil,
+			nil,
+		),
+		workloadsInformers: map[schema.GroupVersionKind]cache.SharedInformer{},
+		workloadScales:     map[string]workloadScale{},
+		defaults:           k8s.NewResourceDefaults(kubeClient, dynamicClient),
+		idleThreshold:      k8s.DefaultIdleThreshold,
+		collectors:         map[string]*metricsCollector{},
+	}
+	if cfg.MaxScaleToZeroPerMinute > 0 {
+		// The limiter is shared by all metrics collectors. It permits a whole
+		// minute's worth of apps to be scaled to zero at once and is replenished
+		// at a steady rate from then on.
+		z.scaleToZeroLimiter = rate.NewLimiter(
+			rate.Every(time.Minute/time.Duration(cfg.MaxScaleToZeroPerMinute)),
+			cfg.MaxScaleToZeroPerMinute,
+		)
+	}
+	if cfg.IdleThreshold != "" {
+		var err error
+		z.idleThreshold, err = k8s.ParseIdleThreshold(cfg.IdleThreshold)
+		if err != nil {
+			return nil, err
+		}
 	}
 	z.deploymentsInformer.AddEventHandler(cache.ResourceEventHandlerFuncs{
 		AddFunc: z.syncDeployment,
@@ -50,147 +108,656 @@ func NewZeroscaler(cfg Config, kubeClient kubernetes.Interface) Zeroscaler {
 		},
 		DeleteFunc: z.syncDeletedDeployment,
 	})
-	return z
+	z.statefulSetsInformer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: z.syncStatefulSet,
+		UpdateFunc: func(_, newObj interface{}) {
+			z.syncStatefulSet(newObj)
+		},
+		DeleteFunc: z.syncDeletedStatefulSet,
+	})
+	z.hpasInformer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: z.syncHPA,
+		UpdateFunc: func(_, newObj interface{}) {
+			z.syncHPA(newObj)
+		},
+		DeleteFunc: func(obj interface{}) {
+			z.syncHPA(getDeletedObject(obj))
+		},
+	})
+	z.defaults.AddNamespaceEventHandler(z.syncNamespace)
+	// Additional kinds of workloads are watched generically
+	for _, kind := range cfg.WorkloadKinds {
+		gvk, err := k8s.ParseGroupVersionKind(kind)
+		if err != nil {
+			return nil, err
+		}
+		gvr, err := workloadsClient.ResourceFor(gvk)
+		if err != nil {
+			return nil, fmt.Errorf(
+				"Error finding resource for workload kind %s: %s",
+				kind,
+				err,
+			)
+		}
+		informer := k8s.WorkloadsIndexInformer(
+			dynamicClient,
+			gvr,
+			metav1.NamespaceAll,
+			nil,
+			nil,
+		)
+		informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+			AddFunc: func(obj interface{}) {
+				z.syncWorkload(gvk, obj)
+			},
+			UpdateFunc: func(_, newObj interface{}) {
+				z.syncWorkload(gvk, newObj)
+			},
+			DeleteFunc: func(obj interface{}) {
+				z.syncDeletedWorkload(gvk, obj)
+			},
+		})
+		z.workloadsInformers[gvk] = informer
+	}
+	return z, nil
 }
 
-// Run causes the controller to collect metrics for Osiris-enabled deployments.
+// Run causes the controller to collect metrics for Osiris-enabled workloads.
+// Informers run regardless of whether this replica of the zeroscaler is the
+// leader, so that a replica that acquires leadership can take over promptly.
 func (z *zeroscaler) Run(ctx context.Context) {
 	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
-	z.ctx = ctx
 	go func() {
 		<-ctx.Done()
 		glog.Infof("Zeroscaler is shutting down")
 	}()
 	glog.Infof("Zeroscaler is started")
+	for _, informer := range z.getInformers() {
+		go func(informer cache.SharedInformer) {
+			informer.Run(ctx.Done())
+			cancel()
+		}(informer)
+	}
 	go func() {
-		z.deploymentsInformer.Run(ctx.Done())
+		if err := k8s.RunLeaderElection(
+			ctx,
+			z.kubeClient,
+			z.cfg.LeaderElectionConfig,
+			z.lead,
+		); err != nil {
+			glog.Errorf("Zeroscaler leader election error: %s", err)
+		}
 		cancel()
 	}()
 	healthz.RunServer(ctx, 5000)
 	cancel()
 }
 
+// lead is invoked when this replica of the zeroscaler becomes the leader.
+// Metrics are collected for all Osiris-enabled workloads until the context
+// is canceled, which signals the loss of leadership.
+func (z *zeroscaler) lead(ctx context.Context) {
+	hasSynced := []cache.InformerSynced{}
+	for _, informer := range z.getInformers() {
+		hasSynced = append(hasSynced, informer.HasSynced)
+	}
+	if !cache.WaitForCacheSync(ctx.Done(), hasSynced...) {
+		return
+	}
+	glog.Infof("Zeroscaler is leading")
+	z.startLeading(ctx)
+	// Catch up on all workloads we've been informed about while not leading
+	for _, obj := range z.deploymentsInformer.GetStore().List() {
+		z.syncDeployment(obj)
+	}
+	for _, obj := range z.statefulSetsInformer.GetStore().List() {
+		z.syncStatefulSet(obj)
+	}
+	for gvk, informer := range z.workloadsInformers {
+		for _, obj := range informer.GetStore().List() {
+			z.syncWorkload(gvk, obj)
+		}
+	}
+	if z.defaults.Policies != nil {
+		go z.reportPolicyStatuses(ctx)
+	}
+	<-ctx.Done()
+	glog.Infof("Zeroscaler is no longer leading")
+	z.stopLeading(ctx)
+}
+
+// startLeading begins a term of leadership, for which metrics collectors are
+// started with the given context. Collectors left over from a previous term
+// were started with that term's context, which is canceled already, so they
+// are stopping and are forgotten, lest they be mistaken for current ones.
+func (z *zeroscaler) startLeading(ctx context.Context) {
+	z.collectorsLock.Lock()
+	defer z.collectorsLock.Unlock()
+	z.ctx = ctx
+	z.collectors = map[string]*metricsCollector{}
+}
+
+// stopLeading ends the term of leadership that was started with the given
+// context. Leadership may be lost and acquired again in quick succession, so
+// the next term may have started already, in which case there is nothing to
+// do.
+func (z *zeroscaler) stopLeading(ctx context.Context) {
+	z.collectorsLock.Lock()
+	defer z.collectorsLock.Unlock()
+	if z.ctx != ctx {
+		return
+	}
+	z.ctx = nil
+	// All collectors were started with the now canceled context, so they are
+	// already stopping
+	z.collectors = map[string]*metricsCollector{}
+}
+
+func (z *zeroscaler) getInformers() []cache.SharedInformer {
+	informers := []cache.SharedInformer{
+		z.deploymentsInformer,
+		z.statefulSetsInformer,
+		z.hpasInformer,
+	}
+	for _, informer := range z.defaults.Informers() {
+		informers = append(informers, informer)
+	}
+	for _, informer := range z.workloadsInformers {
+		informers = append(informers, informer)
+	}
+	return informers
+}
+
 func (z *zeroscaler) syncDeployment(obj interface{}) {
 	deployment := obj.(*appsv1.Deployment)
-	if k8s.ResourceIsOsirisEnabled(deployment.Annotations) {
+	z.syncApp(
+		k8s.DeploymentReference(deployment.Name),
+		deployment,
+		*deployment.Spec.Replicas,
+		deployment.Status.AvailableReplicas,
+		labels.SelectorFromSet(deployment.Spec.Selector.MatchLabels),
+	)
+}
+
+func (z *zeroscaler) syncDeletedDeployment(obj interface{}) {
+	deployment, ok := getDeletedObject(obj).(*appsv1.Deployment)
+	if !ok {
+		return
+	}
+	z.syncDeletedApp(k8s.DeploymentReference(deployment.Name), deployment)
+}
+
+func (z *zeroscaler) syncStatefulSet(obj interface{}) {
+	statefulSet := obj.(*appsv1.StatefulSet)
+	z.syncApp(
+		k8s.StatefulSetReference(statefulSet.Name),
+		statefulSet,
+		*statefulSet.Spec.Replicas,
+		statefulSet.Status.ReadyReplicas,
+		labels.SelectorFromSet(statefulSet.Spec.Selector.MatchLabels),
+	)
+}
+
+func (z *zeroscaler) syncDeletedStatefulSet(obj interface{}) {
+	statefulSet, ok := getDeletedObject(obj).(*appsv1.StatefulSet)
+	if !ok {
+		return
+	}
+	z.syncDeletedApp(k8s.StatefulSetReference(statefulSet.Name), statefulSet)
+}
+
+// syncWorkload is notified of all new and updated workloads of the
+// additionally configured kinds. Such workloads do not share a common schema,
+// so replica counts and pod selectors are obtained from each workload's scale
+// subresource instead. The scale subresource doesn't report how many replicas
+// are ready, so the number of replicas that exist, ready or not, stands in for
+// it.
+func (z *zeroscaler) syncWorkload(
+	gvk schema.GroupVersionKind,
+	obj interface{},
+) {
+	workload := obj.(*unstructured.Unstructured)
+	ref := getWorkloadReference(gvk, workload)
+	var replicas, readyReplicas int32
+	selector := labels.Nothing()
+	if k8s.ResourceIsOsirisEnabled(
+		z.defaults.Apply(workload.GetNamespace(), workload),
+	) {
+		scale, ok := z.getWorkloadScale(ref, workload)
+		if !ok {
+			return
+		}
+		replicas = scale.replicas
+		readyReplicas = scale.readyReplicas
+		selector = scale.selector
+	}
+	z.syncApp(ref, workload, replicas, readyReplicas, selector)
+}
+
+// workloadScale is what was learned from the scale subresource of a workload of
+// one of the additionally configured kinds, along with the generation and the
+// status of the workload at the time.
+type workloadScale struct {
+	generation    int64
+	status        interface{}
+	replicas      int32
+	readyReplicas int32
+	selector      labels.Selector
+}
+
+// getWorkloadScale returns what the workload's scale subresource says about
+// it. The scale subresource reflects only the workload's spec, which bumps its
+// generation when changed, and its status, so as long as neither has changed,
+// what was learned the last time is returned without getting it again. This
+// spares the API server on resyncs and on updates to metadata only-- e.g. the
+// annotations Osiris itself adds. If the scale subresource can't be used, false
+// is returned.
+func (z *zeroscaler) getWorkloadScale(
+	ref k8s.WorkloadReference,
+	workload *unstructured.Unstructured,
+) (workloadScale, bool) {
+	key := getAppKey(ref, workload.GetNamespace())
+	status := workload.Object["status"]
+	z.workloadScalesLock.Lock()
+	cached, ok := z.workloadScales[key]
+	z.workloadScalesLock.Unlock()
+	if ok && cached.generation == workload.GetGeneration() &&
+		reflect.DeepEqual(cached.status, status) {
+		return cached, true
+	}
+	scale, err := z.workloadsClient.GetScale(workload.GetNamespace(), ref)
+	if err != nil {
+		glog.Errorf(
+			"Error getting scale of %s in namespace %s: %s",
+			ref,
+			workload.GetNamespace(),
+			err,
+		)
+		return workloadScale{}, false
+	}
+	if scale.Status.Selector == "" {
+		glog.Errorf(
+			"The scale subresource of %s in namespace %s does not indicate a "+
+				"label selector for its pods; cannot collect metrics",
+			ref,
+			workload.GetNamespace(),
+		)
+		return workloadScale{}, false
+	}
+	selector, err := labels.Parse(scale.Status.Selector)
+	if err != nil {
+		glog.Errorf(
+			"Error parsing pod selector of %s in namespace %s: %s",
+			ref,
+			workload.GetNamespace(),
+			err,
+		)
+		return workloadScale{}, false
+	}
+	ws := workloadScale{
+		generation:    workload.GetGeneration(),
+		status:        status,
+		replicas:      scale.Spec.Replicas,
+		readyReplicas: scale.Status.Replicas,
+		selector:      selector,
+	}
+	z.workloadScalesLock.Lock()
+	z.workloadScales[key] = ws
+	z.workloadScalesLock.Unlock()
+	return ws, true
+}
+
+func (z *zeroscaler) syncDeletedWorkload(
+	gvk schema.GroupVersionKind,
+	obj interface{},
+) {
+	workload, ok := getDeletedObject(obj).(*unstructured.Unstructured)
+	if !ok {
+		return
+	}
+	ref := getWorkloadReference(gvk, workload)
+	z.workloadScalesLock.Lock()
+	delete(z.workloadScales, getAppKey(ref, workload.GetNamespace()))
+	z.workloadScalesLock.Unlock()
+	z.syncDeletedApp(ref, workload)
+}
+
+// syncHPA is notified of all new, updated, and deleted horizontal pod
+// autoscalers. An autoscaler bears on the minimum number of replicas its
+// target workload runs, so that workload is synced again.
+func (z *zeroscaler) syncHPA(obj interface{}) {
+	hpa, ok := obj.(*autoscalingv1.HorizontalPodAutoscaler)
+	if !ok {
+		return
+	}
+	target := hpa.Spec.ScaleTargetRef
+	gv, err := schema.ParseGroupVersion(target.APIVersion)
+	if err != nil {
+		glog.Errorf(
+			"Error parsing API version of the target of horizontal pod "+
+				"autoscaler %s in namespace %s: %s",
+			hpa.Name,
+			hpa.Namespace,
+			err,
+		)
+		return
+	}
+	targetGroupKind := gv.WithKind(target.Kind).GroupKind()
+	key := fmt.Sprintf("%s/%s", hpa.Namespace, target.Name)
+	switch targetGroupKind {
+	case k8s.DeploymentReference("").GroupVersionKind().GroupKind():
+		if obj, ok, _ := z.deploymentsInformer.GetStore().GetByKey(key); ok {
+			z.syncDeployment(obj)
+		}
+	case k8s.StatefulSetReference("").GroupVersionKind().GroupKind():
+		if obj, ok, _ := z.statefulSetsInformer.GetStore().GetByKey(key); ok {
+			z.syncStatefulSet(obj)
+		}
+	default:
+		for gvk, informer := range z.workloadsInformers {
+			if gvk.GroupKind() != targetGroupKind {
+				continue
+			}
+			if obj, ok, _ := informer.GetStore().GetByKey(key); ok {
+				z.syncWorkload(gvk, obj)
+			}
+		}
+	}
+}
+
+// syncNamespace is notified of every namespace in which the defaults that
+// workloads inherit from their namespace or scale to zero policy may have
+// changed. Whether the workloads in such a namespace are Osiris-enabled, and
+// how, may depend on those defaults, so they are synced again.
+func (z *zeroscaler) syncNamespace(namespace string) {
+	inNamespace := func(obj interface{}) bool {
+		return obj.(metav1.Object).GetNamespace() == namespace
+	}
+	for _, obj := range z.deploymentsInformer.GetStore().List() {
+		if inNamespace(obj) {
+			z.syncDeployment(obj)
+		}
+	}
+	for _, obj := range z.statefulSetsInformer.GetStore().List() {
+		if inNamespace(obj) {
+			z.syncStatefulSet(obj)
+		}
+	}
+	for gvk, informer := range z.workloadsInformers {
+		for _, obj := range informer.GetStore().List() {
+			if inNamespace(obj) {
+				z.syncWorkload(gvk, obj)
+			}
+		}
+	}
+}
+
+// getWorkloadHPA returns the horizontal pod autoscaler that targets the given
+// workload, or nil if there is none.
+func (z *zeroscaler) getWorkloadHPA(
+	workload k8s.WorkloadReference,
+	namespace string,
+) *autoscalingv1.HorizontalPodAutoscaler {
+	objs, err := z.hpasInformer.GetIndexer().ByIndex(
+		cache.NamespaceIndex,
+		namespace,
+	)
+	if err != nil {
+		glog.Errorf(
+			"Error listing horizontal pod autoscalers in namespace %s: %s",
+			namespace,
+			err,
+		)
+		return nil
+	}
+	hpas := make([]*autoscalingv1.HorizontalPodAutoscaler, len(objs))
+	for i, obj := range objs {
+		hpas[i] = obj.(*autoscalingv1.HorizontalPodAutoscaler)
+	}
+	return k8s.GetWorkloadHPA(hpas, workload)
+}
+
+// syncApp is notified of all new and updated workloads. readyReplicas is the
+// number of replicas that are ready to serve requests-- e.g. available
+// replicas for a deployment or ready replicas for a stateful set. For workloads
+// of the additionally configured kinds, it is only the number of replicas that
+// exist; see syncWorkload.
+func (z *zeroscaler) syncApp(
+	workload k8s.WorkloadReference,
+	app metav1.Object,
+	replicas int32,
+	readyReplicas int32,
+	selector labels.Selector,
+) {
+	annotations :=
+		z.defaults.Apply(app.GetNamespace(), app)
+	if k8s.ResourceIsOsirisEnabled(annotations) {
 		glog.Infof(
-			"Notified about new or updated Osiris-enabled deployment %s in "+
-				"namespace %s",
-			deployment.Name,
-			deployment.Namespace,
-		)
-		minReplicas := k8s.GetMinReplicas(deployment.Annotations, 1)
-		if *deployment.Spec.Replicas > 0 &&
-			deployment.Status.AvailableReplicas <= minReplicas {
+			"Notified about new or updated Osiris-enabled %s in namespace %s",
+			workload,
+			app.GetNamespace(),
+		)
+		minReplicas := k8s.GetMinReplicas(annotations, 1)
+		// A horizontal pod autoscaler won't scale the app below its own minimum,
+		// so the app may idle at that many replicas instead
+		hpa := z.getWorkloadHPA(workload, app.GetNamespace())
+		if hpaMinReplicas :=
+			k8s.GetHPAMinReplicas(hpa, minReplicas); hpaMinReplicas > minReplicas {
+			minReplicas = hpaMinReplicas
+		}
+		if replicas > minReplicas {
+			z.rememberReplicas(workload, app, replicas)
+		}
+		if replicas > 0 && readyReplicas <= minReplicas {
 			glog.Infof(
-				"Osiris-enabled deployment %s in namespace %s is running the minimun "+
-					"number of replicas or fewer; ensuring metrics collection",
-				deployment.Name,
-				deployment.Namespace,
+				"Osiris-enabled %s in namespace %s is running the minimun number "+
+					"of replicas or fewer; ensuring metrics collection",
+				workload,
+				app.GetNamespace(),
 			)
-			z.ensureMetricsCollection(deployment)
+			z.ensureMetricsCollection(workload, app, selector)
 		} else {
 			glog.Infof(
-				"Osiris-enabled deployment %s in namespace %s is running zero "+
-					"replicas OR more than the minimum number of replicas; ensuring "+
-					"NO metrics collection",
-				deployment.Name,
-				deployment.Namespace,
+				"Osiris-enabled %s in namespace %s is running zero replicas OR "+
+					"more than the minimum number of replicas; ensuring NO metrics "+
+					"collection",
+				workload,
+				app.GetNamespace(),
 			)
-			z.ensureNoMetricsCollection(deployment)
+			z.ensureNoMetricsCollection(workload, app)
 		}
 	} else {
 		glog.Infof(
-			"Notified about new or updated non-Osiris-enabled deployment %s in "+
-				"namespace %s; ensuring NO metrics collection",
-			deployment.Name,
-			deployment.Namespace,
+			"Notified about new or updated non-Osiris-enabled %s in namespace %s; "+
+				"ensuring NO metrics collection",
+			workload,
+			app.GetNamespace(),
 		)
-		z.ensureNoMetricsCollection(deployment)
+		z.ensureNoMetricsCollection(workload, app)
 	}
 }
 
-func (z *zeroscaler) syncDeletedDeployment(obj interface{}) {
-	deployment := obj.(*appsv1.Deployment)
+// rememberReplicas records the number of replicas an app is running above its
+// minimum in an annotation on its workload so that, if the workload opts into
+// it, the same number of replicas can be restored when the app is activated.
+// By the time an app is scaled to zero, it has already idled down to its
+// minimum, so this must be recorded while the app is still busy. Only the
+// leader records it, and only when it has changed.
+func (z *zeroscaler) rememberReplicas(
+	workload k8s.WorkloadReference,
+	app metav1.Object,
+	replicas int32,
+) {
+	z.collectorsLock.Lock()
+	isLeader := z.ctx != nil
+	z.collectorsLock.Unlock()
+	if !isLeader {
+		return
+	}
+	previousReplicas := strconv.Itoa(int(replicas))
+	if app.GetAnnotations()[k8s.PreviousReplicasAnnotationName] ==
+		previousReplicas {
+		return
+	}
+	if err := z.workloadsClient.Annotate(
+		app.GetNamespace(),
+		workload,
+		map[string]string{
+			k8s.PreviousReplicasAnnotationName: previousReplicas,
+		},
+	); err != nil {
+		glog.Errorf(
+			"Error annotating %s in namespace %s: %s",
+			workload,
+			app.GetNamespace(),
+			err,
+		)
+	}
+}
+
+func (z *zeroscaler) syncDeletedApp(
+	workload k8s.WorkloadReference,
+	app metav1.Object,
+) {
 	glog.Infof(
-		"Notified about deleted deployment %s in namespace %s; ensuring NO "+
-			"metrics collection",
-		deployment.Name,
-		deployment.Namespace,
+		"Notified about deleted %s in namespace %s; ensuring NO metrics "+
+			"collection",
+		workload,
+		app.GetNamespace(),
 	)
-	z.ensureNoMetricsCollection(deployment)
+	z.ensureNoMetricsCollection(workload, app)
 }
 
-func (z *zeroscaler) ensureMetricsCollection(deployment *appsv1.Deployment) {
+func (z *zeroscaler) ensureMetricsCollection(
+	workload k8s.WorkloadReference,
+	app metav1.Object,
+	selector labels.Selector,
+) {
 	z.collectorsLock.Lock()
 	defer z.collectorsLock.Unlock()
-	key := getDeploymentKey(deployment)
-	selector := labels.SelectorFromSet(deployment.Spec.Selector.MatchLabels)
-	metricsCheckInterval := z.getMetricsCheckInterval(deployment)
-	if collector, ok := z.collectors[key]; !ok ||
-		shouldUpdateCollector(collector, selector, metricsCheckInterval) {
+	if z.ctx == nil {
+		// This replica of the zeroscaler is not the leader
+		return
+	}
+	ctx := z.ctx
+	key := getAppKey(workload, app.GetNamespace())
+	metricsCheckInterval := z.getMetricsCheckInterval(app)
+	idleThreshold := z.getIdleThreshold(app)
+	annotations := z.defaults.Apply(app.GetNamespace(), app)
+	keepWarm := getKeepWarmPolicy(app.GetName(), annotations)
+	dryRun := k8s.GetDryRun(annotations, z.cfg.DryRun)
+	activitySource, err := getActivitySource(annotations)
+	if err != nil {
+		glog.Warningf(
+			"There was an error getting custom activity source in %s, falling "+
+				"back to the default %s activity source; error: %s",
+			app.GetName(),
+			activitySourceProxy,
+			err,
+		)
+		activitySource = proxyActivitySource{}
+	}
+	// On error, the returned hook is the zero value, meaning there is none
+	preScaleDownHook, err := getPreScaleDownHook(annotations)
+	if err != nil {
+		glog.Warningf(
+			"There was an error getting the pre-scale-down hook of %s, ignoring "+
+				"the hook; error: %s",
+			app.GetName(),
+			err,
+		)
+	}
+	if collector, ok := z.collectors[key]; !ok || shouldUpdateCollector(
+		collector,
+		selector,
+		metricsCheckInterval,
+		idleThreshold,
+		keepWarm,
+		dryRun,
+		activitySource,
+		preScaleDownHook,
+	) {
 		if ok {
 			collector.stop()
 		}
 		glog.Infof(
-			"Using new metrics collector for deployment %s in namespace %s "+
-				"with metrics check interval of %s",
-			deployment.Name,
-			deployment.Namespace,
+			"Using new metrics collector for %s in namespace %s with metrics "+
+				"check interval of %s and idle threshold of %s",
+			workload,
+			app.GetNamespace(),
 			metricsCheckInterval.String(),
+			idleThreshold,
 		)
 		collector := newMetricsCollector(
-			z.kubeClient,
-			deployment.Name,
-			deployment.Namespace,
+			z.podsInformer,
+			z.workloadsClient,
+			z.scaleToZeroLimiter,
+			workload,
+			app.GetNamespace(),
+			workload.ObjectReference(app.GetNamespace(), app.GetUID()),
+			z.eventRecorder,
 			selector,
 			metricsCheckInterval,
+			idleThreshold,
+			keepWarm,
+			dryRun,
+			activitySource,
+			preScaleDownHook,
 		)
 		go func() {
-			collector.run(z.ctx)
+			collector.run(ctx)
 			// Once the collector has run to completion (scaled to zero) remove it
-			// from the map
+			// from the map, unless it has already been replaced
 			z.collectorsLock.Lock()
 			defer z.collectorsLock.Unlock()
-			delete(z.collectors, key)
+			if z.collectors[key] == collector {
+				delete(z.collectors, key)
+			}
 		}()
 		z.collectors[key] = collector
 		return
 	}
 	glog.Infof(
-		"Using existing metrics collector for deployment %s in namespace %s",
-		deployment.Name,
-		deployment.Namespace,
+		"Using existing metrics collector for %s in namespace %s",
+		workload,
+		app.GetNamespace(),
 	)
 }
 
-func (z *zeroscaler) ensureNoMetricsCollection(deployment *appsv1.Deployment) {
+func (z *zeroscaler) ensureNoMetricsCollection(
+	workload k8s.WorkloadReference,
+	app metav1.Object,
+) {
 	z.collectorsLock.Lock()
 	defer z.collectorsLock.Unlock()
-	key := getDeploymentKey(deployment)
+	key := getAppKey(workload, app.GetNamespace())
 	if collector, ok := z.collectors[key]; ok {
 		collector.stop()
 		delete(z.collectors, key)
 	}
 }
 
-func (z *zeroscaler) getMetricsCheckInterval(
-	deployment *appsv1.Deployment,
-) time.Duration {
+// getMetricsCheckInterval returns the metrics check interval indicated by an
+// app's annotations or, failing that, its scale to zero policy or its
+// namespace, falling back to the globally configured default if none is
+// indicated or the indicated value is invalid.
+func (z *zeroscaler) getMetricsCheckInterval(app metav1.Object) time.Duration {
 	var (
 		metricsCheckInterval int
 		err                  error
 	)
+	annotations :=
+		z.defaults.Apply(app.GetNamespace(), app)
 	if rawMetricsCheckInterval, ok :=
-		deployment.Annotations[k8s.MetricsCheckIntervalAnnotationName]; ok {
+		annotations[k8s.MetricsCheckIntervalAnnotationName]; ok {
 		metricsCheckInterval, err = strconv.Atoi(rawMetricsCheckInterval)
 		if err != nil {
 			glog.Warningf(
 				"There was an error getting custom metrics check interval value "+
-					"in deployment %s, falling back to the default value of %d "+
-					"seconds; error: %s",
-				deployment.Name,
+					"in %s, falling back to the default value of %d seconds; "+
+					"error: %s",
+				app.GetName(),
 				z.cfg.MetricsCheckInterval,
 				err,
 			)
@@ -199,10 +766,10 @@ func (z *zeroscaler) getMetricsCheckInterval(
 	}
 	if metricsCheckInterval <= 0 {
 		glog.Warningf(
-			"Invalid custom metrics check interval value %d in deployment %s,"+
-				" falling back to the default value of %d seconds",
+			"Invalid custom metrics check interval value %d in %s, falling back "+
+				"to the default value of %d seconds",
 			metricsCheckInterval,
-			deployment.Name,
+			app.GetName(),
 			z.cfg.MetricsCheckInterval,
 		)
 		metricsCheckInterval = z.cfg.MetricsCheckInterval
@@ -210,10 +777,39 @@ func (z *zeroscaler) getMetricsCheckInterval(
 	return time.Duration(metricsCheckInterval) * time.Second
 }
 
+// getIdleThreshold returns the idle threshold indicated by an app's
+// annotations or, failing that, its scale to zero policy, falling back to the
+// globally configured default if none is indicated or the indicated value is
+// invalid.
+func (z *zeroscaler) getIdleThreshold(app metav1.Object) k8s.IdleThreshold {
+	annotations := z.defaults.Apply(app.GetNamespace(), app)
+	rawIdleThreshold, ok := annotations[k8s.IdleThresholdAnnotationName]
+	if !ok {
+		return z.idleThreshold
+	}
+	idleThreshold, err := k8s.ParseIdleThreshold(rawIdleThreshold)
+	if err != nil {
+		glog.Warningf(
+			"There was an error getting custom idle threshold value in %s, "+
+				"falling back to the default value of %s; error: %s",
+			app.GetName(),
+			z.idleThreshold,
+			err,
+		)
+		return z.idleThreshold
+	}
+	return idleThreshold
+}
+
 func shouldUpdateCollector(
 	collector *metricsCollector,
 	newSelector labels.Selector,
 	newMetricsCheckInterval time.Duration,
+	newIdleThreshold k8s.IdleThreshold,
+	newKeepWarm keepWarmPolicy,
+	newDryRun bool,
+	newActivitySource activitySource,
+	newPreScaleDownHook preScaleDownHook,
 ) bool {
 	if !reflect.DeepEqual(newSelector, collector.selector) {
 		return true
@@ -221,9 +817,52 @@ func shouldUpdateCollector(
 	if newMetricsCheckInterval != collector.metricsCheckInterval {
 		return true
 	}
+	if newIdleThreshold != collector.idleThreshold {
+		return true
+	}
+	if !newKeepWarm.equals(collector.keepWarm) {
+		return true
+	}
+	if newDryRun != collector.dryRun {
+		return true
+	}
+	if newActivitySource != collector.activitySource {
+		return true
+	}
+	if newPreScaleDownHook != collector.preScaleDownHook {
+		return true
+	}
 	return false
 }
 
-func getDeploymentKey(deployment *appsv1.Deployment) string {
-	return fmt.Sprintf("%s:%s", deployment.Namespace, deployment.Name)
+// getDeletedObject returns the object that a delete notification is about. If
+// the informer missed the deletion, e.g. while its watch was interrupted, the
+// notification carries a tombstone with the object's last known state instead
+// of the object itself.
+func getDeletedObject(obj interface{}) interface{} {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		return tombstone.Obj
+	}
+	return obj
+}
+
+func getWorkloadReference(
+	gvk schema.GroupVersionKind,
+	workload metav1.Object,
+) k8s.WorkloadReference {
+	return k8s.WorkloadReference{
+		Group:   gvk.Group,
+		Version: gvk.Version,
+		Kind:    gvk.Kind,
+		Name:    workload.GetName(),
+	}
+}
+
+func getAppKey(workload k8s.WorkloadReference, namespace string) string {
+	return fmt.Sprintf(
+		"%s:%s:%s",
+		workload.GroupVersionKind().GroupKind(),
+		namespace,
+		workload.Name,
+	)
 }
@@ -1,14 +1,20 @@
 package zeroscaler
 
 import (
+	"context"
 	"testing"
 	"time"
 
 	k8s "github.com/deislabs/osiris/pkg/kubernetes"
 	"github.com/stretchr/testify/assert"
 	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/tools/cache"
 )
 
 func TestShouldUpdateCollector(t *testing.T) {
@@ -17,6 +23,11 @@ func TestShouldUpdateCollector(t *testing.T) {
 		collector               *metricsCollector
 		newSelector             labels.Selector
 		newMetricsCheckInterval time.Duration
+		newIdleThreshold        k8s.IdleThreshold
+		newKeepWarm             keepWarmPolicy
+		newDryRun               bool
+		newActivitySource       activitySource
+		newPreScaleDownHook     preScaleDownHook
 		expectedResult          bool
 	}{
 		{
@@ -49,6 +60,63 @@ func TestShouldUpdateCollector(t *testing.T) {
 			newMetricsCheckInterval: 5 * time.Second,
 			expectedResult:          true,
 		},
+		{
+			name: "same selector and metricsCheckInterval but different " +
+				"idleThreshold",
+			collector: &metricsCollector{
+				selector:             labels.Everything(),
+				metricsCheckInterval: 5 * time.Second,
+				idleThreshold:        k8s.IdleThreshold{Intervals: 1},
+			},
+			newSelector:             labels.Everything(),
+			newMetricsCheckInterval: 5 * time.Second,
+			newIdleThreshold:        k8s.IdleThreshold{Intervals: 3},
+			expectedResult:          true,
+		},
+		{
+			name: "same selector and metricsCheckInterval but dry run enabled",
+			collector: &metricsCollector{
+				selector:             labels.Everything(),
+				metricsCheckInterval: 5 * time.Second,
+			},
+			newSelector:             labels.Everything(),
+			newMetricsCheckInterval: 5 * time.Second,
+			newDryRun:               true,
+			expectedResult:          true,
+		},
+		{
+			name: "same selector and metricsCheckInterval but different " +
+				"activity source",
+			collector: &metricsCollector{
+				selector:             labels.Everything(),
+				metricsCheckInterval: 5 * time.Second,
+				activitySource:       proxyActivitySource{},
+			},
+			newSelector:             labels.Everything(),
+			newMetricsCheckInterval: 5 * time.Second,
+			newActivitySource: prometheusActivitySource{
+				port:       8080,
+				path:       "/metrics",
+				metricName: "jobs_processed_total",
+			},
+			expectedResult: true,
+		},
+		{
+			name: "same selector and metricsCheckInterval but different " +
+				"pre-scale-down hook",
+			collector: &metricsCollector{
+				selector:             labels.Everything(),
+				metricsCheckInterval: 5 * time.Second,
+			},
+			newSelector:             labels.Everything(),
+			newMetricsCheckInterval: 5 * time.Second,
+			newPreScaleDownHook: preScaleDownHook{
+				port:    8080,
+				path:    "/pre-scale-down",
+				timeout: defaultPreScaleDownHookTimeout,
+			},
+			expectedResult: true,
+		},
 		{
 			name: "different selector and metricsCheckInterval",
 			collector: &metricsCollector{
@@ -67,6 +135,11 @@ func TestShouldUpdateCollector(t *testing.T) {
 				test.collector,
 				test.newSelector,
 				test.newMetricsCheckInterval,
+				test.newIdleThreshold,
+				test.newKeepWarm,
+				test.newDryRun,
+				test.newActivitySource,
+				test.newPreScaleDownHook,
 			)
 
 			assert.Equal(t, test.expectedResult, actual)
@@ -143,13 +216,528 @@ func TestGetMetricsCheckInterval(t *testing.T) {
 			},
 			expectedResult: 150 * time.Second,
 		},
+		{
+			name: "namespace default",
+			zeroScaler: &zeroscaler{
+				cfg: Config{
+					MetricsCheckInterval: 150,
+				},
+			},
+			deployment: &appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace:   "with-defaults",
+					Annotations: map[string]string{},
+				},
+			},
+			expectedResult: 90 * time.Second,
+		},
+		{
+			name: "custom valid annotation overriding namespace default",
+			zeroScaler: &zeroscaler{
+				cfg: Config{
+					MetricsCheckInterval: 150,
+				},
+			},
+			deployment: &appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: "with-defaults",
+					Annotations: map[string]string{
+						k8s.MetricsCheckIntervalAnnotationName: "60",
+					},
+				},
+			},
+			expectedResult: 60 * time.Second,
+		},
 	}
 
 	for _, test := range testcases {
 		t.Run(test.name, func(t *testing.T) {
+			test.zeroScaler.defaults = newTestResourceDefaults(
+				t,
+				[]*corev1.Namespace{
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "with-defaults",
+							Annotations: map[string]string{
+								k8s.MetricsCheckIntervalAnnotationName: "90",
+							},
+						},
+					},
+				},
+				nil,
+			)
 			actual := test.zeroScaler.getMetricsCheckInterval(test.deployment)
 
 			assert.Equal(t, test.expectedResult, actual)
 		})
 	}
 }
+
+func TestGetIdleThreshold(t *testing.T) {
+	testcases := []struct {
+		name           string
+		labels         map[string]string
+		annotations    map[string]string
+		expectedResult k8s.IdleThreshold
+	}{
+		{
+			name:           "no specific annotation",
+			annotations:    map[string]string{},
+			expectedResult: k8s.IdleThreshold{Intervals: 2},
+		},
+		{
+			name: "custom number of intervals",
+			annotations: map[string]string{
+				k8s.IdleThresholdAnnotationName: "5",
+			},
+			expectedResult: k8s.IdleThreshold{Intervals: 5},
+		},
+		{
+			name: "custom duration",
+			annotations: map[string]string{
+				k8s.IdleThresholdAnnotationName: "10m",
+			},
+			expectedResult: k8s.IdleThreshold{Duration: 10 * time.Minute},
+		},
+		{
+			name: "custom invalid annotation value",
+			annotations: map[string]string{
+				k8s.IdleThresholdAnnotationName: "something",
+			},
+			expectedResult: k8s.IdleThreshold{Intervals: 2},
+		},
+		{
+			name: "scale to zero policy",
+			labels: map[string]string{
+				"app": "my-app",
+			},
+			annotations:    map[string]string{},
+			expectedResult: k8s.IdleThreshold{Duration: 15 * time.Minute},
+		},
+		{
+			name: "custom annotation overriding scale to zero policy",
+			labels: map[string]string{
+				"app": "my-app",
+			},
+			annotations: map[string]string{
+				k8s.IdleThresholdAnnotationName: "5",
+			},
+			expectedResult: k8s.IdleThreshold{Intervals: 5},
+		},
+	}
+
+	z := &zeroscaler{
+		idleThreshold: k8s.IdleThreshold{Intervals: 2},
+		defaults: newTestResourceDefaults(
+			t,
+			nil,
+			[]*unstructured.Unstructured{
+				{
+					Object: map[string]interface{}{
+						"apiVersion": "osiris.deislabs.io/v1alpha1",
+						"kind":       "ScaleToZeroPolicy",
+						"metadata": map[string]interface{}{
+							"namespace": "default",
+							"name":      "my-policy",
+						},
+						"spec": map[string]interface{}{
+							"selector": map[string]interface{}{
+								"matchLabels": map[string]interface{}{
+									"app": "my-app",
+								},
+							},
+							"idleThreshold": "15m",
+						},
+					},
+				},
+			},
+		),
+	}
+	for _, test := range testcases {
+		t.Run(test.name, func(t *testing.T) {
+			actual := z.getIdleThreshold(&appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace:   "default",
+					Labels:      test.labels,
+					Annotations: test.annotations,
+				},
+			})
+
+			assert.Equal(t, test.expectedResult, actual)
+		})
+	}
+}
+
+func TestGetDeletedObject(t *testing.T) {
+	hpa := &autoscalingv1.HorizontalPodAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "default",
+			Name:      "my-app",
+		},
+	}
+	testcases := []struct {
+		name        string
+		obj         interface{}
+		expectedObj interface{}
+	}{
+		{
+			name:        "object",
+			obj:         hpa,
+			expectedObj: hpa,
+		},
+		{
+			name: "tombstone",
+			obj: cache.DeletedFinalStateUnknown{
+				Key: "default/my-app",
+				Obj: hpa,
+			},
+			expectedObj: hpa,
+		},
+	}
+	for _, test := range testcases {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expectedObj, getDeletedObject(test.obj))
+		})
+	}
+}
+
+func TestSyncDeletedDeployment(t *testing.T) {
+	deployment := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "default",
+			Name:      "my-app",
+		},
+	}
+	testcases := []struct {
+		name string
+		obj  interface{}
+	}{
+		{
+			name: "deployment",
+			obj:  deployment,
+		},
+		{
+			name: "tombstone",
+			obj: cache.DeletedFinalStateUnknown{
+				Key: "default/my-app",
+				Obj: deployment,
+			},
+		},
+		{
+			name: "tombstone of something else",
+			obj: cache.DeletedFinalStateUnknown{
+				Key: "default/my-app",
+				Obj: &appsv1.StatefulSet{},
+			},
+		},
+	}
+	for _, test := range testcases {
+		t.Run(test.name, func(t *testing.T) {
+			z := &zeroscaler{
+				collectors: map[string]*metricsCollector{},
+			}
+			assert.NotPanics(t, func() {
+				z.syncDeletedDeployment(test.obj)
+			})
+		})
+	}
+}
+
+func TestSyncDeletedStatefulSet(t *testing.T) {
+	statefulSet := &appsv1.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "default",
+			Name:      "my-app",
+		},
+	}
+	testcases := []struct {
+		name string
+		obj  interface{}
+	}{
+		{
+			name: "stateful set",
+			obj:  statefulSet,
+		},
+		{
+			name: "tombstone",
+			obj: cache.DeletedFinalStateUnknown{
+				Key: "default/my-app",
+				Obj: statefulSet,
+			},
+		},
+		{
+			name: "tombstone of something else",
+			obj: cache.DeletedFinalStateUnknown{
+				Key: "default/my-app",
+				Obj: &appsv1.Deployment{},
+			},
+		},
+	}
+	for _, test := range testcases {
+		t.Run(test.name, func(t *testing.T) {
+			z := &zeroscaler{
+				collectors: map[string]*metricsCollector{},
+			}
+			assert.NotPanics(t, func() {
+				z.syncDeletedStatefulSet(test.obj)
+			})
+		})
+	}
+}
+
+func TestSyncWorkloadGetsScaleOnlyWhenChanged(t *testing.T) {
+	gvk := schema.GroupVersionKind{
+		Group:   "argoproj.io",
+		Version: "v1alpha1",
+		Kind:    "Rollout",
+	}
+	workload := &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"status": map[string]interface{}{
+				"replicas": int64(1),
+			},
+		},
+	}
+	workload.SetNamespace("default")
+	workload.SetName("my-app")
+	workload.SetGeneration(1)
+	workload.SetAnnotations(map[string]string{
+		"osiris.deislabs.io/enabled": "true",
+	})
+	workloadsClient := &fakeWorkloadsClient{
+		replicas: map[string]int32{
+			getAppKey(getWorkloadReference(gvk, workload), "default"): 1,
+		},
+		selector: "app=my-app",
+	}
+	z := &zeroscaler{
+		workloadsClient: workloadsClient,
+		hpasInformer: cache.NewSharedIndexInformer(
+			&cache.ListWatch{},
+			&autoscalingv1.HorizontalPodAutoscaler{},
+			0,
+			cache.Indexers{
+				cache.NamespaceIndex: cache.MetaNamespaceIndexFunc,
+			},
+		),
+		defaults:       newTestResourceDefaults(t, nil, nil),
+		workloadScales: map[string]workloadScale{},
+		collectors:     map[string]*metricsCollector{},
+	}
+
+	z.syncWorkload(gvk, workload)
+	assert.Equal(t, 1, workloadsClient.getScaleCalls)
+
+	// Resynced
+	z.syncWorkload(gvk, workload)
+	assert.Equal(t, 1, workloadsClient.getScaleCalls)
+
+	// Only annotated
+	workload = workload.DeepCopy()
+	workload.SetAnnotations(map[string]string{
+		"osiris.deislabs.io/enabled":       "true",
+		k8s.LastScaledToZeroAnnotationName: "2019-01-07T18:00:00Z",
+	})
+	z.syncWorkload(gvk, workload)
+	assert.Equal(t, 1, workloadsClient.getScaleCalls)
+
+	// Spec changed
+	workload = workload.DeepCopy()
+	workload.SetGeneration(2)
+	z.syncWorkload(gvk, workload)
+	assert.Equal(t, 2, workloadsClient.getScaleCalls)
+
+	// Status changed
+	workload = workload.DeepCopy()
+	workload.Object["status"] = map[string]interface{}{
+		"replicas": int64(2),
+	}
+	z.syncWorkload(gvk, workload)
+	assert.Equal(t, 3, workloadsClient.getScaleCalls)
+
+	// Deleted
+	z.syncDeletedWorkload(gvk, workload)
+	assert.Empty(t, z.workloadScales)
+}
+
+func TestSyncDeletedWorkload(t *testing.T) {
+	gvk := schema.GroupVersionKind{
+		Group:   "argoproj.io",
+		Version: "v1alpha1",
+		Kind:    "Rollout",
+	}
+	workload := &unstructured.Unstructured{}
+	workload.SetNamespace("default")
+	workload.SetName("my-app")
+	testcases := []struct {
+		name string
+		obj  interface{}
+	}{
+		{
+			name: "workload",
+			obj:  workload,
+		},
+		{
+			name: "tombstone",
+			obj: cache.DeletedFinalStateUnknown{
+				Key: "default/my-app",
+				Obj: workload,
+			},
+		},
+	}
+	for _, test := range testcases {
+		t.Run(test.name, func(t *testing.T) {
+			z := &zeroscaler{
+				collectors: map[string]*metricsCollector{},
+			}
+			assert.NotPanics(t, func() {
+				z.syncDeletedWorkload(gvk, test.obj)
+			})
+		})
+	}
+}
+
+func TestRememberReplicas(t *testing.T) {
+	testcases := []struct {
+		name                     string
+		leading                  bool
+		replicas                 int32
+		previousReplicas         string
+		expectedPreviousReplicas string
+	}{
+		{
+			name:                     "not leading",
+			replicas:                 4,
+			expectedPreviousReplicas: "",
+		},
+		{
+			name:                     "no previous replicas",
+			leading:                  true,
+			replicas:                 4,
+			expectedPreviousReplicas: "4",
+		},
+		{
+			name:                     "same replicas",
+			leading:                  true,
+			replicas:                 4,
+			previousReplicas:         "4",
+			expectedPreviousReplicas: "4",
+		},
+		{
+			name:                     "different replicas",
+			leading:                  true,
+			replicas:                 2,
+			previousReplicas:         "4",
+			expectedPreviousReplicas: "2",
+		},
+	}
+	for _, test := range testcases {
+		t.Run(test.name, func(t *testing.T) {
+			deployment := &appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace:   "default",
+					Name:        "my-app",
+					Annotations: map[string]string{},
+				},
+			}
+			workloadsClient := &fakeWorkloadsClient{
+				annotations: map[string]string{},
+			}
+			if test.previousReplicas != "" {
+				deployment.Annotations[k8s.PreviousReplicasAnnotationName] =
+					test.previousReplicas
+			}
+			z := &zeroscaler{
+				workloadsClient: workloadsClient,
+			}
+			if test.leading {
+				z.ctx = context.Background()
+			}
+			z.rememberReplicas(
+				k8s.DeploymentReference("my-app"),
+				deployment,
+				test.replicas,
+			)
+			if test.previousReplicas == test.expectedPreviousReplicas {
+				// Nothing should have been written
+				assert.Empty(t, workloadsClient.annotations)
+				return
+			}
+			assert.Equal(
+				t,
+				test.expectedPreviousReplicas,
+				workloadsClient.annotations[k8s.PreviousReplicasAnnotationName],
+			)
+		})
+	}
+}
+
+func TestLeadershipTerms(t *testing.T) {
+	z := &zeroscaler{
+		collectors: map[string]*metricsCollector{},
+	}
+
+	// Acquire leadership
+	firstCtx, loseFirst := context.WithCancel(context.Background())
+	z.startLeading(firstCtx)
+	assert.True(t, z.ctx == firstCtx)
+	z.collectors["first"] = &metricsCollector{}
+
+	// Lose leadership and acquire it again before the first term's teardown
+	loseFirst()
+	secondCtx, loseSecond := context.WithCancel(context.Background())
+	defer loseSecond()
+	z.startLeading(secondCtx)
+	assert.True(t, z.ctx == secondCtx)
+	assert.Empty(t, z.collectors)
+	z.collectors["second"] = &metricsCollector{}
+
+	// The first term's teardown must leave the second term alone
+	z.stopLeading(firstCtx)
+	assert.True(t, z.ctx == secondCtx)
+	assert.Contains(t, z.collectors, "second")
+
+	// Lose leadership for good
+	loseSecond()
+	z.stopLeading(secondCtx)
+	assert.Nil(t, z.ctx)
+	assert.Empty(t, z.collectors)
+}
+
+// newTestResourceDefaults returns a ResourceDefaults that knows about the
+// given namespaces and scale to zero policies without talking to the
+// Kubernetes API server
+func newTestResourceDefaults(
+	t *testing.T,
+	namespaces []*corev1.Namespace,
+	policies []*unstructured.Unstructured,
+) *k8s.ResourceDefaults {
+	namespacesInformer := cache.NewSharedIndexInformer(
+		&cache.ListWatch{},
+		&corev1.Namespace{},
+		0,
+		cache.Indexers{},
+	)
+	for _, namespace := range namespaces {
+		assert.NoError(t, namespacesInformer.GetStore().Add(namespace))
+	}
+	policiesInformer := cache.NewSharedIndexInformer(
+		&cache.ListWatch{},
+		&unstructured.Unstructured{},
+		0,
+		cache.Indexers{
+			cache.NamespaceIndex: cache.MetaNamespaceIndexFunc,
+		},
+	)
+	for _, policy := range policies {
+		assert.NoError(t, policiesInformer.GetStore().Add(policy))
+	}
+	return &k8s.ResourceDefaults{
+		Namespaces: &k8s.NamespaceDefaults{
+			SharedIndexInformer: namespacesInformer,
+		},
+		Policies: &k8s.ScaleToZeroPolicies{
+			SharedIndexInformer: policiesInformer,
+		},
+	}
+}
@@ -1,6 +1,9 @@
 package controller
 
-import "github.com/kelseyhightower/envconfig"
+import (
+	k8s "github.com/deislabs/osiris/pkg/kubernetes"
+	"github.com/kelseyhightower/envconfig"
+)
 
 const envconfigPrefix = "OSIRIS_ENDPOINTS_CONTROLLER"
 
@@ -10,13 +13,18 @@ type Config struct {
 	OsirisNamespace                string `envconfig:"OSIRIS_NAMESPACE" required:"true"`
 	ActivatorPodLabelSelectorKey   string `envconfig:"ACTIVATOR_POD_LABEL_SELECTOR_KEY" required:"true"`
 	ActivatorPodLabelSelectorValue string `envconfig:"ACTIVATOR_POD_LABEL_SELECTOR_VALUE" required:"true"`
+	k8s.LeaderElectionConfig
 }
 
 // NewConfigWithDefaults returns a Config object with default values already
 // applied. Callers are then free to set custom values for the remaining fields
 // and/or override default values.
 func NewConfigWithDefaults() Config {
-	return Config{}
+	return Config{
+		LeaderElectionConfig: k8s.NewLeaderElectionConfigWithDefaults(
+			"osiris-endpoints-controller",
+		),
+	}
 }
 
 // GetConfigFromEnvironment returns configuration derived from environment
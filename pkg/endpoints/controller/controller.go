@@ -11,6 +11,7 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/tools/cache"
 )
@@ -27,14 +28,17 @@ type Controller interface {
 // controller is a component that can take over management of endpoints
 // resources corresponding to selector-less, Osiris-enabled services
 type controller struct {
+	config                 Config
 	kubeClient             kubernetes.Interface
 	activatorPodsInformer  cache.SharedIndexInformer
 	readyActivatorPods     map[string]corev1.Pod
 	readyActivatorPodsLock sync.Mutex
 	servicesInformer       cache.SharedIndexInformer
+	defaults               *k8s.ResourceDefaults
 	managers               map[string]*endpointsManager
 	managersLock           sync.Mutex
-	ctx                    context.Context
+	// ctx is only non-nil while this replica of the controller is the leader
+	ctx context.Context
 }
 
 // NewController returns a new component that can take over management of
@@ -42,6 +46,7 @@ type controller struct {
 func NewController(
 	config Config,
 	kubeClient kubernetes.Interface,
+	dynamicClient dynamic.Interface,
 ) Controller {
 	activatorPodsSelector := labels.SelectorFromSet(
 		map[string]string{
@@ -49,6 +54,7 @@ func NewController(
 		},
 	)
 	c := &controller{
+		config:     config,
 		kubeClient: kubeClient,
 		activatorPodsInformer: k8s.PodsIndexInformer(
 			kubeClient,
@@ -63,6 +69,7 @@ func NewController(
 			nil,
 			nil,
 		),
+		defaults: k8s.NewResourceDefaults(kubeClient, dynamicClient),
 		managers: map[string]*endpointsManager{},
 	}
 	c.activatorPodsInformer.AddEventHandler(
@@ -83,16 +90,18 @@ func NewController(
 			DeleteFunc: c.syncDeletedAppService,
 		},
 	)
+	c.defaults.AddNamespaceEventHandler(c.syncNamespace)
 	return c
 }
 
 // Run causes the controller to manage endpoints resources corresponding to
 // selector-less, Osiris-enabled services. This function will not return until
-// the context it has been passed expires or is canceled.
+// the context it has been passed expires or is canceled. Informers run
+// regardless of whether this replica of the controller is the leader, so that
+// a replica that acquires leadership can take over promptly.
 func (c *controller) Run(ctx context.Context) {
 	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
-	c.ctx = ctx
 	go func() {
 		<-ctx.Done()
 		glog.Infof("Controller is shutting down")
@@ -106,17 +115,92 @@ func (c *controller) Run(ctx context.Context) {
 		c.servicesInformer.Run(ctx.Done())
 		cancel()
 	}()
+	for _, informer := range c.defaults.Informers() {
+		go func(informer cache.SharedIndexInformer) {
+			informer.Run(ctx.Done())
+			cancel()
+		}(informer)
+	}
+	go func() {
+		if err := k8s.RunLeaderElection(
+			ctx,
+			c.kubeClient,
+			c.config.LeaderElectionConfig,
+			c.lead,
+		); err != nil {
+			glog.Errorf("Controller leader election error: %s", err)
+		}
+		cancel()
+	}()
 	healthz.RunServer(ctx, 5000)
 	cancel()
 }
 
+// lead is invoked when this replica of the controller becomes the leader.
+// Endpoints resources are managed until the context is canceled, which
+// signals the loss of leadership.
+func (c *controller) lead(ctx context.Context) {
+	hasSynced := []cache.InformerSynced{
+		c.activatorPodsInformer.HasSynced,
+		c.servicesInformer.HasSynced,
+	}
+	for _, informer := range c.defaults.Informers() {
+		hasSynced = append(hasSynced, informer.HasSynced)
+	}
+	if !cache.WaitForCacheSync(ctx.Done(), hasSynced...) {
+		return
+	}
+	glog.Infof("Controller is leading")
+	func() {
+		c.managersLock.Lock()
+		defer c.managersLock.Unlock()
+		c.ctx = ctx
+		// Managers left over from a previous term were started with that term's
+		// context, which is canceled already, so they are stopping
+		c.managers = map[string]*endpointsManager{}
+	}()
+	// Catch up on all services we've been informed about while not leading
+	for _, obj := range c.servicesInformer.GetStore().List() {
+		c.syncAppService(obj)
+	}
+	<-ctx.Done()
+	glog.Infof("Controller is no longer leading")
+	c.managersLock.Lock()
+	defer c.managersLock.Unlock()
+	if c.ctx != ctx {
+		// Leadership was lost and acquired again in quick succession, and the
+		// next term has started already
+		return
+	}
+	c.ctx = nil
+	// All managers were started with the now canceled context, so they are
+	// already stopping
+	c.managers = map[string]*endpointsManager{}
+}
+
 // syncAppService is notified of all new and updated service resources. For
 // those that are Osiris-enabled, on-going management of that service's
 // corresponding endpoints resource will be guaranteed, whilst the same will
-// be prevented for non-Osiris-enabled services.
+// be prevented for non-Osiris-enabled services. A service that was created
+// before Osiris was enabled for it by its namespace or by a scale to zero
+// policy hasn't had its selector moved to an annotation by the hijacker, so it
+// still selects its own pods and is left alone until it is next updated.
 func (c *controller) syncAppService(obj interface{}) {
 	svc := obj.(*corev1.Service)
-	if k8s.ResourceIsOsirisEnabled(svc.Annotations) {
+	if k8s.ResourceIsOsirisEnabled(
+		c.defaults.ApplyToService(svc.Namespace, svc),
+	) {
+		if _, ok := svc.Annotations["osiris.deislabs.io/selector"]; !ok {
+			glog.Infof(
+				"Notified about Osiris-enabled service %s in namespace %s that has "+
+					"not been hijacked yet; ensuring its endpoints are NOT managed "+
+					"until it is updated",
+				svc.Name,
+				svc.Namespace,
+			)
+			c.ensureServiceEndpointsNotManaged(svc)
+			return
+		}
 		glog.Infof(
 			"Notified about new or updated Osiris-enabled service %s in namespace %s",
 			svc.Name,
@@ -134,6 +218,18 @@ func (c *controller) syncAppService(obj interface{}) {
 	}
 }
 
+// syncNamespace is notified of every namespace in which the defaults that
+// services inherit from their namespace or scale to zero policy may have
+// changed. Whether the services in such a namespace are Osiris-enabled may
+// depend on those defaults, so they are synced again.
+func (c *controller) syncNamespace(namespace string) {
+	for _, svcObj := range c.servicesInformer.GetStore().List() {
+		if svcObj.(*corev1.Service).Namespace == namespace {
+			c.syncAppService(svcObj)
+		}
+	}
+}
+
 // syncDeletedAppService is notified of all deleted service resources. It
 // ensures any on-going management (if any) of the service's corresponding
 // endpoints resource is halted.
@@ -154,6 +250,10 @@ func (c *controller) syncDeletedAppService(obj interface{}) {
 func (c *controller) ensureServiceEndpointsManaged(svc *corev1.Service) {
 	c.managersLock.Lock()
 	defer c.managersLock.Unlock()
+	if c.ctx == nil {
+		// This replica of the controller is not the leader
+		return
+	}
 	key := getServiceKey(svc)
 	if e, ok := c.managers[key]; ok {
 		e.stop()
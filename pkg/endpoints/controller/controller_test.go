@@ -0,0 +1,105 @@
+package controller
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	k8s "github.com/deislabs/osiris/pkg/kubernetes"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/cache"
+)
+
+func TestSyncNamespaceEnabledAfterServicesExist(t *testing.T) {
+	// Endpoints managers only talk to the API server when syncing endpoints,
+	// which may fail
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+	namespace := &corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "my-namespace",
+		},
+	}
+	namespacesInformer := cache.NewSharedIndexInformer(
+		&cache.ListWatch{},
+		&corev1.Namespace{},
+		0,
+		cache.Indexers{},
+	)
+	assert.NoError(t, namespacesInformer.GetStore().Add(namespace))
+	servicesInformer := cache.NewSharedIndexInformer(
+		&cache.ListWatch{},
+		&corev1.Service{},
+		0,
+		cache.Indexers{},
+	)
+	// Created before Osiris was enabled for the namespace, so the hijacker left
+	// its selector alone
+	unhijacked := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "my-namespace",
+			Name:      "unhijacked",
+			Annotations: map[string]string{
+				"osiris.deislabs.io/deployment": "unhijacked",
+			},
+		},
+		Spec: corev1.ServiceSpec{
+			Selector: map[string]string{
+				"app": "unhijacked",
+			},
+		},
+	}
+	// Updated since Osiris was enabled for the namespace
+	hijacked := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "my-namespace",
+			Name:      "hijacked",
+			Annotations: map[string]string{
+				"osiris.deislabs.io/deployment": "hijacked",
+				"osiris.deislabs.io/selector": base64.StdEncoding.EncodeToString(
+					[]byte(`{"app":"hijacked"}`),
+				),
+			},
+		},
+	}
+	assert.NoError(t, servicesInformer.GetStore().Add(unhijacked))
+	assert.NoError(t, servicesInformer.GetStore().Add(hijacked))
+	// Leading, but the leadership term is over before managers get going
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	c := &controller{
+		kubeClient: kubernetes.NewForConfigOrDie(
+			&rest.Config{
+				Host: server.URL,
+			},
+		),
+		servicesInformer: servicesInformer,
+		defaults: &k8s.ResourceDefaults{
+			Namespaces: &k8s.NamespaceDefaults{
+				SharedIndexInformer: namespacesInformer,
+			},
+		},
+		managers: map[string]*endpointsManager{},
+		ctx:      ctx,
+	}
+
+	c.syncNamespace("my-namespace")
+	assert.Empty(t, c.managers)
+
+	namespace = namespace.DeepCopy()
+	namespace.Labels = map[string]string{
+		"osiris.deislabs.io/enabled": "true",
+	}
+	assert.NoError(t, namespacesInformer.GetStore().Update(namespace))
+	c.syncNamespace("my-namespace")
+	c.managersLock.Lock()
+	defer c.managersLock.Unlock()
+	assert.Contains(t, c.managers, getServiceKey(hijacked))
+	assert.NotContains(t, c.managers, getServiceKey(unhijacked))
+}
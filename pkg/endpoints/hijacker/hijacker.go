@@ -16,6 +16,9 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/serializer"
+	"k8s.io/client-go/dynamic"
+	k8s "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
 )
 
 const port = 5000
@@ -34,6 +37,7 @@ type Hijacker interface {
 // controller to manage service endpoints
 type hijacker struct {
 	config       Config
+	defaults     *kubernetes.ResourceDefaults
 	deserializer runtime.Decoder
 	srv          *http.Server
 }
@@ -41,11 +45,16 @@ type hijacker struct {
 // NewHijacker returns a new component that handles webhook requests for
 // patching Osiris-enabled services in a manner that will permit the Osiris
 // endpoints controller to manage service endpoints
-func NewHijacker(config Config) Hijacker {
+func NewHijacker(
+	config Config,
+	kubeClient k8s.Interface,
+	dynamicClient dynamic.Interface,
+) Hijacker {
 	mux := http.NewServeMux()
 
 	h := &hijacker{
-		config: config,
+		config:   config,
+		defaults: kubernetes.NewResourceDefaults(kubeClient, dynamicClient),
 		deserializer: serializer.NewCodecFactory(
 			runtime.NewScheme(),
 		).UniversalDeserializer(),
@@ -64,8 +73,21 @@ func NewHijacker(config Config) Hijacker {
 // Run causes the webhook server to serve requests. This function will not
 // return until the context it has been passed expires or is canceled.
 func (h *hijacker) Run(ctx context.Context) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
 	doneCh := make(chan struct{})
 
+	// Namespaces and scale to zero policies must be known before services can be
+	// patched correctly
+	hasSynced := []cache.InformerSynced{}
+	for _, informer := range h.defaults.Informers() {
+		go informer.Run(ctx.Done())
+		hasSynced = append(hasSynced, informer.HasSynced)
+	}
+	if !cache.WaitForCacheSync(ctx.Done(), hasSynced...) {
+		return
+	}
+
 	go func() {
 		select {
 		case <-ctx.Done(): // Context was canceled or expired
@@ -142,10 +164,15 @@ func (h *hijacker) handleRequest(w http.ResponseWriter, r *http.Request) {
 				ar.Request.Operation,
 				ar.Request.UserInfo,
 			)
-			if err = validateService(svc); err != nil {
+			// Services may not have a namespace of their own yet when they are
+			// created
+			osirisEnabled := kubernetes.ResourceIsOsirisEnabled(
+				h.defaults.ApplyToService(ar.Request.Namespace, svc),
+			)
+			if err = validateService(svc, osirisEnabled); err != nil {
 				glog.Errorf("Error validating service: %v", err)
 			} else {
-				patchOps, err = getServicePatchOperations(svc)
+				patchOps, err = getServicePatchOperations(svc, osirisEnabled)
 			}
 		}
 	}
@@ -211,12 +238,23 @@ func (h *hijacker) handleRequest(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-func validateService(svc *corev1.Service) error {
-	if kubernetes.ResourceIsOsirisEnabled(svc.Annotations) {
-		if _, ok := svc.Annotations["osiris.deislabs.io/deployment"]; !ok {
+func validateService(svc *corev1.Service, osirisEnabled bool) error {
+	if osirisEnabled {
+		_, ok, err := kubernetes.GetServiceWorkloadReference(svc.Annotations)
+		if err != nil {
+			return fmt.Errorf(
+				"Osiris-enabled service %s in namespace %s is invalid: %s",
+				svc.Name,
+				svc.Namespace,
+				err,
+			)
+		}
+		if !ok {
 			return fmt.Errorf(
 				`Osiris-enabled service %s in namespace %s is lacking the required `+
-					`"osiris.deislabs.io/deployment" annotation`,
+					`"osiris.deislabs.io/deployment", `+
+					`"osiris.deislabs.io/statefulset", or `+
+					`"osiris.deislabs.io/workload" annotation`,
 				svc.Name,
 				svc.Namespace,
 			)
@@ -17,16 +17,18 @@ import (
 // permitting the Osiris endpoints controller to provide that function instead.
 // The Osiris endpoints controller will use the encoded, saved would-be selector
 // to establish a watch on the very pods that the service would have selected
-// itself were it not selector-less.
+// itself were it not selector-less. Whether the service is Osiris-enabled is
+// determined by the caller, since it may depend on the service's namespace.
 func getServicePatchOperations(
 	svc *corev1.Service,
+	osirisEnabled bool,
 ) ([]kubernetes.PatchOperation, error) {
 	const osirisEnabledAnnotationPath = "/metadata/annotations/osiris.deislabs.io~1selector" // nolint: lll
 
 	patchOps := []kubernetes.PatchOperation{}
 
 	// Service is Osiris-enabled... make it so...
-	if kubernetes.ResourceIsOsirisEnabled(svc.Annotations) {
+	if osirisEnabled {
 
 		glog.Infof("Hijacking service %s", svc.Name)
 
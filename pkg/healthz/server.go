@@ -7,11 +7,28 @@ import (
 	"time"
 
 	"github.com/golang/glog"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
+// RunServer serves health checks at /healthz and Prometheus metrics for the
+// current process at /metrics.
 func RunServer(ctx context.Context, port int) {
+	RunServerWithHandlers(ctx, port, nil)
+}
+
+// RunServerWithHandlers is like RunServer, but additionally serves the given
+// handlers, indexed by path.
+func RunServerWithHandlers(
+	ctx context.Context,
+	port int,
+	handlers map[string]http.Handler,
+) {
 	mux := http.NewServeMux()
 	mux.HandleFunc("/healthz", HandleHealthCheckRequest)
+	mux.Handle("/metrics", promhttp.Handler())
+	for path, handler := range handlers {
+		mux.Handle(path, handler)
+	}
 	srv := &http.Server{
 		Addr:    fmt.Sprintf(":%d", port),
 		Handler: mux,
@@ -2,8 +2,13 @@ package kubernetes
 
 import (
 	"github.com/kelseyhightower/envconfig"
+	"k8s.io/client-go/discovery"
+	cacheddiscovery "k8s.io/client-go/discovery/cached"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
+	"k8s.io/client-go/restmapper"
+	"k8s.io/client-go/scale"
 	"k8s.io/client-go/tools/clientcmd"
 )
 
@@ -17,19 +22,61 @@ type config struct {
 
 // Client returns a new Kubernetes client
 func Client() (*kubernetes.Clientset, error) {
-	c := config{}
-	err := envconfig.Process(envconfigPrefix, &c)
+	cfg, err := restConfig()
 	if err != nil {
 		return nil, err
 	}
-	var cfg *rest.Config
-	if c.MasterURL == "" && c.KubeConfigPath == "" {
-		cfg, err = rest.InClusterConfig()
-	} else {
-		cfg, err = clientcmd.BuildConfigFromFlags(c.MasterURL, c.KubeConfigPath)
+	return kubernetes.NewForConfig(cfg)
+}
+
+// DynamicClient returns a new dynamic Kubernetes client
+func DynamicClient() (dynamic.Interface, error) {
+	cfg, err := restConfig()
+	if err != nil {
+		return nil, err
 	}
+	return dynamic.NewForConfig(cfg)
+}
+
+// NewWorkloadsClient returns a new client for uniformly accessing workloads of
+// any kind that implements the scale subresource. Kinds are mapped to
+// resources using (cached) API discovery.
+func NewWorkloadsClient() (WorkloadsClient, error) {
+	cfg, err := restConfig()
 	if err != nil {
 		return nil, err
 	}
-	return kubernetes.NewForConfig(cfg)
+	discoveryClient, err := discovery.NewDiscoveryClientForConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	restMapper := restmapper.NewDeferredDiscoveryRESTMapper(
+		cacheddiscovery.NewMemCacheClient(discoveryClient),
+	)
+	scalesGetter, err := scale.NewForConfig(
+		cfg,
+		restMapper,
+		dynamic.LegacyAPIPathResolverFunc,
+		scale.NewDiscoveryScaleKindResolver(discoveryClient),
+	)
+	if err != nil {
+		return nil, err
+	}
+	dynamicClient, err := dynamic.NewForConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return newWorkloadsClient(dynamicClient, scalesGetter, restMapper), nil
+}
+
+func restConfig() (*rest.Config, error) {
+	c := config{}
+	err := envconfig.Process(envconfigPrefix, &c)
+	if err != nil {
+		return nil, err
+	}
+	if c.MasterURL == "" && c.KubeConfigPath == "" {
+		return rest.InClusterConfig()
+	}
+	return clientcmd.BuildConfigFromFlags(c.MasterURL, c.KubeConfigPath)
 }
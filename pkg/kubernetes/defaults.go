@@ -0,0 +1,120 @@
+package kubernetes
+
+import (
+	"github.com/golang/glog"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+)
+
+// ResourceDefaults applies the Osiris configuration that resources inherit
+// from elsewhere than their own annotations-- i.e. from the scale to zero
+// policy that applies to them and from their namespace. Annotations set on a
+// resource take precedence over its scale to zero policy, which takes
+// precedence over its namespace. ResourceDefaults relies on informers, which
+// must be run by the caller.
+type ResourceDefaults struct {
+	Namespaces *NamespaceDefaults
+	// Policies is nil if scale to zero policies aren't available
+	Policies *ScaleToZeroPolicies
+}
+
+// NewResourceDefaults returns a ResourceDefaults backed by informers for all
+// namespaces and all scale to zero policies. If the scale to zero policy
+// custom resource definition isn't installed, scale to zero policies are
+// ignored, since an informer for them would never sync.
+func NewResourceDefaults(
+	kubeClient kubernetes.Interface,
+	dynamicClient dynamic.Interface,
+) *ResourceDefaults {
+	r := &ResourceDefaults{
+		Namespaces: NewNamespaceDefaults(kubeClient),
+	}
+	installed, err := ScaleToZeroPolicyResourceInstalled(kubeClient.Discovery())
+	if err != nil {
+		glog.Errorf(
+			"Error discovering scale to zero policies; they will be ignored: %s",
+			err,
+		)
+	} else if !installed {
+		glog.Warningf(
+			"Scale to zero policy custom resource definition is not installed; " +
+				"scale to zero policies will be ignored",
+		)
+	} else {
+		r.Policies = NewScaleToZeroPolicies(dynamicClient)
+	}
+	return r
+}
+
+// Informers returns the informers that must be run, and synced, for defaults
+// to be applied.
+func (r *ResourceDefaults) Informers() []cache.SharedIndexInformer {
+	if r.Policies == nil {
+		return []cache.SharedIndexInformer{r.Namespaces}
+	}
+	return []cache.SharedIndexInformer{r.Namespaces, r.Policies}
+}
+
+// AddNamespaceEventHandler registers a handler that is notified of the name of
+// every namespace in which defaults may have changed-- e.g. to reconsider the
+// resources in that namespace.
+func (r *ResourceDefaults) AddNamespaceEventHandler(
+	handler func(namespace string),
+) {
+	r.Namespaces.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			handler(obj.(*corev1.Namespace).Name)
+		},
+		UpdateFunc: func(_, newObj interface{}) {
+			handler(newObj.(*corev1.Namespace).Name)
+		},
+	})
+	if r.Policies == nil {
+		return
+	}
+	handlePolicy := func(obj interface{}) {
+		if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+			obj = tombstone.Obj
+		}
+		handler(obj.(*unstructured.Unstructured).GetNamespace())
+	}
+	r.Policies.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: handlePolicy,
+		UpdateFunc: func(_, newObj interface{}) {
+			handlePolicy(newObj)
+		},
+		DeleteFunc: handlePolicy,
+	})
+}
+
+// Apply returns a copy of the annotations of a resource in the given
+// namespace, supplemented by the scale to zero policy that applies to the
+// resource and by the defaults set on the namespace. The namespace is passed
+// separately because it isn't always set on resources that are being created.
+func (r *ResourceDefaults) Apply(
+	namespace string,
+	obj metav1.Object,
+) map[string]string {
+	var policy *ScaleToZeroPolicy
+	if r.Policies != nil {
+		policy = r.Policies.Select(namespace, obj.GetLabels())
+	}
+	return ApplyNamespaceDefaults(
+		ApplyScaleToZeroPolicy(obj.GetAnnotations(), policy),
+		r.Namespaces.getNamespace(namespace),
+	)
+}
+
+// ApplyToService is like Apply, except that Osiris being enabled by a scale to
+// zero policy or by the namespace only enables it for a service that refers
+// to the workload behind it. See ApplyNamespaceDefaultsToService.
+func (r *ResourceDefaults) ApplyToService(
+	namespace string,
+	svc *corev1.Service,
+) map[string]string {
+	return withoutImpliedServiceEnablement(svc, r.Apply(namespace, svc))
+}
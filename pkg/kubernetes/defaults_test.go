@@ -0,0 +1,112 @@
+package kubernetes
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/tools/cache"
+)
+
+func TestResourceDefaultsApply(t *testing.T) {
+	namespacesInformer := cache.NewSharedIndexInformer(
+		&cache.ListWatch{},
+		&corev1.Namespace{},
+		0,
+		cache.Indexers{},
+	)
+	require.NoError(t, namespacesInformer.GetStore().Add(&corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "default",
+			Annotations: map[string]string{
+				MetricsCheckIntervalAnnotationName: "60",
+				MinReplicasAnnotationName:          "2",
+			},
+		},
+	}))
+	policiesInformer := cache.NewSharedIndexInformer(
+		&cache.ListWatch{},
+		&unstructured.Unstructured{},
+		0,
+		cache.Indexers{
+			cache.NamespaceIndex: cache.MetaNamespaceIndexFunc,
+		},
+	)
+	require.NoError(t, policiesInformer.GetStore().Add(&unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"apiVersion": "osiris.deislabs.io/v1alpha1",
+			"kind":       "ScaleToZeroPolicy",
+			"metadata": map[string]interface{}{
+				"namespace": "default",
+				"name":      "my-policy",
+			},
+			"spec": map[string]interface{}{
+				"selector": map[string]interface{}{
+					"matchLabels": map[string]interface{}{
+						"app": "my-app",
+					},
+				},
+				"minReplicas": int64(3),
+			},
+		},
+	}))
+	defaults := &ResourceDefaults{
+		Namespaces: &NamespaceDefaults{SharedIndexInformer: namespacesInformer},
+		Policies:   &ScaleToZeroPolicies{SharedIndexInformer: policiesInformer},
+	}
+
+	testcases := []struct {
+		name                string
+		labels              map[string]string
+		annotations         map[string]string
+		expectedAnnotations map[string]string
+	}{
+		{
+			name:   "namespace defaults only",
+			labels: map[string]string{"app": "other-app"},
+			expectedAnnotations: map[string]string{
+				MetricsCheckIntervalAnnotationName: "60",
+				MinReplicasAnnotationName:          "2",
+			},
+		},
+		{
+			name:   "policy taking precedence over namespace",
+			labels: map[string]string{"app": "my-app"},
+			expectedAnnotations: map[string]string{
+				osirisEnabledAnnotationName:        "true",
+				MetricsCheckIntervalAnnotationName: "60",
+				MinReplicasAnnotationName:          "3",
+			},
+		},
+		{
+			name:   "annotations taking precedence over policy",
+			labels: map[string]string{"app": "my-app"},
+			annotations: map[string]string{
+				MinReplicasAnnotationName: "4",
+			},
+			expectedAnnotations: map[string]string{
+				osirisEnabledAnnotationName:        "true",
+				MetricsCheckIntervalAnnotationName: "60",
+				MinReplicasAnnotationName:          "4",
+			},
+		},
+	}
+	for _, test := range testcases {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(
+				t,
+				test.expectedAnnotations,
+				defaults.Apply(
+					"default",
+					&metav1.ObjectMeta{
+						Labels:      test.labels,
+						Annotations: test.annotations,
+					},
+				),
+			)
+		})
+	}
+}
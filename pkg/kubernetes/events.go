@@ -0,0 +1,29 @@
+package kubernetes
+
+import (
+	"github.com/golang/glog"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+// NewEventRecorder returns an EventRecorder that records events, attributed to
+// the specified component, using the provided client.
+func NewEventRecorder(
+	client kubernetes.Interface,
+	component string,
+) record.EventRecorder {
+	broadcaster := record.NewBroadcaster()
+	broadcaster.StartLogging(glog.Infof)
+	broadcaster.StartRecordingToSink(
+		&typedcorev1.EventSinkImpl{
+			Interface: client.CoreV1().Events(""),
+		},
+	)
+	return broadcaster.NewRecorder(
+		scheme.Scheme,
+		corev1.EventSource{Component: component},
+	)
+}
@@ -0,0 +1,45 @@
+package kubernetes
+
+import (
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// GetWorkloadHPA returns the first of the given horizontal pod autoscalers
+// that targets the referenced workload, or nil if none do. All the given
+// autoscalers are assumed to be in the same namespace as the workload.
+func GetWorkloadHPA(
+	hpas []*autoscalingv1.HorizontalPodAutoscaler,
+	workload WorkloadReference,
+) *autoscalingv1.HorizontalPodAutoscaler {
+	for _, hpa := range hpas {
+		target := hpa.Spec.ScaleTargetRef
+		if target.Kind != workload.Kind || target.Name != workload.Name {
+			continue
+		}
+		// The version of the target's API doesn't matter, only its group
+		gv, err := schema.ParseGroupVersion(target.APIVersion)
+		if err != nil || gv.Group != workload.Group {
+			continue
+		}
+		return hpa
+	}
+	return nil
+}
+
+// GetHPAMinReplicas returns the minimum number of replicas that the given
+// horizontal pod autoscaler will scale its target to, or the default value if
+// the autoscaler is nil.
+func GetHPAMinReplicas(
+	hpa *autoscalingv1.HorizontalPodAutoscaler,
+	defaultVal int32,
+) int32 {
+	if hpa == nil {
+		return defaultVal
+	}
+	if hpa.Spec.MinReplicas == nil {
+		// This is the API server's default
+		return 1
+	}
+	return *hpa.Spec.MinReplicas
+}
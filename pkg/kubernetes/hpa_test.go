@@ -0,0 +1,95 @@
+package kubernetes
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestGetWorkloadHPA(t *testing.T) {
+	newHPA := func(
+		name string,
+		apiVersion string,
+		kind string,
+		targetName string,
+	) *autoscalingv1.HorizontalPodAutoscaler {
+		return &autoscalingv1.HorizontalPodAutoscaler{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: name,
+			},
+			Spec: autoscalingv1.HorizontalPodAutoscalerSpec{
+				ScaleTargetRef: autoscalingv1.CrossVersionObjectReference{
+					APIVersion: apiVersion,
+					Kind:       kind,
+					Name:       targetName,
+				},
+			},
+		}
+	}
+	hpas := []*autoscalingv1.HorizontalPodAutoscaler{
+		newHPA("other-app", "apps/v1", "Deployment", "other-app"),
+		newHPA("my-stateful-app", "apps/v1", "StatefulSet", "my-app"),
+		newHPA("my-app", "extensions/v1beta1", "Deployment", "my-app"),
+		newHPA("my-legacy-app", "apps/v1beta2", "Deployment", "my-legacy-app"),
+	}
+	testcases := []struct {
+		name         string
+		workload     WorkloadReference
+		expectedName string
+	}{
+		{
+			name:         "matching kind and name",
+			workload:     StatefulSetReference("my-app"),
+			expectedName: "my-stateful-app",
+		},
+		{
+			name:         "matching kind and name, other version of group",
+			workload:     DeploymentReference("my-legacy-app"),
+			expectedName: "my-legacy-app",
+		},
+		{
+			name:     "matching kind and name, other group",
+			workload: DeploymentReference("my-app"),
+		},
+		{
+			name:     "no match",
+			workload: DeploymentReference("yet-another-app"),
+		},
+	}
+	for _, test := range testcases {
+		t.Run(test.name, func(t *testing.T) {
+			hpa := GetWorkloadHPA(hpas, test.workload)
+			if test.expectedName == "" {
+				assert.Nil(t, hpa)
+				return
+			}
+			if assert.NotNil(t, hpa) {
+				assert.Equal(t, test.expectedName, hpa.Name)
+			}
+		})
+	}
+}
+
+func TestGetHPAMinReplicas(t *testing.T) {
+	minReplicas := int32(3)
+	assert.Equal(t, int32(2), GetHPAMinReplicas(nil, 2))
+	assert.Equal(
+		t,
+		int32(1),
+		GetHPAMinReplicas(&autoscalingv1.HorizontalPodAutoscaler{}, 2),
+	)
+	assert.Equal(
+		t,
+		int32(3),
+		GetHPAMinReplicas(
+			&autoscalingv1.HorizontalPodAutoscaler{
+				Spec: autoscalingv1.HorizontalPodAutoscalerSpec{
+					MinReplicas: &minReplicas,
+				},
+			},
+			2,
+		),
+	)
+}
@@ -0,0 +1,69 @@
+package kubernetes
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// IdleThreshold expresses how long an app must remain continuously idle
+// before it is scaled to zero-- either as a number of consecutive metrics
+// check intervals in which no activity was observed or as a wall-clock
+// duration. Exactly one of the two fields is non-zero.
+type IdleThreshold struct {
+	Intervals int
+	Duration  time.Duration
+}
+
+// DefaultIdleThreshold preserves the original behavior of scaling to zero
+// after a single idle metrics check interval.
+var DefaultIdleThreshold = IdleThreshold{Intervals: 1}
+
+// ParseIdleThreshold parses either a positive integer number of consecutive
+// idle intervals (e.g. "3") or a positive duration (e.g. "10m").
+func ParseIdleThreshold(str string) (IdleThreshold, error) {
+	if intervals, err := strconv.Atoi(str); err == nil {
+		if intervals <= 0 {
+			return IdleThreshold{}, fmt.Errorf(
+				"Invalid idle threshold %q; number of intervals must be positive",
+				str,
+			)
+		}
+		return IdleThreshold{Intervals: intervals}, nil
+	}
+	duration, err := time.ParseDuration(str)
+	if err != nil {
+		return IdleThreshold{}, fmt.Errorf(
+			"Invalid idle threshold %q; expected a number of intervals or a "+
+				"duration",
+			str,
+		)
+	}
+	if duration <= 0 {
+		return IdleThreshold{}, fmt.Errorf(
+			"Invalid idle threshold %q; duration must be positive",
+			str,
+		)
+	}
+	return IdleThreshold{Duration: duration}, nil
+}
+
+// Reached returns true if an app that has been idle for the given number of
+// consecutive intervals, spanning the given wall-clock duration, has met the
+// threshold.
+func (i IdleThreshold) Reached(
+	idleIntervals int,
+	idleDuration time.Duration,
+) bool {
+	if i.Duration > 0 {
+		return idleDuration >= i.Duration
+	}
+	return idleIntervals >= i.Intervals
+}
+
+func (i IdleThreshold) String() string {
+	if i.Duration > 0 {
+		return i.Duration.String()
+	}
+	return fmt.Sprintf("%d interval(s)", i.Intervals)
+}
@@ -0,0 +1,100 @@
+package kubernetes
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseIdleThreshold(t *testing.T) {
+	testcases := []struct {
+		name           string
+		str            string
+		expectedResult IdleThreshold
+		expectError    bool
+	}{
+		{
+			name:           "number of intervals",
+			str:            "3",
+			expectedResult: IdleThreshold{Intervals: 3},
+		},
+		{
+			name:           "duration",
+			str:            "10m",
+			expectedResult: IdleThreshold{Duration: 10 * time.Minute},
+		},
+		{
+			name:        "zero intervals",
+			str:         "0",
+			expectError: true,
+		},
+		{
+			name:        "negative duration",
+			str:         "-5m",
+			expectError: true,
+		},
+		{
+			name:        "garbage",
+			str:         "something",
+			expectError: true,
+		},
+	}
+
+	for _, test := range testcases {
+		t.Run(test.name, func(t *testing.T) {
+			actual, err := ParseIdleThreshold(test.str)
+			if test.expectError {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, test.expectedResult, actual)
+		})
+	}
+}
+
+func TestIdleThresholdReached(t *testing.T) {
+	testcases := []struct {
+		name           string
+		threshold      IdleThreshold
+		idleIntervals  int
+		idleDuration   time.Duration
+		expectedResult bool
+	}{
+		{
+			name:           "too few intervals",
+			threshold:      IdleThreshold{Intervals: 3},
+			idleIntervals:  2,
+			idleDuration:   time.Hour,
+			expectedResult: false,
+		},
+		{
+			name:           "enough intervals",
+			threshold:      IdleThreshold{Intervals: 3},
+			idleIntervals:  3,
+			expectedResult: true,
+		},
+		{
+			name:           "too short a duration",
+			threshold:      IdleThreshold{Duration: 10 * time.Minute},
+			idleIntervals:  100,
+			idleDuration:   9 * time.Minute,
+			expectedResult: false,
+		},
+		{
+			name:           "long enough duration",
+			threshold:      IdleThreshold{Duration: 10 * time.Minute},
+			idleIntervals:  1,
+			idleDuration:   10 * time.Minute,
+			expectedResult: true,
+		},
+	}
+
+	for _, test := range testcases {
+		t.Run(test.name, func(t *testing.T) {
+			actual := test.threshold.Reached(test.idleIntervals, test.idleDuration)
+			assert.Equal(t, test.expectedResult, actual)
+		})
+	}
+}
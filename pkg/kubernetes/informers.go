@@ -2,12 +2,16 @@ package kubernetes
 
 import (
 	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/fields"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/tools/cache"
 )
@@ -46,6 +50,40 @@ func DeploymentsIndexInformer(
 	)
 }
 
+func StatefulSetsIndexInformer(
+	client kubernetes.Interface,
+	namespace string,
+	fieldSelector fields.Selector,
+	labelSelector labels.Selector,
+) cache.SharedIndexInformer {
+	statefulSetsClient := client.AppsV1().StatefulSets(namespace)
+	return cache.NewSharedIndexInformer(
+		&cache.ListWatch{
+			ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+				if fieldSelector != nil {
+					options.FieldSelector = fieldSelector.String()
+				}
+				if labelSelector != nil {
+					options.LabelSelector = labelSelector.String()
+				}
+				return statefulSetsClient.List(options)
+			},
+			WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+				if fieldSelector != nil {
+					options.FieldSelector = fieldSelector.String()
+				}
+				if labelSelector != nil {
+					options.LabelSelector = labelSelector.String()
+				}
+				return statefulSetsClient.Watch(options)
+			},
+		},
+		&appsv1.StatefulSet{},
+		0,
+		cache.Indexers{},
+	)
+}
+
 func PodsIndexInformer(
 	client kubernetes.Interface,
 	namespace string,
@@ -181,3 +219,152 @@ func NodesIndexInformer(
 		cache.Indexers{},
 	)
 }
+
+func NamespacesIndexInformer(
+	client kubernetes.Interface,
+	fieldSelector fields.Selector,
+	labelSelector labels.Selector,
+) cache.SharedIndexInformer {
+	namespacesClient := client.CoreV1().Namespaces()
+	return cache.NewSharedIndexInformer(
+		&cache.ListWatch{
+			ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+				if fieldSelector != nil {
+					options.FieldSelector = fieldSelector.String()
+				}
+				if labelSelector != nil {
+					options.LabelSelector = labelSelector.String()
+				}
+				return namespacesClient.List(options)
+			},
+			WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+				if fieldSelector != nil {
+					options.FieldSelector = fieldSelector.String()
+				}
+				if labelSelector != nil {
+					options.LabelSelector = labelSelector.String()
+				}
+				return namespacesClient.Watch(options)
+			},
+		},
+		&corev1.Namespace{},
+		0,
+		cache.Indexers{},
+	)
+}
+
+// HorizontalPodAutoscalersIndexInformer returns an informer for horizontal pod
+// autoscalers that indexes them by namespace.
+func HorizontalPodAutoscalersIndexInformer(
+	client kubernetes.Interface,
+	namespace string,
+	fieldSelector fields.Selector,
+	labelSelector labels.Selector,
+) cache.SharedIndexInformer {
+	hpasClient := client.AutoscalingV1().HorizontalPodAutoscalers(namespace)
+	return cache.NewSharedIndexInformer(
+		&cache.ListWatch{
+			ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+				if fieldSelector != nil {
+					options.FieldSelector = fieldSelector.String()
+				}
+				if labelSelector != nil {
+					options.LabelSelector = labelSelector.String()
+				}
+				return hpasClient.List(options)
+			},
+			WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+				if fieldSelector != nil {
+					options.FieldSelector = fieldSelector.String()
+				}
+				if labelSelector != nil {
+					options.LabelSelector = labelSelector.String()
+				}
+				return hpasClient.Watch(options)
+			},
+		},
+		&autoscalingv1.HorizontalPodAutoscaler{},
+		0,
+		cache.Indexers{
+			cache.NamespaceIndex: cache.MetaNamespaceIndexFunc,
+		},
+	)
+}
+
+// WorkloadsIndexInformer returns an informer for resources of an arbitrary
+// kind, e.g. custom resources that implement the scale subresource. Informed
+// objects are of type *unstructured.Unstructured.
+func WorkloadsIndexInformer(
+	client dynamic.Interface,
+	gvr schema.GroupVersionResource,
+	namespace string,
+	fieldSelector fields.Selector,
+	labelSelector labels.Selector,
+) cache.SharedIndexInformer {
+	workloadsClient := client.Resource(gvr).Namespace(namespace)
+	return cache.NewSharedIndexInformer(
+		&cache.ListWatch{
+			ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+				if fieldSelector != nil {
+					options.FieldSelector = fieldSelector.String()
+				}
+				if labelSelector != nil {
+					options.LabelSelector = labelSelector.String()
+				}
+				return workloadsClient.List(options)
+			},
+			WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+				if fieldSelector != nil {
+					options.FieldSelector = fieldSelector.String()
+				}
+				if labelSelector != nil {
+					options.LabelSelector = labelSelector.String()
+				}
+				return workloadsClient.Watch(options)
+			},
+		},
+		&unstructured.Unstructured{},
+		0,
+		cache.Indexers{},
+	)
+}
+
+// ScaleToZeroPoliciesIndexInformer returns an informer for scale to zero
+// policies that indexes them by namespace. Informed objects are of type
+// *unstructured.Unstructured.
+func ScaleToZeroPoliciesIndexInformer(
+	client dynamic.Interface,
+	namespace string,
+	fieldSelector fields.Selector,
+	labelSelector labels.Selector,
+) cache.SharedIndexInformer {
+	policiesClient := client.Resource(ScaleToZeroPolicyResource).
+		Namespace(namespace)
+	return cache.NewSharedIndexInformer(
+		&cache.ListWatch{
+			ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+				if fieldSelector != nil {
+					options.FieldSelector = fieldSelector.String()
+				}
+				if labelSelector != nil {
+					options.LabelSelector = labelSelector.String()
+				}
+				return policiesClient.List(options)
+			},
+			WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+				if fieldSelector != nil {
+					options.FieldSelector = fieldSelector.String()
+				}
+				if labelSelector != nil {
+					options.LabelSelector = labelSelector.String()
+				}
+				return policiesClient.Watch(options)
+			},
+		},
+		&unstructured.Unstructured{},
+		0,
+		cache.Indexers{
+			cache.NamespaceIndex: cache.MetaNamespaceIndexFunc,
+		},
+	)
+}
@@ -0,0 +1,111 @@
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/golang/glog"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+)
+
+// LeaderElectionConfig represents configuration options for leader election
+// amongst replicas of a component. It is meant to be embedded in the
+// configuration of any such component.
+// nolint: lll
+type LeaderElectionConfig struct {
+	LeaderElectionEnabled       bool          `envconfig:"LEADER_ELECTION_ENABLED"`
+	LeaderElectionNamespace     string        `envconfig:"LEADER_ELECTION_NAMESPACE"`
+	LeaderElectionLeaseName     string        `envconfig:"LEADER_ELECTION_LEASE_NAME"`
+	LeaderElectionLeaseDuration time.Duration `envconfig:"LEADER_ELECTION_LEASE_DURATION"`
+	LeaderElectionRenewDeadline time.Duration `envconfig:"LEADER_ELECTION_RENEW_DEADLINE"`
+	LeaderElectionRetryPeriod   time.Duration `envconfig:"LEADER_ELECTION_RETRY_PERIOD"`
+}
+
+// NewLeaderElectionConfigWithDefaults returns a LeaderElectionConfig object
+// with default values already applied. Leader election is disabled by default.
+func NewLeaderElectionConfigWithDefaults(
+	leaseName string,
+) LeaderElectionConfig {
+	return LeaderElectionConfig{
+		LeaderElectionLeaseName:     leaseName,
+		LeaderElectionLeaseDuration: 15 * time.Second,
+		LeaderElectionRenewDeadline: 10 * time.Second,
+		LeaderElectionRetryPeriod:   2 * time.Second,
+	}
+}
+
+// RunLeaderElection campaigns, for as long as the given context remains
+// unexpired and uncanceled, for leadership amongst all replicas of a
+// component. Each time leadership is acquired, the lead function is invoked
+// with a context that is canceled when leadership is lost. If leader election
+// is disabled, the lead function is invoked immediately. This function will
+// not return until the context it has been passed expires or is canceled.
+func RunLeaderElection(
+	ctx context.Context,
+	kubeClient kubernetes.Interface,
+	cfg LeaderElectionConfig,
+	lead func(ctx context.Context),
+) error {
+	if !cfg.LeaderElectionEnabled {
+		lead(ctx)
+		return nil
+	}
+	if cfg.LeaderElectionNamespace == "" {
+		return fmt.Errorf("No namespace was specified for leader election")
+	}
+	identity, err := os.Hostname()
+	if err != nil {
+		return fmt.Errorf("Error determining leader election identity: %s", err)
+	}
+	lock := &leaseLock{
+		leaseMeta: metav1.ObjectMeta{
+			Namespace: cfg.LeaderElectionNamespace,
+			Name:      cfg.LeaderElectionLeaseName,
+		},
+		client: kubeClient.CoordinationV1beta1(),
+		lockConfig: resourcelock.ResourceLockConfig{
+			Identity: identity,
+			EventRecorder: NewEventRecorder(
+				kubeClient,
+				cfg.LeaderElectionLeaseName,
+			),
+		},
+	}
+	for {
+		elector, err := leaderelection.NewLeaderElector(
+			leaderelection.LeaderElectionConfig{
+				Lock:          lock,
+				LeaseDuration: cfg.LeaderElectionLeaseDuration,
+				RenewDeadline: cfg.LeaderElectionRenewDeadline,
+				RetryPeriod:   cfg.LeaderElectionRetryPeriod,
+				Callbacks: leaderelection.LeaderCallbacks{
+					OnStartedLeading: func(ctx context.Context) {
+						glog.Infof("%s became the leader", identity)
+						lead(ctx)
+					},
+					OnStoppedLeading: func() {
+						glog.Infof("%s is not the leader", identity)
+					},
+					OnNewLeader: func(leader string) {
+						glog.Infof("The leader is %s", leader)
+					},
+				},
+			},
+		)
+		if err != nil {
+			return err
+		}
+		// This returns when leadership is lost or the context is canceled
+		elector.Run(ctx)
+		select {
+		case <-ctx.Done():
+			return nil
+		default:
+		}
+	}
+}
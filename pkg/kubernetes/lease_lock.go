@@ -0,0 +1,124 @@
+package kubernetes
+
+import (
+	"errors"
+	"fmt"
+
+	coordinationv1beta1 "k8s.io/api/coordination/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	coordinationclientv1beta1 "k8s.io/client-go/kubernetes/typed/coordination/v1beta1" // nolint: lll
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+)
+
+// leaseLock is a resourcelock.Interface that stores leader election records
+// in coordination.k8s.io Lease resources. The version of client-go that we
+// depend on only offers locks based on endpoints and config maps, both of
+// which generate considerably more noise for other watchers of those kinds
+// of resources.
+type leaseLock struct {
+	leaseMeta  metav1.ObjectMeta
+	client     coordinationclientv1beta1.LeasesGetter
+	lockConfig resourcelock.ResourceLockConfig
+	lease      *coordinationv1beta1.Lease
+}
+
+// Get returns the election record from the lease
+func (l *leaseLock) Get() (*resourcelock.LeaderElectionRecord, error) {
+	var err error
+	l.lease, err = l.client.Leases(l.leaseMeta.Namespace).Get(
+		l.leaseMeta.Name,
+		metav1.GetOptions{},
+	)
+	if err != nil {
+		return nil, err
+	}
+	return leaseSpecToLeaderElectionRecord(&l.lease.Spec), nil
+}
+
+// Create attempts to create a lease containing the given election record
+func (l *leaseLock) Create(ler resourcelock.LeaderElectionRecord) error {
+	var err error
+	l.lease, err = l.client.Leases(l.leaseMeta.Namespace).Create(
+		&coordinationv1beta1.Lease{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      l.leaseMeta.Name,
+				Namespace: l.leaseMeta.Namespace,
+			},
+			Spec: leaderElectionRecordToLeaseSpec(&ler),
+		},
+	)
+	return err
+}
+
+// Update updates the existing lease with the given election record
+func (l *leaseLock) Update(ler resourcelock.LeaderElectionRecord) error {
+	if l.lease == nil {
+		return errors.New("lease not initialized, call get or create first")
+	}
+	l.lease.Spec = leaderElectionRecordToLeaseSpec(&ler)
+	var err error
+	l.lease, err = l.client.Leases(l.leaseMeta.Namespace).Update(l.lease)
+	return err
+}
+
+// RecordEvent records an event concerning the lease
+func (l *leaseLock) RecordEvent(s string) {
+	if l.lockConfig.EventRecorder == nil || l.lease == nil {
+		return
+	}
+	l.lockConfig.EventRecorder.Eventf(
+		&coordinationv1beta1.Lease{ObjectMeta: l.lease.ObjectMeta},
+		corev1.EventTypeNormal,
+		"LeaderElection",
+		"%s %s",
+		l.lockConfig.Identity,
+		s,
+	)
+}
+
+// Describe returns a string that identifies the lease
+func (l *leaseLock) Describe() string {
+	return fmt.Sprintf("%s/%s", l.leaseMeta.Namespace, l.leaseMeta.Name)
+}
+
+// Identity returns the identity of the candidate using this lock
+func (l *leaseLock) Identity() string {
+	return l.lockConfig.Identity
+}
+
+func leaseSpecToLeaderElectionRecord(
+	spec *coordinationv1beta1.LeaseSpec,
+) *resourcelock.LeaderElectionRecord {
+	ler := &resourcelock.LeaderElectionRecord{}
+	if spec.HolderIdentity != nil {
+		ler.HolderIdentity = *spec.HolderIdentity
+	}
+	if spec.LeaseDurationSeconds != nil {
+		ler.LeaseDurationSeconds = int(*spec.LeaseDurationSeconds)
+	}
+	if spec.LeaseTransitions != nil {
+		ler.LeaderTransitions = int(*spec.LeaseTransitions)
+	}
+	if spec.AcquireTime != nil {
+		ler.AcquireTime = metav1.Time{Time: spec.AcquireTime.Time}
+	}
+	if spec.RenewTime != nil {
+		ler.RenewTime = metav1.Time{Time: spec.RenewTime.Time}
+	}
+	return ler
+}
+
+func leaderElectionRecordToLeaseSpec(
+	ler *resourcelock.LeaderElectionRecord,
+) coordinationv1beta1.LeaseSpec {
+	leaseDurationSeconds := int32(ler.LeaseDurationSeconds)
+	leaseTransitions := int32(ler.LeaderTransitions)
+	return coordinationv1beta1.LeaseSpec{
+		HolderIdentity:       &ler.HolderIdentity,
+		LeaseDurationSeconds: &leaseDurationSeconds,
+		AcquireTime:          &metav1.MicroTime{Time: ler.AcquireTime.Time},
+		RenewTime:            &metav1.MicroTime{Time: ler.RenewTime.Time},
+		LeaseTransitions:     &leaseTransitions,
+	}
+}
@@ -0,0 +1,26 @@
+package kubernetes
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+)
+
+func TestLeaderElectionRecordLeaseSpecRoundTrip(t *testing.T) {
+	now := time.Now()
+	ler := resourcelock.LeaderElectionRecord{
+		HolderIdentity:       "osiris-zeroscaler-abcde",
+		LeaseDurationSeconds: 15,
+		AcquireTime:          metav1.NewTime(now.Add(-time.Minute)),
+		RenewTime:            metav1.NewTime(now),
+		LeaderTransitions:    3,
+	}
+	spec := leaderElectionRecordToLeaseSpec(&ler)
+	assert.Equal(t, "osiris-zeroscaler-abcde", *spec.HolderIdentity)
+	assert.Equal(t, int32(15), *spec.LeaseDurationSeconds)
+	assert.Equal(t, int32(3), *spec.LeaseTransitions)
+	assert.Equal(t, ler, *leaseSpecToLeaderElectionRecord(&spec))
+}
@@ -0,0 +1,110 @@
+package kubernetes
+
+import (
+	"github.com/golang/glog"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+)
+
+// namespaceDefaultAnnotationNames are the names of the annotations that, when
+// set on a namespace, supply default values for the resources in it
+var namespaceDefaultAnnotationNames = []string{
+	IgnoredPathsAnnotationName,
+	MetricsCheckIntervalAnnotationName,
+	MinReplicasAnnotationName,
+}
+
+// NamespaceIsOsirisEnabled checks the labels and annotations of a namespace to
+// see if Osiris is enabled by default for the resources in it.
+func NamespaceIsOsirisEnabled(namespace *corev1.Namespace) bool {
+	return ResourceIsOsirisEnabled(namespace.Labels) ||
+		ResourceIsOsirisEnabled(namespace.Annotations)
+}
+
+// ApplyNamespaceDefaults returns a copy of a resource's annotations,
+// supplemented by any defaults set on the resource's namespace. If Osiris is
+// enabled for the namespace, it is enabled for the resource, too. Annotations
+// set on the resource itself always take precedence, so a resource can, for
+// instance, opt out of Osiris in an Osiris-enabled namespace. The namespace
+// may be nil, in which case there are no defaults to apply.
+func ApplyNamespaceDefaults(
+	annotations map[string]string,
+	namespace *corev1.Namespace,
+) map[string]string {
+	merged := map[string]string{}
+	if namespace != nil {
+		if NamespaceIsOsirisEnabled(namespace) {
+			merged[osirisEnabledAnnotationName] = "true"
+		}
+		for _, name := range namespaceDefaultAnnotationNames {
+			if val, ok := namespace.Annotations[name]; ok {
+				merged[name] = val
+			}
+		}
+	}
+	for key, val := range annotations {
+		merged[key] = val
+	}
+	return merged
+}
+
+// ApplyNamespaceDefaultsToService is like ApplyNamespaceDefaults, except that
+// Osiris being enabled for the namespace only enables it for a service that
+// refers to the workload behind it. Osiris can't manage a service without
+// knowing that workload.
+func ApplyNamespaceDefaultsToService(
+	svc *corev1.Service,
+	namespace *corev1.Namespace,
+) map[string]string {
+	return withoutImpliedServiceEnablement(
+		svc,
+		ApplyNamespaceDefaults(svc.Annotations, namespace),
+	)
+}
+
+// withoutImpliedServiceEnablement removes the enablement of Osiris from a
+// service's effective annotations if the service neither enables Osiris
+// explicitly nor refers to the workload behind it
+func withoutImpliedServiceEnablement(
+	svc *corev1.Service,
+	annotations map[string]string,
+) map[string]string {
+	if _, ok := svc.Annotations[osirisEnabledAnnotationName]; !ok {
+		// An invalid reference is still treated as a reference, so that the
+		// problem with it is reported instead of being ignored
+		if _, ok, err := GetServiceWorkloadReference(svc.Annotations); !ok &&
+			err == nil {
+			delete(annotations, osirisEnabledAnnotationName)
+		}
+	}
+	return annotations
+}
+
+// NamespaceDefaults provides access to the defaults set on namespaces. It
+// relies on an informer to keep track of namespaces. That informer must be run
+// by the caller.
+type NamespaceDefaults struct {
+	cache.SharedIndexInformer
+}
+
+// NewNamespaceDefaults returns a NamespaceDefaults backed by an informer for
+// all namespaces.
+func NewNamespaceDefaults(client kubernetes.Interface) *NamespaceDefaults {
+	return &NamespaceDefaults{
+		SharedIndexInformer: NamespacesIndexInformer(client, nil, nil),
+	}
+}
+
+// getNamespace returns the named namespace, or nil if it isn't known
+func (n *NamespaceDefaults) getNamespace(name string) *corev1.Namespace {
+	obj, ok, err := n.GetStore().GetByKey(name)
+	if err != nil {
+		glog.Errorf("Error getting namespace %s: %s", name, err)
+		return nil
+	}
+	if !ok {
+		return nil
+	}
+	return obj.(*corev1.Namespace)
+}
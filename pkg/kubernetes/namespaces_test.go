@@ -0,0 +1,148 @@
+package kubernetes
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestApplyNamespaceDefaults(t *testing.T) {
+	testcases := []struct {
+		name                string
+		annotations         map[string]string
+		namespace           *corev1.Namespace
+		expectedAnnotations map[string]string
+	}{
+		{
+			name: "unknown namespace",
+			annotations: map[string]string{
+				MinReplicasAnnotationName: "2",
+			},
+			expectedAnnotations: map[string]string{
+				MinReplicasAnnotationName: "2",
+			},
+		},
+		{
+			name:        "namespace enabled by label",
+			annotations: nil,
+			namespace: &corev1.Namespace{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: map[string]string{
+						osirisEnabledAnnotationName: "true",
+					},
+				},
+			},
+			expectedAnnotations: map[string]string{
+				osirisEnabledAnnotationName: "true",
+			},
+		},
+		{
+			name: "namespace enabled by annotation, with defaults",
+			annotations: map[string]string{
+				MinReplicasAnnotationName: "2",
+			},
+			namespace: &corev1.Namespace{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{
+						osirisEnabledAnnotationName:        "yes",
+						MetricsCheckIntervalAnnotationName: "60",
+						MinReplicasAnnotationName:          "3",
+						IgnoredPathsAnnotationName:         "/healthz",
+						DryRunAnnotationName:               "true",
+					},
+				},
+			},
+			expectedAnnotations: map[string]string{
+				osirisEnabledAnnotationName:        "true",
+				MetricsCheckIntervalAnnotationName: "60",
+				MinReplicasAnnotationName:          "2",
+				IgnoredPathsAnnotationName:         "/healthz",
+			},
+		},
+		{
+			name: "resource opted out of enabled namespace",
+			annotations: map[string]string{
+				osirisEnabledAnnotationName: "false",
+			},
+			namespace: &corev1.Namespace{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: map[string]string{
+						osirisEnabledAnnotationName: "true",
+					},
+				},
+			},
+			expectedAnnotations: map[string]string{
+				osirisEnabledAnnotationName: "false",
+			},
+		},
+	}
+	for _, test := range testcases {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(
+				t,
+				test.expectedAnnotations,
+				ApplyNamespaceDefaults(test.annotations, test.namespace),
+			)
+		})
+	}
+}
+
+func TestApplyNamespaceDefaultsToService(t *testing.T) {
+	enabledNamespace := &corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{
+			Labels: map[string]string{
+				osirisEnabledAnnotationName: "true",
+			},
+		},
+	}
+	testcases := []struct {
+		name            string
+		annotations     map[string]string
+		expectedEnabled bool
+	}{
+		{
+			name:            "service without a workload reference",
+			annotations:     map[string]string{},
+			expectedEnabled: false,
+		},
+		{
+			name: "service with a workload reference",
+			annotations: map[string]string{
+				"osiris.deislabs.io/deployment": "my-app",
+			},
+			expectedEnabled: true,
+		},
+		{
+			name: "service with an invalid workload reference",
+			annotations: map[string]string{
+				"osiris.deislabs.io/workload": "my-app",
+			},
+			expectedEnabled: true,
+		},
+		{
+			name: "explicitly enabled service without a workload reference",
+			annotations: map[string]string{
+				osirisEnabledAnnotationName: "true",
+			},
+			expectedEnabled: true,
+		},
+	}
+	for _, test := range testcases {
+		t.Run(test.name, func(t *testing.T) {
+			svc := &corev1.Service{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: test.annotations,
+				},
+			}
+			assert.Equal(
+				t,
+				test.expectedEnabled,
+				ResourceIsOsirisEnabled(
+					ApplyNamespaceDefaultsToService(svc, enabledNamespace),
+				),
+			)
+		})
+	}
+}
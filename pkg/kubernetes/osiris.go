@@ -3,12 +3,51 @@ package kubernetes
 import (
 	"strconv"
 	"strings"
+	"time"
 )
 
+// nolint: lll
 const (
-	IgnoredPathsAnnotationName         = "osiris.deislabs.io/ignoredPaths"
-	MetricsCheckIntervalAnnotationName = "osiris.deislabs.io/metricsCheckInterval"
-	osirisEnabledAnnotationName        = "osiris.deislabs.io/enabled"
+	ActivationReplicasAnnotationName            = "osiris.deislabs.io/activationReplicas"
+	ActivationTimeoutAnnotationName             = "osiris.deislabs.io/activationTimeout"
+	ActivitySourceAnnotationName                = "osiris.deislabs.io/activitySource"
+	DryRunAnnotationName                        = "osiris.deislabs.io/dryRun"
+	DryRunScaleToZeroAnnotationName             = "osiris.deislabs.io/dryRunScaleToZero"
+	IdleThresholdAnnotationName                 = "osiris.deislabs.io/idleThreshold"
+	IdleStateAnnotationName                     = "osiris.deislabs.io/idleState"
+	IgnoredPathsAnnotationName                  = "osiris.deislabs.io/ignoredPaths"
+	IngressHostnameAnnotationName               = "osiris.deislabs.io/ingressHostname"
+	KeepWarmUntilAnnotationName                 = "osiris.deislabs.io/keepWarmUntil"
+	LastActivationAnnotationName                = "osiris.deislabs.io/lastActivation"
+	LastActivationDurationAnnotationName        = "osiris.deislabs.io/lastActivationDuration"
+	LastActivityAnnotationName                  = "osiris.deislabs.io/lastActivity"
+	LastScaledToZeroAnnotationName              = "osiris.deislabs.io/lastScaledToZero"
+	LastScaledToZeroReasonAnnotationName        = "osiris.deislabs.io/lastScaledToZeroReason"
+	LoadBalancerHostnameAnnotationName          = "osiris.deislabs.io/loadBalancerHostname"
+	MetricsCheckIntervalAnnotationName          = "osiris.deislabs.io/metricsCheckInterval"
+	MinReplicasAnnotationName                   = "osiris.deislabs.io/minReplicas"
+	OpenConnectionQuietPeriodAnnotationName     = "osiris.deislabs.io/openConnectionQuietPeriod"
+	PreScaleDownHookFailurePolicyAnnotationName = "osiris.deislabs.io/preScaleDownHookFailurePolicy"
+	PreScaleDownHookPathAnnotationName          = "osiris.deislabs.io/preScaleDownHookPath"
+	PreScaleDownHookPortAnnotationName          = "osiris.deislabs.io/preScaleDownHookPort"
+	PreScaleDownHookTimeoutAnnotationName       = "osiris.deislabs.io/preScaleDownHookTimeout"
+	PreviousReplicasAnnotationName              = "osiris.deislabs.io/previousReplicas"
+	PrometheusMetricAnnotationName              = "osiris.deislabs.io/prometheusMetric"
+	PrometheusMetricsPathAnnotationName         = "osiris.deislabs.io/prometheusMetricsPath"
+	PrometheusMetricsPortAnnotationName         = "osiris.deislabs.io/prometheusMetricsPort"
+	WakingUpPageAnnotationName                  = "osiris.deislabs.io/wakingUpPage"
+	osirisEnabledAnnotationName                 = "osiris.deislabs.io/enabled"
+)
+
+// Possible values of the ActivationReplicasAnnotationName annotation
+const (
+	// ActivationReplicasMin indicates a workload should be activated with its
+	// minimum number of replicas
+	ActivationReplicasMin = "minReplicas"
+	// ActivationReplicasPrevious indicates a workload should be activated with
+	// the number of replicas it had before it was scaled to zero, or its
+	// minimum number of replicas, whichever is greater
+	ActivationReplicasPrevious = "previous"
 )
 
 // ResourceIsOsirisEnabled checks the annotations to see if the
@@ -26,11 +65,42 @@ func ResourceIsOsirisEnabled(annotations map[string]string) bool {
 	}
 }
 
+// WakingUpPageIsEnabled checks the annotations of a service to see if browsers
+// should be shown a waking up page while the workload behind it is activated,
+// instead of waiting for it.
+func WakingUpPageIsEnabled(annotations map[string]string) bool {
+	switch strings.ToLower(annotations[WakingUpPageAnnotationName]) {
+	case "y", "yes", "true", "on", "1":
+		return true
+	default:
+		return false
+	}
+}
+
+// GetDryRun checks the annotations to see if the kube resource is in dry-run
+// mode-- i.e. whether Osiris should only report what it would have done to the
+// resource instead of doing it. If the annotations do not indicate either way,
+// it returns the default value instead.
+func GetDryRun(annotations map[string]string, defaultVal bool) bool {
+	dryRun, ok := annotations[DryRunAnnotationName]
+	if !ok {
+		return defaultVal
+	}
+	switch strings.ToLower(dryRun) {
+	case "y", "yes", "true", "on", "1":
+		return true
+	case "n", "no", "false", "off", "0":
+		return false
+	default:
+		return defaultVal
+	}
+}
+
 // GetMinReplicas gets the minimum number of replicas required for scale up
 // from the annotations. If it fails to do so, it returns the default value
 // instead.
 func GetMinReplicas(annotations map[string]string, defaultVal int32) int32 {
-	val, ok := annotations["osiris.deislabs.io/minReplicas"]
+	val, ok := annotations[MinReplicasAnnotationName]
 	if !ok {
 		return defaultVal
 	}
@@ -40,3 +110,48 @@ func GetMinReplicas(annotations map[string]string, defaultVal int32) int32 {
 	}
 	return int32(minReplicas)
 }
+
+// GetActivationReplicas gets the number of replicas a workload should be
+// scaled to when it is activated from the annotations. By default, this is the
+// minimum number of replicas, but the annotations may opt into restoring the
+// number of replicas the workload had before it was scaled to zero. In all
+// cases, the minimum number of replicas is respected.
+func GetActivationReplicas(
+	annotations map[string]string,
+	defaultMinReplicas int32,
+) int32 {
+	replicas := GetMinReplicas(annotations, defaultMinReplicas)
+	if !strings.EqualFold(
+		annotations[ActivationReplicasAnnotationName],
+		ActivationReplicasPrevious,
+	) {
+		return replicas
+	}
+	previousReplicas, err :=
+		strconv.Atoi(annotations[PreviousReplicasAnnotationName])
+	if err != nil {
+		return replicas
+	}
+	if int32(previousReplicas) > replicas {
+		return int32(previousReplicas)
+	}
+	return replicas
+}
+
+// GetActivationTimeout gets, from the annotations, how long activating a
+// workload may take before requests waiting for it are turned away. If it
+// fails to do so, it returns the default value instead.
+func GetActivationTimeout(
+	annotations map[string]string,
+	defaultVal time.Duration,
+) time.Duration {
+	val, ok := annotations[ActivationTimeoutAnnotationName]
+	if !ok {
+		return defaultVal
+	}
+	timeout, err := time.ParseDuration(val)
+	if err != nil || timeout <= 0 {
+		return defaultVal
+	}
+	return timeout
+}
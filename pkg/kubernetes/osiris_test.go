@@ -2,6 +2,7 @@ package kubernetes
 
 import (
 	"testing"
+	"time"
 )
 
 func TestResourceIsOsirisEnabled(t *testing.T) {
@@ -73,6 +74,45 @@ func TestResourceIsOsirisEnabled(t *testing.T) {
 
 }
 
+func TestWakingUpPageIsEnabled(t *testing.T) {
+	testcases := []struct {
+		name           string
+		annotations    map[string]string
+		expectedResult bool
+	}{
+		{
+			name: "map with waking up page entry and value true",
+			annotations: map[string]string{
+				"osiris.deislabs.io/wakingUpPage": "true",
+			},
+			expectedResult: true,
+		},
+		{
+			name: "map with waking up page entry and value off",
+			annotations: map[string]string{
+				"osiris.deislabs.io/wakingUpPage": "off",
+			},
+			expectedResult: false,
+		},
+		{
+			name:           "map with no waking up page entry",
+			annotations:    map[string]string{},
+			expectedResult: false,
+		},
+	}
+
+	for _, test := range testcases {
+		t.Run(test.name, func(t *testing.T) {
+			actual := WakingUpPageIsEnabled(test.annotations)
+			if actual != test.expectedResult {
+				t.Errorf(
+					"expected WakingUpPageIsEnabled to return %t, but got %t",
+					test.expectedResult, actual)
+			}
+		})
+	}
+}
+
 func TestGetMinReplicas(t *testing.T) {
 	testcases := []struct {
 		name           string
@@ -113,3 +153,119 @@ func TestGetMinReplicas(t *testing.T) {
 		})
 	}
 }
+
+func TestGetActivationReplicas(t *testing.T) {
+	testcases := []struct {
+		name           string
+		annotations    map[string]string
+		expectedResult int32
+	}{
+		{
+			name: "map with no activation replicas entry",
+			annotations: map[string]string{
+				"osiris.deislabs.io/minReplicas":      "2",
+				"osiris.deislabs.io/previousReplicas": "4",
+			},
+			expectedResult: 2,
+		},
+		{
+			name: "map with min replicas activation replicas entry",
+			annotations: map[string]string{
+				"osiris.deislabs.io/activationReplicas": "minReplicas",
+				"osiris.deislabs.io/previousReplicas":   "4",
+			},
+			expectedResult: 1,
+		},
+		{
+			name: "map with previous activation replicas entry",
+			annotations: map[string]string{
+				"osiris.deislabs.io/activationReplicas": "previous",
+				"osiris.deislabs.io/previousReplicas":   "4",
+			},
+			expectedResult: 4,
+		},
+		{
+			name: "map with previous activation replicas entry and more min replicas",
+			annotations: map[string]string{
+				"osiris.deislabs.io/activationReplicas": "previous",
+				"osiris.deislabs.io/minReplicas":        "5",
+				"osiris.deislabs.io/previousReplicas":   "4",
+			},
+			expectedResult: 5,
+		},
+		{
+			name: "map with previous activation replicas entry and no previous " +
+				"replicas entry",
+			annotations: map[string]string{
+				"osiris.deislabs.io/activationReplicas": "previous",
+			},
+			expectedResult: 1,
+		},
+		{
+			name: "map with previous activation replicas entry and invalid " +
+				"previous replicas entry",
+			annotations: map[string]string{
+				"osiris.deislabs.io/activationReplicas": "previous",
+				"osiris.deislabs.io/previousReplicas":   "invalid",
+			},
+			expectedResult: 1,
+		},
+	}
+
+	for _, test := range testcases {
+		t.Run(test.name, func(t *testing.T) {
+			actual := GetActivationReplicas(test.annotations, 1)
+			if actual != test.expectedResult {
+				t.Errorf(
+					"expected GetActivationReplicas to return %d, but got %d",
+					test.expectedResult, actual)
+			}
+		})
+	}
+}
+
+func TestGetActivationTimeout(t *testing.T) {
+	testcases := []struct {
+		name           string
+		annotations    map[string]string
+		expectedResult time.Duration
+	}{
+		{
+			name: "map with activation timeout entry",
+			annotations: map[string]string{
+				"osiris.deislabs.io/activationTimeout": "5m",
+			},
+			expectedResult: 5 * time.Minute,
+		},
+		{
+			name:           "map with no activation timeout entry",
+			annotations:    map[string]string{},
+			expectedResult: 2 * time.Minute,
+		},
+		{
+			name: "map with invalid activation timeout entry",
+			annotations: map[string]string{
+				"osiris.deislabs.io/activationTimeout": "invalid",
+			},
+			expectedResult: 2 * time.Minute,
+		},
+		{
+			name: "map with negative activation timeout entry",
+			annotations: map[string]string{
+				"osiris.deislabs.io/activationTimeout": "-5m",
+			},
+			expectedResult: 2 * time.Minute,
+		},
+	}
+
+	for _, test := range testcases {
+		t.Run(test.name, func(t *testing.T) {
+			actual := GetActivationTimeout(test.annotations, 2*time.Minute)
+			if actual != test.expectedResult {
+				t.Errorf(
+					"expected GetActivationTimeout to return %s, but got %s",
+					test.expectedResult, actual)
+			}
+		})
+	}
+}
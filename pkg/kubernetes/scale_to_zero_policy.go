@@ -0,0 +1,448 @@
+package kubernetes
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/tools/cache"
+)
+
+// ScaleToZeroPolicyResource is the custom resource that scale to zero policies
+// are stored as
+var ScaleToZeroPolicyResource = schema.GroupVersionResource{
+	Group:    "osiris.deislabs.io",
+	Version:  "v1alpha1",
+	Resource: "scaletozeropolicies",
+}
+
+// ScaleToZeroPolicy configures Osiris for the workloads, pods, and services in
+// its namespace that its selector matches, as an alternative to annotating
+// each of them. Matching resources are Osiris-enabled unless they opt out, and
+// annotations set on them take precedence over the policy.
+type ScaleToZeroPolicy struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+	Spec              ScaleToZeroPolicySpec   `json:"spec"`
+	Status            ScaleToZeroPolicyStatus `json:"status,omitempty"`
+}
+
+// ScaleToZeroPolicySpec is the desired configuration of the resources that a
+// scale to zero policy matches. Each field, except the selector, corresponds
+// to an annotation.
+type ScaleToZeroPolicySpec struct {
+	// Selector selects the resources the policy applies to by label
+	Selector *metav1.LabelSelector `json:"selector"`
+	// MinReplicas corresponds to osiris.deislabs.io/minReplicas
+	MinReplicas *int32 `json:"minReplicas,omitempty"`
+	// ActivationReplicas corresponds to osiris.deislabs.io/activationReplicas
+	ActivationReplicas string `json:"activationReplicas,omitempty"`
+	// ActivationTimeout corresponds to osiris.deislabs.io/activationTimeout
+	ActivationTimeout string `json:"activationTimeout,omitempty"`
+	// MetricsCheckInterval corresponds to
+	// osiris.deislabs.io/metricsCheckInterval
+	MetricsCheckInterval *int32 `json:"metricsCheckInterval,omitempty"`
+	// IdleThreshold corresponds to osiris.deislabs.io/idleThreshold
+	IdleThreshold string `json:"idleThreshold,omitempty"`
+	// DryRun corresponds to osiris.deislabs.io/dryRun
+	DryRun *bool `json:"dryRun,omitempty"`
+	// IgnoredPaths corresponds to osiris.deislabs.io/ignoredPaths
+	IgnoredPaths []string `json:"ignoredPaths,omitempty"`
+	// LoadBalancerHostnames correspond to
+	// osiris.deislabs.io/loadBalancerHostname-<n>
+	LoadBalancerHostnames []string `json:"loadBalancerHostnames,omitempty"`
+	// IngressHostnames correspond to osiris.deislabs.io/ingressHostname-<n>
+	IngressHostnames []string `json:"ingressHostnames,omitempty"`
+}
+
+// ScaleToZeroPolicyStatus is the observed state of the workloads that a scale
+// to zero policy applies to.
+type ScaleToZeroPolicyStatus struct {
+	Workloads []ScaleToZeroPolicyWorkloadStatus `json:"workloads,omitempty"`
+}
+
+// ScaleToZeroPolicyWorkloadStatus is the observed state of a single workload
+// that a scale to zero policy applies to.
+type ScaleToZeroPolicyWorkloadStatus struct {
+	APIVersion       string `json:"apiVersion"`
+	Kind             string `json:"kind"`
+	Name             string `json:"name"`
+	Enabled          bool   `json:"enabled"`
+	Replicas         int32  `json:"replicas"`
+	LastActivity     string `json:"lastActivity,omitempty"`
+	LastScaledToZero string `json:"lastScaledToZero,omitempty"`
+}
+
+// ScaleToZeroPolicyFromUnstructured converts a scale to zero policy, as
+// informed by a dynamic client, to its typed representation and validates it.
+func ScaleToZeroPolicyFromUnstructured(
+	obj *unstructured.Unstructured,
+) (*ScaleToZeroPolicy, error) {
+	policy := &ScaleToZeroPolicy{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(
+		obj.Object,
+		policy,
+	); err != nil {
+		return nil, err
+	}
+	if err := policy.validate(); err != nil {
+		return nil, err
+	}
+	return policy, nil
+}
+
+// validate checks the constraints on a scale to zero policy's spec that the
+// API server can't be relied upon to have checked
+func (p *ScaleToZeroPolicy) validate() error {
+	if p.Spec.Selector == nil {
+		return errors.New("No selector specified")
+	}
+	if _, err := metav1.LabelSelectorAsSelector(p.Spec.Selector); err != nil {
+		return fmt.Errorf("Invalid selector: %s", err)
+	}
+	if p.Spec.MinReplicas != nil && *p.Spec.MinReplicas < 1 {
+		return fmt.Errorf("Invalid minReplicas %d", *p.Spec.MinReplicas)
+	}
+	if p.Spec.MetricsCheckInterval != nil && *p.Spec.MetricsCheckInterval < 1 {
+		return fmt.Errorf(
+			"Invalid metricsCheckInterval %d",
+			*p.Spec.MetricsCheckInterval,
+		)
+	}
+	switch p.Spec.ActivationReplicas {
+	case "", ActivationReplicasMin, ActivationReplicasPrevious:
+	default:
+		return fmt.Errorf(
+			"Invalid activationReplicas %q",
+			p.Spec.ActivationReplicas,
+		)
+	}
+	if p.Spec.IdleThreshold != "" {
+		if _, err := ParseIdleThreshold(p.Spec.IdleThreshold); err != nil {
+			return err
+		}
+	}
+	if p.Spec.ActivationTimeout != "" {
+		if timeout, err := time.ParseDuration(
+			p.Spec.ActivationTimeout,
+		); err != nil || timeout <= 0 {
+			return fmt.Errorf(
+				"Invalid activationTimeout %q",
+				p.Spec.ActivationTimeout,
+			)
+		}
+	}
+	return nil
+}
+
+// Matches returns true if the policy's selector matches the given labels.
+func (p *ScaleToZeroPolicy) Matches(lbls map[string]string) bool {
+	selector, err := metav1.LabelSelectorAsSelector(p.Spec.Selector)
+	if err != nil {
+		return false
+	}
+	return selector.Matches(labels.Set(lbls))
+}
+
+// annotations returns the annotations that are equivalent to the policy's spec
+func (p *ScaleToZeroPolicy) annotations() map[string]string {
+	annotations := map[string]string{
+		osirisEnabledAnnotationName: "true",
+	}
+	if p.Spec.MinReplicas != nil {
+		annotations[MinReplicasAnnotationName] =
+			strconv.Itoa(int(*p.Spec.MinReplicas))
+	}
+	if p.Spec.ActivationReplicas != "" {
+		annotations[ActivationReplicasAnnotationName] = p.Spec.ActivationReplicas
+	}
+	if p.Spec.ActivationTimeout != "" {
+		annotations[ActivationTimeoutAnnotationName] = p.Spec.ActivationTimeout
+	}
+	if p.Spec.MetricsCheckInterval != nil {
+		annotations[MetricsCheckIntervalAnnotationName] =
+			strconv.Itoa(int(*p.Spec.MetricsCheckInterval))
+	}
+	if p.Spec.IdleThreshold != "" {
+		annotations[IdleThresholdAnnotationName] = p.Spec.IdleThreshold
+	}
+	if p.Spec.DryRun != nil {
+		annotations[DryRunAnnotationName] = strconv.FormatBool(*p.Spec.DryRun)
+	}
+	if len(p.Spec.IgnoredPaths) > 0 {
+		annotations[IgnoredPathsAnnotationName] =
+			strings.Join(p.Spec.IgnoredPaths, ",")
+	}
+	for i, hostname := range p.Spec.LoadBalancerHostnames {
+		name := fmt.Sprintf("%s-%d", LoadBalancerHostnameAnnotationName, i+1)
+		annotations[name] = hostname
+	}
+	for i, hostname := range p.Spec.IngressHostnames {
+		name := fmt.Sprintf("%s-%d", IngressHostnameAnnotationName, i+1)
+		annotations[name] = hostname
+	}
+	return annotations
+}
+
+// ApplyScaleToZeroPolicy returns a copy of a resource's annotations,
+// supplemented by the configuration of the given scale to zero policy.
+// Annotations set on the resource itself always take precedence. Hostnames are
+// considered as a whole: if the resource sets any load balancer (or ingress)
+// hostname, none of the policy's load balancer (or ingress) hostnames apply.
+// The policy may be nil, in which case there is nothing to apply.
+func ApplyScaleToZeroPolicy(
+	annotations map[string]string,
+	policy *ScaleToZeroPolicy,
+) map[string]string {
+	merged := map[string]string{}
+	if policy != nil {
+		merged = policy.annotations()
+		for _, prefix := range []string{
+			LoadBalancerHostnameAnnotationName,
+			IngressHostnameAnnotationName,
+		} {
+			if hasAnnotationWithPrefix(annotations, prefix) {
+				for key := range merged {
+					if strings.HasPrefix(key, prefix) {
+						delete(merged, key)
+					}
+				}
+			}
+		}
+	}
+	for key, val := range annotations {
+		merged[key] = val
+	}
+	return merged
+}
+
+func hasAnnotationWithPrefix(
+	annotations map[string]string,
+	prefix string,
+) bool {
+	for key := range annotations {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// SelectScaleToZeroPolicy returns the policy, amongst the given ones, that
+// applies to a resource having the given labels, or nil if none does. If more
+// than one policy matches the resource, the oldest one applies.
+func SelectScaleToZeroPolicy(
+	policies []*ScaleToZeroPolicy,
+	lbls map[string]string,
+) *ScaleToZeroPolicy {
+	matches := []*ScaleToZeroPolicy{}
+	for _, policy := range policies {
+		if policy.Matches(lbls) {
+			matches = append(matches, policy)
+		}
+	}
+	if len(matches) == 0 {
+		return nil
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		iTime, jTime := matches[i].CreationTimestamp, matches[j].CreationTimestamp
+		if !iTime.Equal(&jTime) {
+			return iTime.Before(&jTime)
+		}
+		return matches[i].Name < matches[j].Name
+	})
+	return matches[0]
+}
+
+// ScaleToZeroPolicyResourceInstalled returns whether the API server serves
+// the scale to zero policy custom resource, i.e. whether its custom resource
+// definition is installed.
+func ScaleToZeroPolicyResourceInstalled(
+	client discovery.DiscoveryInterface,
+) (bool, error) {
+	resources, err := client.ServerResourcesForGroupVersion(
+		ScaleToZeroPolicyResource.GroupVersion().String(),
+	)
+	if apierrors.IsNotFound(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	for _, resource := range resources.APIResources {
+		if resource.Name == ScaleToZeroPolicyResource.Resource {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// ScaleToZeroPolicies provides access to the scale to zero policies in all
+// namespaces. It relies on an informer to keep track of them. That informer
+// must be run by the caller.
+type ScaleToZeroPolicies struct {
+	cache.SharedIndexInformer
+	client dynamic.Interface
+	// converted holds the typed representation of each policy in the
+	// informer's store, or the error converting it, by key
+	converted     map[string]convertedScaleToZeroPolicy
+	convertedLock sync.RWMutex
+}
+
+// convertedScaleToZeroPolicy is the outcome of converting a single version of
+// a scale to zero policy to its typed representation
+type convertedScaleToZeroPolicy struct {
+	resourceVersion string
+	policy          *ScaleToZeroPolicy
+	err             error
+}
+
+// NewScaleToZeroPolicies returns a ScaleToZeroPolicies backed by an informer
+// for the scale to zero policies in all namespaces. Policies are converted,
+// and validated, as the informer is notified of them.
+func NewScaleToZeroPolicies(client dynamic.Interface) *ScaleToZeroPolicies {
+	s := &ScaleToZeroPolicies{
+		SharedIndexInformer: ScaleToZeroPoliciesIndexInformer(
+			client,
+			metav1.NamespaceAll,
+			nil,
+			nil,
+		),
+		client:    client,
+		converted: map[string]convertedScaleToZeroPolicy{},
+	}
+	s.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			s.convert(obj.(*unstructured.Unstructured))
+		},
+		UpdateFunc: func(_, newObj interface{}) {
+			s.convert(newObj.(*unstructured.Unstructured))
+		},
+		DeleteFunc: func(obj interface{}) {
+			key, err := cache.DeletionHandlingMetaNamespaceKeyFunc(obj)
+			if err != nil {
+				return
+			}
+			s.convertedLock.Lock()
+			defer s.convertedLock.Unlock()
+			delete(s.converted, key)
+		},
+	})
+	return s
+}
+
+// convert returns the typed representation of the given scale to zero policy.
+// Each version of a policy is only converted, and validated, once. Invalid
+// policies are logged.
+func (s *ScaleToZeroPolicies) convert(
+	u *unstructured.Unstructured,
+) (*ScaleToZeroPolicy, error) {
+	key, err := cache.MetaNamespaceKeyFunc(u)
+	if err != nil {
+		return nil, err
+	}
+	s.convertedLock.RLock()
+	converted, ok := s.converted[key]
+	s.convertedLock.RUnlock()
+	// The informer may not have notified us of this version of the policy yet
+	if ok && converted.resourceVersion == u.GetResourceVersion() {
+		return converted.policy, converted.err
+	}
+	policy, err := ScaleToZeroPolicyFromUnstructured(u)
+	if err != nil {
+		glog.Errorf(
+			"Ignoring invalid scale to zero policy %s in namespace %s: %s",
+			u.GetName(),
+			u.GetNamespace(),
+			err,
+		)
+	}
+	s.convertedLock.Lock()
+	defer s.convertedLock.Unlock()
+	if s.converted == nil {
+		s.converted = map[string]convertedScaleToZeroPolicy{}
+	}
+	s.converted[key] = convertedScaleToZeroPolicy{
+		resourceVersion: u.GetResourceVersion(),
+		policy:          policy,
+		err:             err,
+	}
+	return policy, err
+}
+
+// List returns all the valid scale to zero policies in the given namespace.
+// Invalid policies are ignored.
+func (s *ScaleToZeroPolicies) List(namespace string) []*ScaleToZeroPolicy {
+	objs, err := s.GetIndexer().ByIndex(cache.NamespaceIndex, namespace)
+	if err != nil {
+		glog.Errorf(
+			"Error listing scale to zero policies in namespace %s: %s",
+			namespace,
+			err,
+		)
+		return nil
+	}
+	policies := []*ScaleToZeroPolicy{}
+	for _, obj := range objs {
+		if policy, err := s.convert(obj.(*unstructured.Unstructured)); err == nil {
+			policies = append(policies, policy)
+		}
+	}
+	return policies
+}
+
+// Select returns the scale to zero policy that applies to a resource in the
+// given namespace and having the given labels, or nil if none does.
+func (s *ScaleToZeroPolicies) Select(
+	namespace string,
+	lbls map[string]string,
+) *ScaleToZeroPolicy {
+	return SelectScaleToZeroPolicy(s.List(namespace), lbls)
+}
+
+// UpdateStatus replaces the status of the given scale to zero policy.
+func (s *ScaleToZeroPolicies) UpdateStatus(
+	policy *ScaleToZeroPolicy,
+	status ScaleToZeroPolicyStatus,
+) error {
+	key, err := cache.MetaNamespaceKeyFunc(policy)
+	if err != nil {
+		return err
+	}
+	obj, ok, err := s.GetStore().GetByKey(key)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("Scale to zero policy %s not found", key)
+	}
+	rawStatus, err := runtime.DefaultUnstructuredConverter.ToUnstructured(
+		&status,
+	)
+	if err != nil {
+		return err
+	}
+	// Objects in the informer's store must not be modified
+	u := obj.(*unstructured.Unstructured).DeepCopy()
+	if err = unstructured.SetNestedField(u.Object, rawStatus, "status"); err !=
+		nil {
+		return err
+	}
+	_, err = s.client.Resource(ScaleToZeroPolicyResource).
+		Namespace(policy.Namespace).
+		UpdateStatus(u, metav1.UpdateOptions{})
+	return err
+}
@@ -0,0 +1,257 @@
+package kubernetes
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/tools/cache"
+)
+
+func TestScaleToZeroPolicyFromUnstructured(t *testing.T) {
+	testcases := []struct {
+		name        string
+		spec        map[string]interface{}
+		expectedErr bool
+	}{
+		{
+			name: "valid policy",
+			spec: map[string]interface{}{
+				"selector": map[string]interface{}{
+					"matchLabels": map[string]interface{}{
+						"app": "my-app",
+					},
+				},
+				"minReplicas":  int64(2),
+				"ignoredPaths": []interface{}{"/healthz"},
+			},
+		},
+		{
+			name: "no selector",
+			spec: map[string]interface{}{
+				"minReplicas": int64(2),
+			},
+			expectedErr: true,
+		},
+		{
+			name: "invalid minReplicas",
+			spec: map[string]interface{}{
+				"selector":    map[string]interface{}{},
+				"minReplicas": int64(0),
+			},
+			expectedErr: true,
+		},
+		{
+			name: "invalid activationReplicas",
+			spec: map[string]interface{}{
+				"selector":           map[string]interface{}{},
+				"activationReplicas": "all",
+			},
+			expectedErr: true,
+		},
+		{
+			name: "invalid activationTimeout",
+			spec: map[string]interface{}{
+				"selector":          map[string]interface{}{},
+				"activationTimeout": "forever",
+			},
+			expectedErr: true,
+		},
+		{
+			name: "invalid idleThreshold",
+			spec: map[string]interface{}{
+				"selector":      map[string]interface{}{},
+				"idleThreshold": "0",
+			},
+			expectedErr: true,
+		},
+	}
+	for _, test := range testcases {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := ScaleToZeroPolicyFromUnstructured(&unstructured.Unstructured{
+				Object: map[string]interface{}{
+					"apiVersion": "osiris.deislabs.io/v1alpha1",
+					"kind":       "ScaleToZeroPolicy",
+					"metadata": map[string]interface{}{
+						"name": "my-policy",
+					},
+					"spec": test.spec,
+				},
+			})
+			if test.expectedErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestApplyScaleToZeroPolicy(t *testing.T) {
+	minReplicas := int32(2)
+	policy := &ScaleToZeroPolicy{
+		Spec: ScaleToZeroPolicySpec{
+			Selector:              &metav1.LabelSelector{},
+			MinReplicas:           &minReplicas,
+			IgnoredPaths:          []string{"/healthz", "/metrics"},
+			LoadBalancerHostnames: []string{"a.example.com", "b.example.com"},
+			IngressHostnames:      []string{"c.example.com"},
+		},
+	}
+	testcases := []struct {
+		name                string
+		annotations         map[string]string
+		policy              *ScaleToZeroPolicy
+		expectedAnnotations map[string]string
+	}{
+		{
+			name: "no policy",
+			annotations: map[string]string{
+				MinReplicasAnnotationName: "3",
+			},
+			expectedAnnotations: map[string]string{
+				MinReplicasAnnotationName: "3",
+			},
+		},
+		{
+			name:        "policy",
+			annotations: nil,
+			policy:      policy,
+			expectedAnnotations: map[string]string{
+				osirisEnabledAnnotationName:               "true",
+				MinReplicasAnnotationName:                 "2",
+				IgnoredPathsAnnotationName:                "/healthz,/metrics",
+				LoadBalancerHostnameAnnotationName + "-1": "a.example.com",
+				LoadBalancerHostnameAnnotationName + "-2": "b.example.com",
+				IngressHostnameAnnotationName + "-1":      "c.example.com",
+			},
+		},
+		{
+			name: "annotations overriding policy",
+			annotations: map[string]string{
+				osirisEnabledAnnotationName:        "false",
+				MinReplicasAnnotationName:          "3",
+				LoadBalancerHostnameAnnotationName: "d.example.com",
+			},
+			policy: policy,
+			expectedAnnotations: map[string]string{
+				osirisEnabledAnnotationName:          "false",
+				MinReplicasAnnotationName:            "3",
+				IgnoredPathsAnnotationName:           "/healthz,/metrics",
+				LoadBalancerHostnameAnnotationName:   "d.example.com",
+				IngressHostnameAnnotationName + "-1": "c.example.com",
+			},
+		},
+	}
+	for _, test := range testcases {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(
+				t,
+				test.expectedAnnotations,
+				ApplyScaleToZeroPolicy(test.annotations, test.policy),
+			)
+		})
+	}
+}
+
+func TestSelectScaleToZeroPolicy(t *testing.T) {
+	now := time.Now()
+	newPolicy := func(
+		name string,
+		created time.Time,
+		matchLabels map[string]string,
+	) *ScaleToZeroPolicy {
+		return &ScaleToZeroPolicy{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              name,
+				CreationTimestamp: metav1.NewTime(created),
+			},
+			Spec: ScaleToZeroPolicySpec{
+				Selector: &metav1.LabelSelector{
+					MatchLabels: matchLabels,
+				},
+			},
+		}
+	}
+	policies := []*ScaleToZeroPolicy{
+		newPolicy("newer", now, map[string]string{"tier": "dev"}),
+		newPolicy("older", now.Add(-time.Hour), map[string]string{"tier": "dev"}),
+		newPolicy("other", now.Add(-2*time.Hour), map[string]string{"tier": "qa"}),
+	}
+	testcases := []struct {
+		name           string
+		labels         map[string]string
+		expectedPolicy string
+	}{
+		{
+			name:   "no matching policy",
+			labels: map[string]string{"tier": "prod"},
+		},
+		{
+			name:           "one matching policy",
+			labels:         map[string]string{"tier": "qa"},
+			expectedPolicy: "other",
+		},
+		{
+			name:           "several matching policies",
+			labels:         map[string]string{"tier": "dev"},
+			expectedPolicy: "older",
+		},
+	}
+	for _, test := range testcases {
+		t.Run(test.name, func(t *testing.T) {
+			policy := SelectScaleToZeroPolicy(policies, test.labels)
+			if test.expectedPolicy == "" {
+				assert.Nil(t, policy)
+			} else if assert.NotNil(t, policy) {
+				assert.Equal(t, test.expectedPolicy, policy.Name)
+			}
+		})
+	}
+}
+
+func TestScaleToZeroPoliciesList(t *testing.T) {
+	newPolicy := func(resourceVersion string, minReplicas int64) interface{} {
+		return &unstructured.Unstructured{
+			Object: map[string]interface{}{
+				"apiVersion": "osiris.deislabs.io/v1alpha1",
+				"kind":       "ScaleToZeroPolicy",
+				"metadata": map[string]interface{}{
+					"namespace":       "default",
+					"name":            "my-policy",
+					"resourceVersion": resourceVersion,
+				},
+				"spec": map[string]interface{}{
+					"selector":    map[string]interface{}{},
+					"minReplicas": minReplicas,
+				},
+			},
+		}
+	}
+	informer := cache.NewSharedIndexInformer(
+		&cache.ListWatch{},
+		&unstructured.Unstructured{},
+		0,
+		cache.Indexers{
+			cache.NamespaceIndex: cache.MetaNamespaceIndexFunc,
+		},
+	)
+	policies := &ScaleToZeroPolicies{SharedIndexInformer: informer}
+	require.NoError(t, informer.GetStore().Add(newPolicy("1", 2)))
+	listed := policies.List("default")
+	require.Len(t, listed, 1)
+	assert.Equal(t, int32(2), *listed[0].Spec.MinReplicas)
+	// The same version of the policy is only converted once
+	assert.True(t, listed[0] == policies.List("default")[0])
+	// An invalid version of the policy is ignored
+	require.NoError(t, informer.GetStore().Update(newPolicy("2", 0)))
+	assert.Empty(t, policies.List("default"))
+	// A valid version of the policy is converted again
+	require.NoError(t, informer.GetStore().Update(newPolicy("3", 3)))
+	listed = policies.List("default")
+	require.Len(t, listed, 1)
+	assert.Equal(t, int32(3), *listed[0].Spec.MinReplicas)
+}
@@ -0,0 +1,178 @@
+package kubernetes
+
+import (
+	"fmt"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+const (
+	deploymentAnnotationName  = "osiris.deislabs.io/deployment"
+	statefulSetAnnotationName = "osiris.deislabs.io/statefulset"
+	workloadAnnotationName    = "osiris.deislabs.io/workload"
+)
+
+var (
+	deploymentKind  = appsv1.SchemeGroupVersion.WithKind("Deployment")
+	statefulSetKind = appsv1.SchemeGroupVersion.WithKind("StatefulSet")
+)
+
+// WorkloadReference refers to a workload-- any kind of resource that
+// implements the scale subresource-- by group, version, kind, and name.
+type WorkloadReference struct {
+	Group   string
+	Version string
+	Kind    string
+	Name    string
+}
+
+// DeploymentReference returns a reference to the named deployment.
+func DeploymentReference(name string) WorkloadReference {
+	return workloadReferenceFor(deploymentKind, name)
+}
+
+// StatefulSetReference returns a reference to the named stateful set.
+func StatefulSetReference(name string) WorkloadReference {
+	return workloadReferenceFor(statefulSetKind, name)
+}
+
+func workloadReferenceFor(
+	gvk schema.GroupVersionKind,
+	name string,
+) WorkloadReference {
+	return WorkloadReference{
+		Group:   gvk.Group,
+		Version: gvk.Version,
+		Kind:    gvk.Kind,
+		Name:    name,
+	}
+}
+
+// ParseWorkloadReference parses a workload reference of the form
+// group/version/kind/name. For kinds belonging to the core API group, the group
+// may be omitted, i.e. version/kind/name.
+func ParseWorkloadReference(str string) (WorkloadReference, error) {
+	gvk, name, err := parseGroupVersionKindPath(str, 1)
+	if err != nil {
+		return WorkloadReference{}, fmt.Errorf(
+			"Invalid workload reference %q; expected group/version/kind/name",
+			str,
+		)
+	}
+	return workloadReferenceFor(gvk, name[0]), nil
+}
+
+// ParseGroupVersionKind parses a kind of the form group/version/kind. For kinds
+// belonging to the core API group, the group may be omitted, i.e. version/kind.
+func ParseGroupVersionKind(str string) (schema.GroupVersionKind, error) {
+	gvk, _, err := parseGroupVersionKindPath(str, 0)
+	if err != nil {
+		return gvk, fmt.Errorf(
+			"Invalid kind %q; expected group/version/kind",
+			str,
+		)
+	}
+	return gvk, nil
+}
+
+// parseGroupVersionKindPath parses a slash-delimited group, version, and kind
+// followed by the specified number of additional non-empty tokens, which are
+// also returned.
+func parseGroupVersionKindPath(
+	str string,
+	extraTokens int,
+) (schema.GroupVersionKind, []string, error) {
+	gvk := schema.GroupVersionKind{}
+	tokens := strings.Split(str, "/")
+	for _, token := range tokens {
+		if token == "" {
+			return gvk, nil, fmt.Errorf("empty token in %q", str)
+		}
+	}
+	switch len(tokens) - extraTokens {
+	case 2:
+		gvk.Version, gvk.Kind = tokens[0], tokens[1]
+	case 3:
+		gvk.Group, gvk.Version, gvk.Kind = tokens[0], tokens[1], tokens[2]
+	default:
+		return gvk, nil, fmt.Errorf("wrong number of tokens in %q", str)
+	}
+	return gvk, tokens[len(tokens)-extraTokens:], nil
+}
+
+// GroupVersionKind returns the group, version, and kind of the referenced
+// workload.
+func (w WorkloadReference) GroupVersionKind() schema.GroupVersionKind {
+	return schema.GroupVersionKind{
+		Group:   w.Group,
+		Version: w.Version,
+		Kind:    w.Kind,
+	}
+}
+
+// String returns a human readable representation of the reference, e.g.
+// "deployment my-app" or "rollout.argoproj.io my-app". The group is omitted for
+// kinds belonging to the core or apps API groups.
+func (w WorkloadReference) String() string {
+	kind := strings.ToLower(w.Kind)
+	if w.Group != "" && w.Group != appsv1.GroupName {
+		kind = fmt.Sprintf("%s.%s", kind, w.Group)
+	}
+	return fmt.Sprintf("%s %s", kind, w.Name)
+}
+
+// GetServiceWorkloadReference returns a reference to the workload that is
+// behind a service, as indicated by the service's annotations. Exactly one of
+// the osiris.deislabs.io/deployment, osiris.deislabs.io/statefulset, or
+// osiris.deislabs.io/workload annotations may be used. If none is used, false
+// is returned.
+func GetServiceWorkloadReference(
+	annotations map[string]string,
+) (WorkloadReference, bool, error) {
+	refs := []WorkloadReference{}
+	if name, ok := annotations[deploymentAnnotationName]; ok {
+		refs = append(refs, DeploymentReference(name))
+	}
+	if name, ok := annotations[statefulSetAnnotationName]; ok {
+		refs = append(refs, StatefulSetReference(name))
+	}
+	if str, ok := annotations[workloadAnnotationName]; ok {
+		ref, err := ParseWorkloadReference(str)
+		if err != nil {
+			return ref, false, err
+		}
+		refs = append(refs, ref)
+	}
+	switch len(refs) {
+	case 0:
+		return WorkloadReference{}, false, nil
+	case 1:
+		return refs[0], true, nil
+	default:
+		return WorkloadReference{}, false, fmt.Errorf(
+			`Only one of the "%s", "%s", or "%s" annotations may be used`,
+			deploymentAnnotationName,
+			statefulSetAnnotationName,
+			workloadAnnotationName,
+		)
+	}
+}
+
+// ObjectReference returns a reference to the workload, in the given namespace
+// and having the given UID, that is suitable for associating events with it.
+func (w WorkloadReference) ObjectReference(
+	namespace string,
+	uid types.UID,
+) *corev1.ObjectReference {
+	return &corev1.ObjectReference{
+		APIVersion: w.GroupVersionKind().GroupVersion().String(),
+		Kind:       w.Kind,
+		Namespace:  namespace,
+		Name:       w.Name,
+		UID:        uid,
+	}
+}
@@ -0,0 +1,161 @@
+package kubernetes
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseWorkloadReference(t *testing.T) {
+	testcases := []struct {
+		name        string
+		str         string
+		expectedRef WorkloadReference
+		expectError bool
+	}{
+		{
+			name: "group, version, kind, and name",
+			str:  "argoproj.io/v1alpha1/Rollout/my-app",
+			expectedRef: WorkloadReference{
+				Group:   "argoproj.io",
+				Version: "v1alpha1",
+				Kind:    "Rollout",
+				Name:    "my-app",
+			},
+		},
+		{
+			name: "core group omitted",
+			str:  "v1/ReplicationController/my-app",
+			expectedRef: WorkloadReference{
+				Version: "v1",
+				Kind:    "ReplicationController",
+				Name:    "my-app",
+			},
+		},
+		{
+			name:        "missing name",
+			str:         "argoproj.io/v1alpha1/Rollout/",
+			expectError: true,
+		},
+		{
+			name:        "too few tokens",
+			str:         "Rollout/my-app",
+			expectError: true,
+		},
+		{
+			name:        "too many tokens",
+			str:         "a/argoproj.io/v1alpha1/Rollout/my-app",
+			expectError: true,
+		},
+	}
+	for _, test := range testcases {
+		t.Run(test.name, func(t *testing.T) {
+			ref, err := ParseWorkloadReference(test.str)
+			if test.expectError {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, test.expectedRef, ref)
+		})
+	}
+}
+
+func TestWorkloadReferenceString(t *testing.T) {
+	assert.Equal(t, "deployment my-app", DeploymentReference("my-app").String())
+	assert.Equal(
+		t,
+		"statefulset my-app",
+		StatefulSetReference("my-app").String(),
+	)
+	assert.Equal(
+		t,
+		"rollout.argoproj.io my-app",
+		WorkloadReference{
+			Group:   "argoproj.io",
+			Version: "v1alpha1",
+			Kind:    "Rollout",
+			Name:    "my-app",
+		}.String(),
+	)
+}
+
+func TestGetServiceWorkloadReference(t *testing.T) {
+	testcases := []struct {
+		name        string
+		annotations map[string]string
+		expectedRef WorkloadReference
+		expectedOK  bool
+		expectError bool
+	}{
+		{
+			name:        "no annotations",
+			annotations: map[string]string{},
+		},
+		{
+			name: "deployment annotation",
+			annotations: map[string]string{
+				deploymentAnnotationName: "my-app",
+			},
+			expectedRef: DeploymentReference("my-app"),
+			expectedOK:  true,
+		},
+		{
+			name: "stateful set annotation",
+			annotations: map[string]string{
+				statefulSetAnnotationName: "my-app",
+			},
+			expectedRef: StatefulSetReference("my-app"),
+			expectedOK:  true,
+		},
+		{
+			name: "workload annotation",
+			annotations: map[string]string{
+				workloadAnnotationName: "apps/v1/Deployment/my-app",
+			},
+			expectedRef: DeploymentReference("my-app"),
+			expectedOK:  true,
+		},
+		{
+			name: "invalid workload annotation",
+			annotations: map[string]string{
+				workloadAnnotationName: "my-app",
+			},
+			expectError: true,
+		},
+		{
+			name: "multiple annotations",
+			annotations: map[string]string{
+				deploymentAnnotationName:  "my-app",
+				statefulSetAnnotationName: "my-app",
+			},
+			expectError: true,
+		},
+	}
+	for _, test := range testcases {
+		t.Run(test.name, func(t *testing.T) {
+			ref, ok, err := GetServiceWorkloadReference(test.annotations)
+			if test.expectError {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, test.expectedOK, ok)
+			assert.Equal(t, test.expectedRef, ref)
+		})
+	}
+}
+
+func TestWorkloadReferenceObjectReference(t *testing.T) {
+	ref := WorkloadReference{
+		Group:   "argoproj.io",
+		Version: "v1alpha1",
+		Kind:    "Rollout",
+		Name:    "my-app",
+	}.ObjectReference("my-namespace", "1234")
+	assert.Equal(t, "argoproj.io/v1alpha1", ref.APIVersion)
+	assert.Equal(t, "Rollout", ref.Kind)
+	assert.Equal(t, "my-namespace", ref.Namespace)
+	assert.Equal(t, "my-app", ref.Name)
+	assert.Equal(t, "1234", string(ref.UID))
+}
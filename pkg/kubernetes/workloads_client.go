@@ -0,0 +1,179 @@
+package kubernetes
+
+import (
+	"encoding/json"
+
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/scale"
+	"k8s.io/client-go/util/retry"
+)
+
+// WorkloadsClient is an interface for components that provide uniform access
+// to workloads of any kind that implements the scale subresource-- e.g.
+// deployments, stateful sets, or custom resources.
+type WorkloadsClient interface {
+	// ResourceFor returns the resource corresponding to the specified kind.
+	ResourceFor(
+		gvk schema.GroupVersionKind,
+	) (schema.GroupVersionResource, error)
+	// Get returns the referenced workload.
+	Get(
+		namespace string,
+		ref WorkloadReference,
+	) (*unstructured.Unstructured, error)
+	// GetScale returns the scale subresource of the referenced workload.
+	GetScale(
+		namespace string,
+		ref WorkloadReference,
+	) (*autoscalingv1.Scale, error)
+	// Scale sets the desired number of replicas for the referenced workload.
+	Scale(namespace string, ref WorkloadReference, replicas int32) error
+	// Annotate adds the specified annotations to the referenced workload,
+	// overwriting any existing annotations having the same names.
+	Annotate(
+		namespace string,
+		ref WorkloadReference,
+		annotations map[string]string,
+	) error
+	// RemoveAnnotations removes the specified annotations from the referenced
+	// workload. Annotations that aren't set are ignored.
+	RemoveAnnotations(
+		namespace string,
+		ref WorkloadReference,
+		names ...string,
+	) error
+}
+
+// workloadsClient is a component that provides uniform access to workloads of
+// any kind that implements the scale subresource
+type workloadsClient struct {
+	dynamicClient dynamic.Interface
+	scalesGetter  scale.ScalesGetter
+	restMapper    meta.RESTMapper
+}
+
+func newWorkloadsClient(
+	dynamicClient dynamic.Interface,
+	scalesGetter scale.ScalesGetter,
+	restMapper meta.RESTMapper,
+) WorkloadsClient {
+	return &workloadsClient{
+		dynamicClient: dynamicClient,
+		scalesGetter:  scalesGetter,
+		restMapper:    restMapper,
+	}
+}
+
+func (w *workloadsClient) ResourceFor(
+	gvk schema.GroupVersionKind,
+) (schema.GroupVersionResource, error) {
+	mapping, err := w.restMapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	if err != nil {
+		return schema.GroupVersionResource{}, err
+	}
+	return mapping.Resource, nil
+}
+
+func (w *workloadsClient) Get(
+	namespace string,
+	ref WorkloadReference,
+) (*unstructured.Unstructured, error) {
+	gvr, err := w.ResourceFor(ref.GroupVersionKind())
+	if err != nil {
+		return nil, err
+	}
+	return w.dynamicClient.Resource(gvr).Namespace(namespace).Get(
+		ref.Name,
+		metav1.GetOptions{},
+	)
+}
+
+func (w *workloadsClient) GetScale(
+	namespace string,
+	ref WorkloadReference,
+) (*autoscalingv1.Scale, error) {
+	gvr, err := w.ResourceFor(ref.GroupVersionKind())
+	if err != nil {
+		return nil, err
+	}
+	return w.scalesGetter.Scales(namespace).Get(gvr.GroupResource(), ref.Name)
+}
+
+func (w *workloadsClient) Scale(
+	namespace string,
+	ref WorkloadReference,
+	replicas int32,
+) error {
+	gvr, err := w.ResourceFor(ref.GroupVersionKind())
+	if err != nil {
+		return err
+	}
+	scales := w.scalesGetter.Scales(namespace)
+	// Another process may update the workload between our reading and writing
+	// its scale subresource. If that happens, just try again.
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		s, err := scales.Get(gvr.GroupResource(), ref.Name)
+		if err != nil {
+			return err
+		}
+		s.Spec.Replicas = replicas
+		_, err = scales.Update(gvr.GroupResource(), s)
+		return err
+	})
+}
+
+func (w *workloadsClient) Annotate(
+	namespace string,
+	ref WorkloadReference,
+	annotations map[string]string,
+) error {
+	return w.patchAnnotations(namespace, ref, annotations)
+}
+
+func (w *workloadsClient) RemoveAnnotations(
+	namespace string,
+	ref WorkloadReference,
+	names ...string,
+) error {
+	// In a merge patch, null removes a key
+	annotations := map[string]interface{}{}
+	for _, name := range names {
+		annotations[name] = nil
+	}
+	return w.patchAnnotations(namespace, ref, annotations)
+}
+
+// patchAnnotations merges the given annotations, which must marshal to a JSON
+// object, into those of the referenced workload.
+func (w *workloadsClient) patchAnnotations(
+	namespace string,
+	ref WorkloadReference,
+	annotations interface{},
+) error {
+	gvr, err := w.ResourceFor(ref.GroupVersionKind())
+	if err != nil {
+		return err
+	}
+	patch := map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": annotations,
+		},
+	}
+	patchBytes, err := json.Marshal(patch)
+	if err != nil {
+		return err
+	}
+	_, err = w.dynamicClient.Resource(gvr).Namespace(namespace).Patch(
+		ref.Name,
+		types.MergePatchType,
+		patchBytes,
+		metav1.UpdateOptions{},
+	)
+	return err
+}
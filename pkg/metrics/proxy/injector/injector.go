@@ -15,6 +15,9 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/serializer"
+	"k8s.io/client-go/dynamic"
+	k8s "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
 )
 
 const port = 5000
@@ -25,15 +28,21 @@ type Injector interface {
 
 type injector struct {
 	config       Config
+	defaults     *kubernetes.ResourceDefaults
 	deserializer runtime.Decoder
 	srv          *http.Server
 }
 
-func NewInjector(config Config) Injector {
+func NewInjector(
+	config Config,
+	kubeClient k8s.Interface,
+	dynamicClient dynamic.Interface,
+) Injector {
 	mux := http.NewServeMux()
 
 	i := &injector{
-		config: config,
+		config:   config,
+		defaults: kubernetes.NewResourceDefaults(kubeClient, dynamicClient),
 		deserializer: serializer.NewCodecFactory(
 			runtime.NewScheme(),
 		).UniversalDeserializer(),
@@ -50,8 +59,21 @@ func NewInjector(config Config) Injector {
 }
 
 func (i *injector) Run(ctx context.Context) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
 	doneCh := make(chan struct{})
 
+	// Namespaces and scale to zero policies must be known before pods can be
+	// patched correctly
+	hasSynced := []cache.InformerSynced{}
+	for _, informer := range i.defaults.Informers() {
+		go informer.Run(ctx.Done())
+		hasSynced = append(hasSynced, informer.HasSynced)
+	}
+	if !cache.WaitForCacheSync(ctx.Done(), hasSynced...) {
+		return
+	}
+
 	go func() {
 		select {
 		case <-ctx.Done(): // Context was canceled or expired
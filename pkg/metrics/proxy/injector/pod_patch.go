@@ -40,7 +40,9 @@ func (i *injector) getPodPatchOperations(
 		req.UserInfo,
 	)
 
-	if !kubernetes.ResourceIsOsirisEnabled(pod.Annotations) ||
+	// Pods may not have a namespace of their own yet when they are created
+	annotations := i.defaults.Apply(req.Namespace, &pod)
+	if !kubernetes.ResourceIsOsirisEnabled(annotations) ||
 		(podContainsProxyInitContainer(&pod) && podContainsProxyContainer(&pod)) {
 		return nil, nil
 	}
@@ -129,7 +131,7 @@ func (i *injector) getPodPatchOperations(
 				},
 				{
 					Name:  "IGNORED_PATHS",
-					Value: pod.Annotations[kubernetes.IgnoredPathsAnnotationName],
+					Value: annotations[kubernetes.IgnoredPathsAnnotationName],
 				},
 			},
 			Ports: []corev1.ContainerPort{
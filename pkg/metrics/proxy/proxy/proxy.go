@@ -22,20 +22,29 @@ type Proxy interface {
 
 type proxy struct {
 	proxyID              string
+	startTime            time.Time
 	connectionsOpened    *uint64
 	connectionsClosed    *uint64
+	bytesRead            *uint64
+	bytesWritten         *uint64
+	lastActivity         *int64
 	dynamicProxies       []tcp.DynamicProxy
 	healthzAndMetricsSvr *http.Server
 	ignoredPaths         map[string]struct{}
 }
 
 func NewProxy(cfg Config) (Proxy, error) {
-	var connectionsOpened, connectionsClosed uint64
+	var connectionsOpened, connectionsClosed, bytesRead, bytesWritten uint64
+	var lastActivity int64
 	healthzAndMetricsMux := http.NewServeMux()
 	p := &proxy{
 		proxyID:           uuid.NewV4().String(),
+		startTime:         time.Now(),
 		connectionsOpened: &connectionsOpened,
 		connectionsClosed: &connectionsClosed,
+		bytesRead:         &bytesRead,
+		bytesWritten:      &bytesWritten,
+		lastActivity:      &lastActivity,
 		dynamicProxies:    []tcp.DynamicProxy{},
 		healthzAndMetricsSvr: &http.Server{
 			Addr:    fmt.Sprintf(":%d", cfg.MetricsAndHealthPort),
@@ -50,24 +59,32 @@ func NewProxy(cfg Config) (Proxy, error) {
 			fmt.Sprintf(":%d", listenPort),
 			func(r *http.Request) (string, int, error) {
 				if !p.isIgnoredRequest(r) {
-					atomic.AddUint64(p.connectionsOpened, 1)
+					p.recordConnectionOpened()
 				}
 				return "localhost", tp, nil
 			},
 			func(r *http.Request) error {
 				if !p.isIgnoredRequest(r) {
-					atomic.AddUint64(p.connectionsClosed, 1)
+					p.recordConnectionClosed()
 				}
 				return nil
 			},
+			func(r *http.Request, bytesRead int, bytesWritten int) {
+				if !p.isIgnoredRequest(r) {
+					p.recordBytesTransferred(bytesRead, bytesWritten)
+				}
+			},
 			func(string) (string, int, error) {
-				atomic.AddUint64(p.connectionsOpened, 1)
+				p.recordConnectionOpened()
 				return "localhost", tp, nil
 			},
 			func(string) error {
-				atomic.AddUint64(p.connectionsClosed, 1)
+				p.recordConnectionClosed()
 				return nil
 			},
+			func(_ string, bytesRead int, bytesWritten int) {
+				p.recordBytesTransferred(bytesRead, bytesWritten)
+			},
 		)
 		if err != nil {
 			return nil, err
@@ -124,11 +141,40 @@ func (p *proxy) Run(ctx context.Context) {
 	}
 }
 
+// recordConnectionOpened counts an opened connection and records the time of
+// this activity in nanoseconds since the epoch
+func (p *proxy) recordConnectionOpened() {
+	atomic.AddUint64(p.connectionsOpened, 1)
+	atomic.StoreInt64(p.lastActivity, time.Now().UnixNano())
+}
+
+func (p *proxy) recordConnectionClosed() {
+	atomic.AddUint64(p.connectionsClosed, 1)
+	atomic.StoreInt64(p.lastActivity, time.Now().UnixNano())
+}
+
+// recordBytesTransferred counts bytes read from and written to a client and
+// records the time of this activity in nanoseconds since the epoch
+func (p *proxy) recordBytesTransferred(bytesRead int, bytesWritten int) {
+	atomic.AddUint64(p.bytesRead, uint64(bytesRead))
+	atomic.AddUint64(p.bytesWritten, uint64(bytesWritten))
+	atomic.StoreInt64(p.lastActivity, time.Now().UnixNano())
+}
+
 func (p *proxy) handleMetricsRequest(w http.ResponseWriter, _ *http.Request) {
+	now := time.Now()
 	pcs := metrics.ProxyConnectionStats{
 		ProxyID:           p.proxyID,
 		ConnectionsOpened: atomic.LoadUint64(p.connectionsOpened),
 		ConnectionsClosed: atomic.LoadUint64(p.connectionsClosed),
+		BytesRead:         atomic.LoadUint64(p.bytesRead),
+		BytesWritten:      atomic.LoadUint64(p.bytesWritten),
+		ProxyStartTime:    &p.startTime,
+		Timestamp:         &now,
+	}
+	if lastActivity := atomic.LoadInt64(p.lastActivity); lastActivity != 0 {
+		lastActivityTime := time.Unix(0, lastActivity)
+		pcs.LastActivity = &lastActivityTime
 	}
 	pcsBytes, err := json.Marshal(pcs)
 	if err != nil {
@@ -1,7 +1,25 @@
 package metrics
 
+import "time"
+
 type ProxyConnectionStats struct {
 	ProxyID           string `json:"proxyId"`
 	ConnectionsOpened uint64 `json:"connectionsOpened"`
 	ConnectionsClosed uint64 `json:"connectionsClosed"`
+	// BytesRead and BytesWritten count the bytes the proxy has read from and
+	// written to clients over the connections it has counted
+	BytesRead    uint64 `json:"bytesRead"`
+	BytesWritten uint64 `json:"bytesWritten"`
+	// ProxyStartTime is when the proxy started counting connections. Together
+	// with ProxyID, it allows consumers of these stats to recognize that the
+	// counters were reset by a proxy restart and to count from zero instead.
+	ProxyStartTime *time.Time `json:"proxyStartTime,omitempty"`
+	// LastActivity is when the proxy last saw a connection opened or closed or
+	// bytes transferred over a connection. It is nil if the proxy has seen no
+	// activity since it started.
+	LastActivity *time.Time `json:"lastActivity,omitempty"`
+	// Timestamp is when the proxy reported these stats, according to the
+	// proxy's own clock. It allows consumers of these stats to determine how
+	// long ago the last activity was without having to account for clock skew.
+	Timestamp *time.Time `json:"timestamp,omitempty"`
 }
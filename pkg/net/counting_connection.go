@@ -0,0 +1,45 @@
+package net
+
+import (
+	"net"
+)
+
+// BytesTransferredCallback is the function signature for functions used as
+// callbacks whenever bytes are read from or written to a connection.
+type BytesTransferredCallback func(bytesRead int, bytesWritten int)
+
+// countingConn wraps a net.Conn and reports every read from and write to that
+// connection to a callback.
+type countingConn struct {
+	net.Conn
+	bytesTransferredCallback BytesTransferredCallback
+}
+
+// NewCountingConn returns a net.Conn that invokes the provided callback with
+// the number of bytes read or written every time the connection is read from
+// or written to.
+func NewCountingConn(
+	conn net.Conn,
+	bytesTransferredCallback BytesTransferredCallback,
+) net.Conn {
+	return &countingConn{
+		Conn:                     conn,
+		bytesTransferredCallback: bytesTransferredCallback,
+	}
+}
+
+func (c *countingConn) Read(b []byte) (int, error) {
+	n, err := c.Conn.Read(b)
+	if n > 0 {
+		c.bytesTransferredCallback(n, 0)
+	}
+	return n, err
+}
+
+func (c *countingConn) Write(b []byte) (int, error) {
+	n, err := c.Conn.Write(b)
+	if n > 0 {
+		c.bytesTransferredCallback(0, n)
+	}
+	return n, err
+}
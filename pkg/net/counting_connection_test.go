@@ -0,0 +1,48 @@
+package net
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCountingConnection(t *testing.T) {
+	var bytes = []byte("All your base are belong to us.")
+	var bytesRead, bytesWritten int
+
+	// Use an in-memory connection pair
+	remoteConn, localConn := net.Pipe()
+	defer remoteConn.Close()
+	countingConn := NewCountingConn(
+		localConn,
+		func(r int, w int) {
+			bytesRead += r
+			bytesWritten += w
+		},
+	)
+	defer countingConn.Close()
+
+	go func() {
+		n, err := remoteConn.Write(bytes)
+		require.NoError(t, err)
+		require.Equal(t, len(bytes), n)
+	}()
+	buf := make([]byte, 1024)
+	n, err := countingConn.Read(buf)
+	require.NoError(t, err)
+	require.Equal(t, bytes, buf[:n])
+	require.Equal(t, len(bytes), bytesRead)
+	require.Zero(t, bytesWritten)
+
+	go func() {
+		m, gerr := remoteConn.Read(buf)
+		require.NoError(t, gerr)
+		require.Equal(t, bytes[:5], buf[:m])
+	}()
+	n, err = countingConn.Write(bytes[:5])
+	require.NoError(t, err)
+	require.Equal(t, 5, n)
+	require.Equal(t, len(bytes), bytesRead)
+	require.Equal(t, 5, bytesWritten)
+}
@@ -0,0 +1,142 @@
+package http
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+
+	mynet "github.com/deislabs/osiris/pkg/net"
+)
+
+// L7BytesTransferredCallback is the function signature for functions used as
+// callbacks whenever bytes of a proxied request or its response are read from
+// or written to the client.
+type L7BytesTransferredCallback func(
+	r *http.Request,
+	bytesRead int,
+	bytesWritten int,
+)
+
+// countBytesTransferred wraps the provided response writer and the body of the
+// provided request so that all bytes transferred between the client and the
+// proxy in the course of serving the request, including those exchanged over
+// a connection that was upgraded (e.g. to a WebSocket), are reported to the
+// provided callback.
+func countBytesTransferred(
+	w http.ResponseWriter,
+	r *http.Request,
+	callback L7BytesTransferredCallback,
+) (http.ResponseWriter, *http.Request) {
+	// The callback is always invoked with the original request so that callers
+	// can correlate it with the request passed to other callbacks
+	origReq := r
+	bytesTransferredCallback := func(bytesRead int, bytesWritten int) {
+		callback(origReq, bytesRead, bytesWritten)
+	}
+	if r.Body != nil {
+		// Make a shallow copy of the request before replacing its body
+		r = r.WithContext(r.Context())
+		r.Body = &countingReadCloser{
+			ReadCloser:               r.Body,
+			bytesTransferredCallback: bytesTransferredCallback,
+		}
+	}
+	return &countingResponseWriter{
+		ResponseWriter:           w,
+		bytesTransferredCallback: bytesTransferredCallback,
+	}, r
+}
+
+// countingReadCloser reports every read from a request body to a callback.
+type countingReadCloser struct {
+	io.ReadCloser
+	bytesTransferredCallback mynet.BytesTransferredCallback
+}
+
+func (c *countingReadCloser) Read(b []byte) (int, error) {
+	n, err := c.ReadCloser.Read(b)
+	if n > 0 {
+		c.bytesTransferredCallback(n, 0)
+	}
+	return n, err
+}
+
+// countingResponseWriter reports every write of a response body to a
+// callback. It always implements http.Flusher, http.CloseNotifier, and
+// http.Hijacker, but flushing does nothing, close notification never happens,
+// and hijacking fails unless the response writer it wraps supports them.
+// Connections that are hijacked continue to be counted.
+type countingResponseWriter struct {
+	http.ResponseWriter
+	bytesTransferredCallback mynet.BytesTransferredCallback
+}
+
+func (c *countingResponseWriter) Write(b []byte) (int, error) {
+	n, err := c.ResponseWriter.Write(b)
+	if n > 0 {
+		c.bytesTransferredCallback(0, n)
+	}
+	return n, err
+}
+
+func (c *countingResponseWriter) Flush() {
+	if flusher, ok := c.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// CloseNotify returns a channel that never receives a value if the wrapped
+// response writer doesn't support close notification.
+func (c *countingResponseWriter) CloseNotify() <-chan bool {
+	if closeNotifier, ok := c.ResponseWriter.(http.CloseNotifier); ok {
+		return closeNotifier.CloseNotify()
+	}
+	return nil
+}
+
+func (c *countingResponseWriter) Hijack() (
+	net.Conn,
+	*bufio.ReadWriter,
+	error,
+) {
+	hijacker, ok := c.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("Response writer does not support hijacking")
+	}
+	conn, rw, err := hijacker.Hijack()
+	if err != nil {
+		return nil, nil, err
+	}
+	// Anything already written to the original buffered writer predates the
+	// hijacking and isn't counted
+	if err = rw.Flush(); err != nil {
+		conn.Close()
+		return nil, nil, err
+	}
+	// The original buffered reader may hold data the client sent after the
+	// request, so reads are still served through it
+	countingConn := mynet.NewCountingConn(
+		&bufferedConn{
+			Conn:   conn,
+			reader: rw.Reader,
+		},
+		c.bytesTransferredCallback,
+	)
+	return countingConn, bufio.NewReadWriter(
+		bufio.NewReader(countingConn),
+		bufio.NewWriter(countingConn),
+	), nil
+}
+
+// bufferedConn is a connection whose reads are served by a reader that may
+// have buffered data read from the connection earlier.
+type bufferedConn struct {
+	net.Conn
+	reader io.Reader
+}
+
+func (b *bufferedConn) Read(p []byte) (int, error) {
+	return b.reader.Read(p)
+}
@@ -0,0 +1,63 @@
+package http
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCountBytesTransferredAfterHijacking(t *testing.T) {
+	var bytesRead, bytesWritten int
+	var lock sync.Mutex
+	done := make(chan struct{})
+	server := httptest.NewServer(
+		http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				defer close(done)
+				w, _ = countBytesTransferred(
+					w,
+					r,
+					func(_ *http.Request, read int, written int) {
+						lock.Lock()
+						defer lock.Unlock()
+						bytesRead += read
+						bytesWritten += written
+					},
+				)
+				conn, rw, err := w.(http.Hijacker).Hijack()
+				require.NoError(t, err)
+				defer conn.Close()
+				line, err := rw.ReadString('\n')
+				require.NoError(t, err)
+				assert.Equal(t, "ping\n", line)
+				_, err = rw.WriteString("pong\n")
+				require.NoError(t, err)
+				require.NoError(t, rw.Flush())
+			},
+		),
+	)
+	defer server.Close()
+
+	conn, err := net.Dial("tcp", server.Listener.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+	// The data following the request is sent along with it, so the server is
+	// likely to have buffered it before the connection is hijacked
+	_, err = conn.Write([]byte("GET / HTTP/1.1\r\nHost: test\r\n\r\nping\n"))
+	require.NoError(t, err)
+	line, err := bufio.NewReader(conn).ReadString('\n')
+	require.NoError(t, err)
+	assert.Equal(t, "pong\n", line)
+	<-done
+
+	lock.Lock()
+	defer lock.Unlock()
+	assert.Equal(t, 5, bytesRead)
+	assert.Equal(t, 5, bytesWritten)
+}
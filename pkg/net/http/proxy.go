@@ -28,16 +28,18 @@ func ProxySingleConnection(
 	httpVersion string,
 	startProxyCallback L7StartProxyCallback,
 	endProxyCallback L7EndProxyCallback,
+	bytesTransferredCallback L7BytesTransferredCallback,
 ) error {
 	switch httpVersion {
 	case "1.0", "1.1":
 		doneCh := make(chan struct{})
 		server := &http.Server{
 			Handler: &http1xProxyRequestHandler{
-				proxyRequestFn:     defaultProxyRequest,
-				startProxyCallback: startProxyCallback,
-				endProxyCallback:   endProxyCallback,
-				doneCh:             doneCh,
+				proxyRequestFn:           defaultProxyRequest,
+				startProxyCallback:       startProxyCallback,
+				endProxyCallback:         endProxyCallback,
+				bytesTransferredCallback: bytesTransferredCallback,
+				doneCh:                   doneCh,
 			},
 		}
 		if err := server.Serve(
@@ -56,9 +58,10 @@ func ProxySingleConnection(
 		server := http2.Server{}
 		server.ServeConn(conn, &http2.ServeConnOpts{
 			Handler: &http2xProxyRequestHandler{
-				proxyRequestFn:     defaultProxyRequest,
-				startProxyCallback: startProxyCallback,
-				endProxyCallback:   endProxyCallback,
+				proxyRequestFn:           defaultProxyRequest,
+				startProxyCallback:       startProxyCallback,
+				endProxyCallback:         endProxyCallback,
+				bytesTransferredCallback: bytesTransferredCallback,
 			},
 		})
 		return nil
@@ -76,9 +79,10 @@ type http1xProxyRequestHandler struct {
 		*http.Request,
 		http.Handler,
 	)
-	startProxyCallback L7StartProxyCallback
-	endProxyCallback   L7EndProxyCallback
-	doneCh             chan struct{}
+	startProxyCallback       L7StartProxyCallback
+	endProxyCallback         L7EndProxyCallback
+	bytesTransferredCallback L7BytesTransferredCallback
+	doneCh                   chan struct{}
 }
 
 // ServeHTTP handles all of the HTTP proxy's inbound requests. It looks at the
@@ -90,16 +94,12 @@ func (h *http1xProxyRequestHandler) ServeHTTP(
 	w http.ResponseWriter,
 	r *http.Request,
 ) {
+	defer close(h.doneCh)
 	targetHost := r.Host
 	if h.startProxyCallback != nil {
 		th, tp, err := h.startProxyCallback(r)
 		if err != nil {
-			glog.Errorf(
-				"Error executing start proxy callback for host \"%s\": %s",
-				r.Host,
-				err,
-			)
-			http.Error(w, "", http.StatusInternalServerError)
+			writeErrorResponse(w, r, err)
 			return
 		}
 		targetHost = fmt.Sprintf("%s:%d", th, tp)
@@ -111,7 +111,11 @@ func (h *http1xProxyRequestHandler) ServeHTTP(
 		return
 	}
 	proxy := httputil.NewSingleHostReverseProxy(targetURL)
-	h.proxyRequestFn(w, r, proxy)
+	proxyW, proxyR := w, r
+	if h.bytesTransferredCallback != nil {
+		proxyW, proxyR = countBytesTransferred(w, r, h.bytesTransferredCallback)
+	}
+	h.proxyRequestFn(proxyW, proxyR, proxy)
 	if h.endProxyCallback != nil {
 		if err := h.endProxyCallback(r); err != nil {
 			glog.Errorf(
@@ -121,7 +125,6 @@ func (h *http1xProxyRequestHandler) ServeHTTP(
 			)
 		}
 	}
-	close(h.doneCh)
 }
 
 // http2xProxyRequestHandler is used internally to handle all of the HTTP
@@ -133,8 +136,9 @@ type http2xProxyRequestHandler struct {
 		*http.Request,
 		http.Handler,
 	)
-	startProxyCallback L7StartProxyCallback
-	endProxyCallback   L7EndProxyCallback
+	startProxyCallback       L7StartProxyCallback
+	endProxyCallback         L7EndProxyCallback
+	bytesTransferredCallback L7BytesTransferredCallback
 }
 
 // ServeHTTP handles all of the HTTP proxy's inbound requests. It looks at the
@@ -150,12 +154,7 @@ func (h *http2xProxyRequestHandler) ServeHTTP(
 	if h.startProxyCallback != nil {
 		th, tp, err := h.startProxyCallback(r)
 		if err != nil {
-			glog.Errorf(
-				"Error executing start proxy callback for host \"%s\": %s",
-				r.Host,
-				err,
-			)
-			http.Error(w, "", http.StatusInternalServerError)
+			writeErrorResponse(w, r, err)
 			return
 		}
 		targetHost = fmt.Sprintf("%s:%d", th, tp)
@@ -168,7 +167,11 @@ func (h *http2xProxyRequestHandler) ServeHTTP(
 	}
 	proxy := httputil.NewSingleHostReverseProxy(targetURL)
 	proxy.Transport = h2cDefaultTransport
-	h.proxyRequestFn(w, r, proxy)
+	proxyW, proxyR := w, r
+	if h.bytesTransferredCallback != nil {
+		proxyW, proxyR = countBytesTransferred(w, r, h.bytesTransferredCallback)
+	}
+	h.proxyRequestFn(proxyW, proxyR, proxy)
 	if h.endProxyCallback != nil {
 		if err := h.endProxyCallback(r); err != nil {
 			glog.Errorf(
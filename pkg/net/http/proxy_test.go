@@ -3,6 +3,7 @@ package http
 import (
 	"context"
 	"crypto/tls"
+	"errors"
 	"io/ioutil"
 	"net"
 	"net/http"
@@ -36,7 +37,7 @@ func TestProxySingleHTTP1xConnection(t *testing.T) {
 
 	// Proxy the proxy end of the connection. This is the function under test.
 	go func() {
-		gerr := ProxySingleConnection(proxyConn, "1.1", nil, nil)
+		gerr := ProxySingleConnection(proxyConn, "1.1", nil, nil, nil)
 		require.NoError(t, gerr)
 	}()
 
@@ -91,7 +92,7 @@ func TestProxySingleHTTP2xConnection(t *testing.T) {
 
 	// Proxy the proxy end of the connection. This is the function under test.
 	go func() {
-		gerr := ProxySingleConnection(proxyConn, "2.0", nil, nil)
+		gerr := ProxySingleConnection(proxyConn, "2.0", nil, nil, nil)
 		require.NoError(t, gerr)
 	}()
 
@@ -127,6 +128,7 @@ func TestProxySingleHTTP2xConnection(t *testing.T) {
 func TestServeHTTP1x(t *testing.T) {
 	body := []byte("foobar")
 	var startProxyCallbackCalled, endProxyCallbackCalled bool
+	var bytesWritten int
 	handler := &http1xProxyRequestHandler{
 		startProxyCallback: func(r *http.Request) (string, int, error) {
 			startProxyCallbackCalled = true
@@ -147,6 +149,9 @@ func TestServeHTTP1x(t *testing.T) {
 			endProxyCallbackCalled = true
 			return nil
 		},
+		bytesTransferredCallback: func(_ *http.Request, _ int, n int) {
+			bytesWritten += n
+		},
 		doneCh: make(chan struct{}),
 	}
 	req, err := http.NewRequest("GET", "/foo", nil)
@@ -158,6 +163,40 @@ func TestServeHTTP1x(t *testing.T) {
 	require.Equal(t, body, rr.Body.Bytes())
 	require.True(t, startProxyCallbackCalled)
 	require.True(t, endProxyCallbackCalled)
+	require.Equal(t, len(body), bytesWritten)
+}
+
+func TestServeHTTP1xResponseError(t *testing.T) {
+	body := []byte(`{"error":"ActivationTimedOut"}`)
+	handler := &http1xProxyRequestHandler{
+		startProxyCallback: func(r *http.Request) (string, int, error) {
+			return "", 0, &ResponseError{
+				Err:        errors.New("timed out"),
+				StatusCode: http.StatusServiceUnavailable,
+				Header: http.Header{
+					"Retry-After": []string{"10"},
+				},
+				Body: body,
+			}
+		},
+		proxyRequestFn: func(http.ResponseWriter, *http.Request, http.Handler) {
+			require.Fail(t, "request should not have been proxied")
+		},
+		doneCh: make(chan struct{}),
+	}
+	req, err := http.NewRequest("GET", "/foo", nil)
+	require.NoError(t, err)
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	require.Equal(t, http.StatusServiceUnavailable, rr.Code)
+	require.Equal(t, "10", rr.Header().Get("Retry-After"))
+	require.Equal(t, body, rr.Body.Bytes())
+	// The handler must be done, so that the connection can be closed
+	select {
+	case <-handler.doneCh:
+	default:
+		require.Fail(t, "handler should be done")
+	}
 }
 
 func TestServeHTTP2x(t *testing.T) {
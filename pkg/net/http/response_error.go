@@ -0,0 +1,52 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/golang/glog"
+)
+
+// ResponseError is an error that an L7StartProxyCallback may return to have
+// the proxy respond to the request with a specific status code, headers, and
+// body, instead of an empty 500.
+type ResponseError struct {
+	Err        error
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+}
+
+func (r *ResponseError) Error() string {
+	return r.Err.Error()
+}
+
+// writeErrorResponse responds to a request that could not be proxied because
+// the start proxy callback returned the given error.
+func writeErrorResponse(w http.ResponseWriter, r *http.Request, err error) {
+	respErr, ok := err.(*ResponseError)
+	if !ok {
+		glog.Errorf(
+			"Error executing start proxy callback for host \"%s\": %s",
+			r.Host,
+			err,
+		)
+		http.Error(w, "", http.StatusInternalServerError)
+		return
+	}
+	// The callback chose how to respond, so this isn't necessarily a failure
+	glog.Infof(
+		"Responding to request for host \"%s\" with status %d: %s",
+		r.Host,
+		respErr.StatusCode,
+		err,
+	)
+	for key, values := range respErr.Header {
+		for _, value := range values {
+			w.Header().Add(key, value)
+		}
+	}
+	w.WriteHeader(respErr.StatusCode)
+	if _, err := w.Write(respErr.Body); err != nil {
+		glog.Errorf("Error writing error response: %s", err)
+	}
+}
@@ -39,26 +39,34 @@ type dynamicProxy struct {
 		httpVersion string,
 		startProxyCallback http.L7StartProxyCallback,
 		endProxyCallback http.L7EndProxyCallback,
+		bytesTransferredCallback http.L7BytesTransferredCallback,
 	) error
-	l7EndProxyCallback   http.L7EndProxyCallback
-	l4StartProxyCallback l4StartProxyCallback
+	l7EndProxyCallback         http.L7EndProxyCallback
+	l7BytesTransferredCallback http.L7BytesTransferredCallback
+	l4StartProxyCallback       l4StartProxyCallback
 	// This can be overridden for testing purposes
 	l4ProxyFn func(
 		conn net.Conn,
 		serverAddr string,
 		startProxyCallback l4StartProxyCallback,
 		endProxyCallback l4EndProxyCallback,
+		bytesTransferredCallback l4BytesTransferredCallback,
 	) error
-	l4EndProxyCallback l4EndProxyCallback
+	l4EndProxyCallback         l4EndProxyCallback
+	l4BytesTransferredCallback l4BytesTransferredCallback
 }
 
-// NewDynamicProxy returns a DynamicProxy.
+// NewDynamicProxy returns a DynamicProxy. Any of the callbacks may be nil. The
+// bytes transferred callbacks, if provided, are invoked every time bytes are
+// read from or written to a proxied client.
 func NewDynamicProxy(
 	listenAddrStr string,
 	l7StartProxyCallback http.L7StartProxyCallback,
 	l7EndProxyCallback http.L7EndProxyCallback,
+	l7BytesTransferredCallback http.L7BytesTransferredCallback,
 	l4StartProxyCallback l4StartProxyCallback,
 	l4EndProxyCallback l4EndProxyCallback,
+	l4BytesTransferredCallback l4BytesTransferredCallback,
 ) (DynamicProxy, error) {
 	listenAddr, err := net.ResolveTCPAddr("tcp", listenAddrStr)
 	if err != nil {
@@ -69,15 +77,17 @@ func NewDynamicProxy(
 		)
 	}
 	d := &dynamicProxy{
-		listenAddr:              listenAddr,
-		httpVersionFn:           http.Version,
-		clientHelloServerNameFn: tls.ClientHelloServerName,
-		l7StartProxyCallback:    l7StartProxyCallback,
-		l7ProxyFn:               http.ProxySingleConnection,
-		l7EndProxyCallback:      l7EndProxyCallback,
-		l4StartProxyCallback:    l4StartProxyCallback,
-		l4ProxyFn:               defaultProxyConnection,
-		l4EndProxyCallback:      l4EndProxyCallback,
+		listenAddr:                 listenAddr,
+		httpVersionFn:              http.Version,
+		clientHelloServerNameFn:    tls.ClientHelloServerName,
+		l7StartProxyCallback:       l7StartProxyCallback,
+		l7ProxyFn:                  http.ProxySingleConnection,
+		l7EndProxyCallback:         l7EndProxyCallback,
+		l7BytesTransferredCallback: l7BytesTransferredCallback,
+		l4StartProxyCallback:       l4StartProxyCallback,
+		l4ProxyFn:                  defaultProxyConnection,
+		l4EndProxyCallback:         l4EndProxyCallback,
+		l4BytesTransferredCallback: l4BytesTransferredCallback,
 	}
 	d.serveConnectionFn = d.defaultServeConnection
 	return d, nil
@@ -129,6 +139,7 @@ func (d *dynamicProxy) defaultServeConnection(conn net.Conn) error {
 			httpVersion,
 			d.l7StartProxyCallback,
 			d.l7EndProxyCallback,
+			d.l7BytesTransferredCallback,
 		); err != nil {
 			return fmt.Errorf("Error applying l7 proxy: %s", err)
 		}
@@ -143,6 +154,7 @@ func (d *dynamicProxy) defaultServeConnection(conn net.Conn) error {
 		serverName,
 		d.l4StartProxyCallback,
 		d.l4EndProxyCallback,
+		d.l4BytesTransferredCallback,
 	); err != nil {
 		return fmt.Errorf("Error applying l4 proxy: %s", err)
 	}
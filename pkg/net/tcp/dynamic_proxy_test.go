@@ -25,6 +25,10 @@ func TestNewDynamicProxy(t *testing.T) {
 		l7EndCalled = true
 		return nil
 	}
+	var l7BytesTransferredCalled bool
+	l7BytesTransferred := func(*http.Request, int, int) {
+		l7BytesTransferredCalled = true
+	}
 	var l4StartCalled bool
 	l4Start := func(string) (string, int, error) {
 		l4StartCalled = true
@@ -35,12 +39,18 @@ func TestNewDynamicProxy(t *testing.T) {
 		l4EndCalled = true
 		return nil
 	}
+	var l4BytesTransferredCalled bool
+	l4BytesTransferred := func(string, int, int) {
+		l4BytesTransferredCalled = true
+	}
 	d, err := NewDynamicProxy(
 		"localhost:5000",
 		l7Start,
 		l7End,
+		l7BytesTransferred,
 		l4Start,
 		l4End,
+		l4BytesTransferred,
 	)
 	require.NoError(t, err)
 	dp, ok := d.(*dynamicProxy)
@@ -57,6 +67,8 @@ func TestNewDynamicProxy(t *testing.T) {
 	err = dp.l7EndProxyCallback(nil)
 	require.NoError(t, err)
 	require.True(t, l7EndCalled)
+	dp.l7BytesTransferredCallback(nil, 0, 0)
+	require.True(t, l7BytesTransferredCalled)
 	_, _, err = dp.l4StartProxyCallback("")
 	require.NoError(t, err)
 	require.True(t, l4StartCalled)
@@ -64,6 +76,8 @@ func TestNewDynamicProxy(t *testing.T) {
 	err = dp.l4EndProxyCallback("")
 	require.NoError(t, err)
 	require.True(t, l4EndCalled)
+	dp.l4BytesTransferredCallback("", 0, 0)
+	require.True(t, l4BytesTransferredCalled)
 }
 
 func TestListenAndServe(t *testing.T) {
@@ -156,6 +170,7 @@ func TestDefaultServeConnection(t *testing.T) {
 					string,
 					myhttp.L7StartProxyCallback,
 					myhttp.L7EndProxyCallback,
+					myhttp.L7BytesTransferredCallback,
 				) error {
 					l7ProxyUsed = true
 					return nil
@@ -165,6 +180,7 @@ func TestDefaultServeConnection(t *testing.T) {
 					string,
 					l4StartProxyCallback,
 					l4EndProxyCallback,
+					l4BytesTransferredCallback,
 				) error {
 					l4ProxyUsed = true
 					return nil
@@ -6,21 +6,37 @@ import (
 	"net"
 	"strings"
 
+	mynet "github.com/deislabs/osiris/pkg/net"
 	"github.com/golang/glog"
 )
 
 type l4StartProxyCallback func(serverName string) (string, int, error)
 type l4EndProxyCallback func(serverName string) error
+type l4BytesTransferredCallback func(
+	serverName string,
+	bytesRead int,
+	bytesWritten int,
+)
 
 func defaultProxyConnection(
 	conn net.Conn,
 	serverName string,
 	startProxyCallback l4StartProxyCallback,
 	endProxyCallback l4EndProxyCallback,
+	bytesTransferredCallback l4BytesTransferredCallback,
 ) error {
 	targetServerName := serverName
 	targetPort := 443
 
+	if bytesTransferredCallback != nil {
+		conn = mynet.NewCountingConn(
+			conn,
+			func(bytesRead int, bytesWritten int) {
+				bytesTransferredCallback(serverName, bytesRead, bytesWritten)
+			},
+		)
+	}
+
 	if endProxyCallback != nil {
 		defer func() {
 			if err := endProxyCallback(serverName); err != nil {
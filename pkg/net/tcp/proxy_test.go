@@ -3,6 +3,7 @@ package tcp
 import (
 	"fmt"
 	"net"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -50,6 +51,7 @@ func TestDefaultProxyConnection(t *testing.T) {
 	}()
 
 	// Proxy the connection. This is the function under test.
+	var totalBytesRead, totalBytesWritten int64
 	errCh := make(chan error)
 	go func() {
 		errCh <- defaultProxyConnection(
@@ -59,6 +61,10 @@ func TestDefaultProxyConnection(t *testing.T) {
 				return "localhost", backendPort, nil
 			},
 			nil,
+			func(_ string, bytesRead int, bytesWritten int) {
+				atomic.AddInt64(&totalBytesRead, int64(bytesRead))
+				atomic.AddInt64(&totalBytesWritten, int64(bytesWritten))
+			},
 		)
 	}()
 
@@ -76,4 +82,10 @@ func TestDefaultProxyConnection(t *testing.T) {
 	case <-time.After(3 * time.Second):
 		require.Fail(t, "timed out waiting for defaultProxyConnection() to return")
 	}
+	require.Equal(t, int64(len(reqBytes)), atomic.LoadInt64(&totalBytesRead))
+	require.Equal(
+		t,
+		int64(len(respBytes)),
+		atomic.LoadInt64(&totalBytesWritten),
+	)
 }